@@ -4,20 +4,23 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"sort"
 	"strings"
 
+	"compass_analyzer/circular"
 	"compass_analyzer/models"
 )
 
 // AngleSegment представляет стабильный сегмент углов
 type AngleSegment struct {
-	StartIndex int       // Начальный индекс сегмента
-	EndIndex   int       // Конечный индекс сегмента
-	AvgAngle   float64   // Репрезентативный угол сегмента
-	AllAngles  []float64 // Все углы в сегменте для вычисления медианы
-	IsStable   bool      // Флаг стабильности
-	Outliers   int       // Количество пропущенных выбросов внутри сегмента
+	StartIndex int       `json:"startIndex"` // Начальный индекс сегмента
+	EndIndex   int       `json:"endIndex"`   // Конечный индекс сегмента
+	AvgAngle   float64   `json:"avgAngle"`   // Репрезентативный угол сегмента
+	AllAngles  []float64 `json:"-"`          // Все углы в сегменте для вычисления медианы
+	IsStable   bool      `json:"isStable"`   // Флаг стабильности
+	Outliers   int       `json:"outliers"`   // Количество пропущенных выбросов внутри сегмента
+	Median     float64   `json:"median"`     // Круговая медиана углов сегмента
+	StdDev     float64   `json:"stdDev"`     // Круговое СКО углов сегмента, градусы
+	MAD        float64   `json:"mad"`        // Медианное абсолютное отклонение от медианы, градусы
 }
 
 // normalizeAngle приводит угол к диапазону [0, 360)
@@ -52,39 +55,23 @@ func signedAngleDifference(fromAngle, toAngle float64) float64 {
 }
 
 // circularMean вычисляет циркулярное (круговое) среднее для углов
-// Правильно обрабатывает углы около 0°/360°
+// Правильно обрабатывает углы около 0°/360° (см. circular.Mean)
 func circularMean(angles []float64) float64 {
-	if len(angles) == 0 {
-		return 0
-	}
-	
-	var sumSin, sumCos float64
-	for _, angle := range angles {
-		rad := angle * math.Pi / 180
-		sumSin += math.Sin(rad)
-		sumCos += math.Cos(rad)
-	}
-	
-	meanRad := math.Atan2(sumSin, sumCos)
-	meanAngle := meanRad * 180 / math.Pi
-	return normalizeAngle(meanAngle)
+	return circular.Mean(angles)
 }
 
-// medianAngle вычисляет медиану углов
+// medianAngle вычисляет круговую медиану углов (см. circular.Median).
+// Линейная сортировка дает неверный результат для сегментов около 0°/360°
 func medianAngle(angles []float64) float64 {
-	if len(angles) == 0 {
-		return 0
-	}
-	
-	sorted := make([]float64, len(angles))
-	copy(sorted, angles)
-	sort.Float64s(sorted)
-	
-	mid := len(sorted) / 2
-	if len(sorted)%2 == 0 {
-		return (sorted[mid-1] + sorted[mid]) / 2
-	}
-	return sorted[mid]
+	return circular.Median(angles)
+}
+
+// describeSegment заполняет круговую статистику разброса углов сегмента
+func describeSegment(seg *AngleSegment) {
+	stats := circular.Describe(seg.AllAngles)
+	seg.Median = stats.Median
+	seg.StdDev = stats.StdDev
+	seg.MAD = stats.MAD
 }
 
 // findStableSegments ищет стабильные сегменты с наращиванием и слиянием
@@ -165,19 +152,22 @@ func findStableSegments(angles []float64, stabilityThreshold float64, minStableL
 		if segmentLength >= minStableLen {
 			avgAngle := circularMean(segmentAngles)
 			
-			if logFile != nil {
-				fmt.Fprintf(logFile, "  ✓ Сегмент [%d:%d] принят: длина=%d, avg=%.2f°, outliers=%d\n", 
-					startIdx, endIdx, segmentLength, avgAngle, outlierCount)
-			}
-			
-			segments = append(segments, AngleSegment{
+			segment := AngleSegment{
 				StartIndex: startIdx,
 				EndIndex:   endIdx,
 				AvgAngle:   avgAngle,
 				AllAngles:  segmentAngles,
 				IsStable:   true,
 				Outliers:   outlierCount,
-			})
+			}
+			describeSegment(&segment)
+			
+			if logFile != nil {
+				fmt.Fprintf(logFile, "  ✓ Сегмент [%d:%d] принят: длина=%d, avg=%.2f°, медиана=%.2f°, σ=%.2f°, MAD=%.2f°, outliers=%d\n", 
+					startIdx, endIdx, segmentLength, avgAngle, segment.Median, segment.StdDev, segment.MAD, outlierCount)
+			}
+			
+			segments = append(segments, segment)
 			
 			// Переходим к следующему индексу
 			// Если были выбросы, начинаем следующий сегмент с первого выброса
@@ -198,14 +188,7 @@ func findStableSegments(angles []float64, stabilityThreshold float64, minStableL
 	// Слияние близких сегментов
 	segments = mergeCloseSegments(segments, stabilityThreshold, logFile)
 
-	if logFile != nil {
-		fmt.Fprintf(logFile, "\n=== Результаты поиска стабильных сегментов ===\n")
-		fmt.Fprintf(logFile, "Найдено сегментов: %d\n", len(segments))
-		for i, seg := range segments {
-			fmt.Fprintf(logFile, "Сегмент %d: индексы %d-%d (длина: %d), репрез. угол: %.2f°\n",
-				i+1, seg.StartIndex, seg.EndIndex, len(seg.AllAngles), seg.AvgAngle)
-		}
-	}
+	logSegments(logFile, segments)
 
 	return segments
 }
@@ -241,6 +224,7 @@ func mergeCloseSegments(segments []AngleSegment, threshold float64, logFile *os.
 			lastMerged.EndIndex = current.EndIndex
 			lastMerged.AvgAngle = circularMean(allAngles)
 			lastMerged.Outliers += current.Outliers
+			describeSegment(lastMerged)
 			
 			if logFile != nil {
 				fmt.Fprintf(logFile, "  ✓ Слиты в один: новый avg=%.2f°, индексы %d-%d\n",
@@ -257,9 +241,10 @@ func mergeCloseSegments(segments []AngleSegment, threshold float64, logFile *os.
 	return merged
 }
 
-// find90DegreeTurns находит повороты на ~90 градусов между стабильными сегментами
-// Новая логика: проверяем ВСЕ пары стабильных сегментов, не только соседние
-func find90DegreeTurns(segments []AngleSegment, turnTolerance float64, logFile *os.File) []models.Turn {
+// findStepTurns находит повороты на угол шага профиля между стабильными сегментами
+// Новая логика: проверяем ВСЕ пары стабильных сегментов, не только соседние.
+// Повороты в направлении, противоположном профилю, отклоняются
+func findStepTurns(segments []AngleSegment, profile models.ProcedureProfile, turnTolerance float64, logFile *os.File) []models.Turn {
 	// Сначала собираем только стабильные сегменты
 	stableSegments := make([]struct {
 		segment AngleSegment
@@ -276,8 +261,8 @@ func find90DegreeTurns(segments []AngleSegment, turnTolerance float64, logFile *
 	}
 
 	if logFile != nil {
-		fmt.Fprintf(logFile, "\n=== Поиск поворотов на ~90° ===\n")
-		fmt.Fprintf(logFile, "Допуск поворота: ±%.2f°\n", turnTolerance)
+		fmt.Fprintf(logFile, "\n=== Поиск поворотов на ~%.0f° ===\n", profile.StepAngle)
+		fmt.Fprintf(logFile, "Допуск поворота: ±%.2f°, направление: %s\n", turnTolerance, profile.Direction)
 		fmt.Fprintf(logFile, "Всего стабильных сегментов: %d (из %d общих)\n", len(stableSegments), len(segments))
 	}
 
@@ -296,7 +281,7 @@ func find90DegreeTurns(segments []AngleSegment, turnTolerance float64, logFile *
 		// Отрицательное значение = поворот против часовой стрелки
 		signedDiff := signedAngleDifference(prevAngle, currAngle)
 		
-		// Берем абсолютное значение для проверки близости к 90°
+		// Берем абсолютное значение для проверки близости к углу шага
 		absDiff := math.Abs(signedDiff)
 		
 		// Определяем направление поворота
@@ -313,9 +298,11 @@ func find90DegreeTurns(segments []AngleSegment, turnTolerance float64, logFile *
 				signedDiff, absDiff, direction)
 		}
 
-		// Проверяем, является ли разница близкой к 90 градусам
-		// И что поворот идет в правильном направлении (положительный - по часовой)
-		if math.Abs(absDiff-90) <= turnTolerance && isClockwise {
+		// Проверяем, является ли разница близкой к углу шага
+		// И что поворот идет в направлении профиля
+		stepMatches := math.Abs(absDiff-profile.StepAngle) <= turnTolerance
+		directionMatches := isClockwise == (profile.Direction == models.Clockwise)
+		if stepMatches && directionMatches {
 			turn := models.Turn{
 				StartAngle:  prevAngle,
 				EndAngle:    currAngle,
@@ -330,14 +317,15 @@ func find90DegreeTurns(segments []AngleSegment, turnTolerance float64, logFile *
 			turns = append(turns, turn)
 			
 			if logFile != nil {
-				fmt.Fprintf(logFile, "  ✓ Поворот найден! Diff=%.2f° (цель: 90±%.2f°), направление: по часовой ✓\n", absDiff, turnTolerance)
+				fmt.Fprintf(logFile, "  ✓ Поворот найден! Diff=%.2f° (цель: %.0f±%.2f°), направление: %s ✓\n",
+					absDiff, profile.StepAngle, turnTolerance, profile.Direction)
 			}
 		} else if logFile != nil {
-			if math.Abs(absDiff-90) <= turnTolerance && !isClockwise {
-				fmt.Fprintf(logFile, "  ✗ Отклонен: разница подходит (%.2f°), но направление ПРОТИВ часовой ✗\n", absDiff)
+			if stepMatches {
+				fmt.Fprintf(logFile, "  ✗ Отклонен: разница подходит (%.2f°), но направление не %s ✗\n", absDiff, profile.Direction)
 			} else {
-				fmt.Fprintf(logFile, "  ✗ Не поворот на 90°: |%.2f° - 90°| = %.2f° > %.2f°\n", 
-					absDiff, math.Abs(absDiff-90), turnTolerance)
+				fmt.Fprintf(logFile, "  ✗ Не поворот на %.0f°: |%.2f° - %.0f°| = %.2f° > %.2f°\n",
+					profile.StepAngle, absDiff, profile.StepAngle, math.Abs(absDiff-profile.StepAngle), turnTolerance)
 			}
 		}
 	}
@@ -345,28 +333,30 @@ func find90DegreeTurns(segments []AngleSegment, turnTolerance float64, logFile *
 	return turns
 }
 
-// findBestTurnSequence находит лучшую последовательность из 4 непрерывных поворотов
-// Ищет все возможные последовательности и выбирает ту, где сумма ближе к 360°
-func findBestTurnSequence(allTurns []models.Turn, logFile *os.File) []models.Turn {
-	if len(allTurns) < 4 {
-		return allTurns // Если меньше 4 поворотов, возвращаем как есть
+// findBestTurnSequence находит лучшую последовательность из StepCount непрерывных поворотов
+// Ищет все возможные последовательности и выбирает ту, где сумма ближе к номинальной
+// (360° для 4×90°)
+func findBestTurnSequence(allTurns []models.Turn, profile models.ProcedureProfile, logFile *os.File) []models.Turn {
+	stepCount := profile.StepCount
+	if len(allTurns) < stepCount {
+		return allTurns // Если поворотов меньше, чем в профиле, возвращаем как есть
 	}
 	
 	if logFile != nil {
-		fmt.Fprintf(logFile, "\n=== Поиск лучшей последовательности из 4 поворотов ===\n")
+		fmt.Fprintf(logFile, "\n=== Поиск лучшей последовательности из %d поворотов ===\n", stepCount)
 		fmt.Fprintf(logFile, "Всего найдено поворотов: %d\n", len(allTurns))
 	}
 	
-	bestSequence := allTurns[:4]
+	bestSequence := allTurns[:stepCount]
 	bestDeviation := 1000.0 // Большое значение для начала
 	
-	// Проходим по всем возможным последовательностям из 4 подряд идущих поворотов
-	for i := 0; i <= len(allTurns)-4; i++ {
-		sequence := allTurns[i : i+4]
+	// Проходим по всем возможным последовательностям из StepCount подряд идущих поворотов
+	for i := 0; i <= len(allTurns)-stepCount; i++ {
+		sequence := allTurns[i : i+stepCount]
 		
 		// Проверяем непрерывность: конец каждого = начало следующего
 		isContinuous := true
-		for j := 1; j < 4; j++ {
+		for j := 1; j < stepCount; j++ {
 			if sequence[j].FromSegment != sequence[j-1].ToSegment {
 				isContinuous = false
 				break
@@ -383,15 +373,12 @@ func findBestTurnSequence(allTurns []models.Turn, logFile *os.File) []models.Tur
 			totalDiff += turn.Diff
 		}
 		
-		// Вычисляем отклонение от 360°
-		deviation := math.Abs(totalDiff - 360)
-		if deviation > 180 {
-			deviation = 360 - deviation
-		}
+		// Вычисляем отклонение от номинальной суммы
+		deviation := math.Abs(totalDiff - profile.TotalAngle())
 		
 		if logFile != nil {
 			fmt.Fprintf(logFile, "Последовательность [%d:%d]: сумма=%.2f°, отклонение=%.2f°\n",
-				i+1, i+4, totalDiff, deviation)
+				i+1, i+stepCount, totalDiff, deviation)
 		}
 		
 		// Если эта последовательность лучше, сохраняем её
@@ -405,85 +392,108 @@ func findBestTurnSequence(allTurns []models.Turn, logFile *os.File) []models.Tur
 	}
 	
 	if logFile != nil {
-		fmt.Fprintf(logFile, "✓ Выбрана последовательность с отклонением %.2f° от 360°\n", bestDeviation)
+		fmt.Fprintf(logFile, "✓ Выбрана последовательность с отклонением %.2f° от %.0f°\n", bestDeviation, profile.TotalAngle())
 	}
 	
 	return bestSequence
 }
 
 // validateTurnSequence проверяет последовательность поворотов на корректность
-// Требования:
-// 1. Ровно 4 поворота на ~90°
-// 2. ВСЕ повороты в ОДНОМ направлении (по часовой стрелке)
-// 3. Сумма всех поворотов ≈ 360° (±15°)
+// Требования (для профиля 4×90° по часовой):
+// 1. Ровно 4 поворота на ~90° (StepCount поворотов на StepAngle)
+// 2. ВСЕ повороты в ОДНОМ направлении (направление профиля)
+// 3. Сумма всех поворотов ≈ 360° (±допуск замыкания профиля)
 // 4. Повороты идут последовательно (без наложений)
 // 5. Повороты образуют непрерывную цепочку
-func validateTurnSequence(turns []models.Turn, logFile *os.File) (bool, []string) {
-	var errors []string
+//
+// Возвращает результаты выполненных проверок по порядку; после первой
+// проваленной проверки остальные не выполняются.
+func validateTurnSequence(turns []models.Turn, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) []models.Check {
+	stepCount := profile.StepCount
+	var checks []models.Check
+	add := func(c models.Check) bool {
+		checks = append(checks, c)
+		logCheck(logFile, c)
+		return !c.Failed()
+	}
 
 	if logFile != nil {
 		fmt.Fprintf(logFile, "\n=== Валидация последовательности поворотов ===\n")
 	}
 
-	// Проверка 1: Минимум 4 поворота (берём первые 4, если больше)
-	if len(turns) < 4 {
-		msg := fmt.Sprintf("Найдено %d поворотов, требуется минимум 4", len(turns))
-		errors = append(errors, msg)
-		if logFile != nil {
-			fmt.Fprintf(logFile, "✗ %s\n", msg)
-		}
-		return false, errors
+	// Проверка 1: Минимум StepCount поворотов (берём первые, если больше)
+	countCheck := models.Check{
+		Name:     models.CheckTurnCount,
+		Passed:   len(turns) >= stepCount,
+		Measured: float64(len(turns)),
+		Limit:    float64(stepCount),
+		Unit:     "шт",
+		Message:  fmt.Sprintf("Найдено не меньше %d поворотов (используем первые %d)", stepCount, stepCount),
+	}
+	if !countCheck.Passed {
+		countCheck.Reason = models.ReasonTooFewTurns
+		countCheck.Message = countCheck.Reason.Format(len(turns), stepCount)
+		add(countCheck)
+		return checks
 	}
 	
-	// Если найдено больше 4 поворотов, берём только первые 4
+	// Если найдено больше поворотов, берём только первые StepCount
 	// (компас мог продолжить вращение после завершения калибровки)
-	if len(turns) > 4 {
+	if len(turns) > stepCount {
 		if logFile != nil {
-			fmt.Fprintf(logFile, "ℹ Найдено %d поворотов, анализируем первые 4 (полный круг)\n", len(turns))
+			fmt.Fprintf(logFile, "ℹ Найдено %d поворотов, анализируем первые %d (полный круг)\n", len(turns), stepCount)
 		}
-		turns = turns[:4] // Обрезаем до первых 4
-	}
-	
-	if logFile != nil {
-		fmt.Fprintf(logFile, "✓ Найдено минимум 4 поворота (используем первые 4)\n")
+		turns = turns[:stepCount] // Обрезаем до первых StepCount
 	}
+	add(countCheck)
 
-	// Проверка 2: ВСЕ повороты должны быть в одном направлении (по часовой)
-	allClockwise := true
+	// Проверка 2: ВСЕ повороты должны быть в направлении профиля
+	directionReason := models.ReasonCounterClockwise
+	if profile.Direction == models.CounterClockwise {
+		directionReason = models.ReasonClockwise
+	}
+	var wrongDirection []string
 	for i, turn := range turns {
-		if !turn.IsClockwise {
-			allClockwise = false
-			msg := fmt.Sprintf("Поворот %d идет ПРОТИВ часовой стрелки (%.2f° → %.2f°, знаковая разница: %.2f°)", 
-				i+1, turn.StartAngle, turn.EndAngle, turn.SignedDiff)
-			errors = append(errors, msg)
-			if logFile != nil {
-				fmt.Fprintf(logFile, "✗ %s\n", msg)
-			}
+		if !profile.Matches(turn) {
+			msg := directionReason.Format(i+1, turn.StartAngle, turn.EndAngle, turn.SignedDiff)
+			wrongDirection = append(wrongDirection, msg)
 		}
 	}
-	
-	if !allClockwise {
-		return false, errors
+	directionCheck := models.Check{
+		Name:     models.CheckDirection,
+		Passed:   len(wrongDirection) == 0,
+		Measured: float64(len(wrongDirection)),
+		Limit:    0,
+		Unit:     "шт",
+		Message:  fmt.Sprintf("Все повороты идут в одном направлении (%s)", profile.Direction),
 	}
-	
-	if logFile != nil {
-		fmt.Fprintf(logFile, "✓ Все повороты идут в одном направлении (по часовой стрелке)\n")
+	if !directionCheck.Passed {
+		directionCheck.Reason = directionReason
+		directionCheck.Message = strings.Join(wrongDirection, "; ")
+	}
+	if !add(directionCheck) {
+		return checks
 	}
 
 	// Проверка 3: Последовательность (без пересечений по индексам)
+	orderCheck := models.Check{
+		Name:    models.CheckIndexOrder,
+		Passed:  true,
+		Unit:    "шт",
+		Message: "Повороты идут последовательно без пересечений по индексам",
+	}
 	for i := 1; i < len(turns); i++ {
 		// Проверка на пересечение индексов данных
 		if turns[i].StartIndex <= turns[i-1].EndIndex {
-			msg := fmt.Sprintf("Поворот %d начинается до конца поворота %d (пересечение индексов)", i+1, i)
-			errors = append(errors, msg)
-			if logFile != nil {
-				fmt.Fprintf(logFile, "✗ %s\n", msg)
-			}
-			return false, errors
+			orderCheck.Passed = false
+			orderCheck.Measured = float64(i + 1)
+			orderCheck.Reason = models.ReasonIndexOverlap
+			orderCheck.Message = orderCheck.Reason.Format(i+1, i)
+			break
 		}
 	}
-	if logFile != nil {
-		fmt.Fprintf(logFile, "✓ Повороты идут последовательно без пересечений по индексам\n")
+	if !add(orderCheck) {
+		return checks
 	}
 	
 	// Проверка 3.5: Непрерывность по индексам сегментов
@@ -503,8 +513,15 @@ func validateTurnSequence(turns []models.Turn, logFile *os.File) (bool, []string
 	}
 	
 	// Проверка 4: Непрерывность цепочки (конечный угол поворота N ≈ начальный угол поворота N+1)
-	const continuityTolerance = 20.0 // Допуск на непрерывность в градусах
-	continuousChain := true
+	continuityTolerance := params.ContinuityTolerance
+	continuityCheck := models.Check{
+		Name:     models.CheckContinuity,
+		Passed:   true,
+		Limit:    continuityTolerance,
+		Unit:     "°",
+		Message:  fmt.Sprintf("Повороты образуют непрерывную цепочку (разрывы ≤ %.2f°)", continuityTolerance),
+		Advisory: true, // Не считаем это критической ошибкой, только предупреждение
+	}
 	
 	for i := 0; i < len(turns)-1; i++ {
 		currentEnd := turns[i].EndAngle
@@ -516,64 +533,60 @@ func validateTurnSequence(turns []models.Turn, logFile *os.File) (bool, []string
 				i+1, currentEnd, i+2, nextStart, gap)
 		}
 		
+		if gap > continuityCheck.Measured {
+			continuityCheck.Measured = gap
+		}
 		if gap > continuityTolerance {
-			continuousChain = false
-			msg := fmt.Sprintf("Разрыв между поворотом %d (конец %.2f°) и поворотом %d (начало %.2f°): %.2f° > %.2f°",
-				i+1, currentEnd, i+2, nextStart, gap, continuityTolerance)
-			if logFile != nil {
-				fmt.Fprintf(logFile, "  ⚠ Предупреждение: %s\n", msg)
+			msg := models.ReasonChainGap.Format(i+1, currentEnd, i+2, nextStart, gap, continuityTolerance)
+			if continuityCheck.Passed {
+				continuityCheck.Passed = false
+				continuityCheck.Reason = models.ReasonChainGap
+				continuityCheck.Message = msg
+			} else {
+				continuityCheck.Message += "; " + msg
 			}
-			// Не считаем это критической ошибкой, только предупреждение
 		}
 	}
-	
-	if continuousChain && logFile != nil {
-		fmt.Fprintf(logFile, "✓ Повороты образуют непрерывную цепочку (разрывы ≤ %.2f°)\n", continuityTolerance)
-	}
+	add(continuityCheck)
 
-	// Проверка 5: Сумма углов ≈ 360°
+	// Проверка 5: Сумма углов ≈ номинальной (360° для 4×90°)
 	var totalDiff float64
 	for _, turn := range turns {
 		totalDiff += turn.Diff
 	}
 	
-	// Нормализуем общую сумму к диапазону [0, 360)
-	totalDiffNorm := math.Mod(totalDiff, 360)
-	deviation := math.Abs(totalDiffNorm - 360)
-	if deviation > 180 {
-		deviation = 360 - deviation
-	}
+	totalAngle := profile.TotalAngle()
+	deviation := math.Abs(totalDiff - totalAngle)
 	
 	if logFile != nil {
-		fmt.Fprintf(logFile, "Сумма поворотов: %.2f° (нормализовано: %.2f°)\n", totalDiff, totalDiffNorm)
-		fmt.Fprintf(logFile, "Отклонение от 360°: %.2f°\n", deviation)
+		fmt.Fprintf(logFile, "Сумма поворотов: %.2f°\n", totalDiff)
+		fmt.Fprintf(logFile, "Отклонение от %.0f°: %.2f°\n", totalAngle, deviation)
 	}
 	
-	const sumTolerance = 15.0 // Допуск ±15° на сумму
-	if deviation > sumTolerance {
-		msg := fmt.Sprintf("Сумма поворотов (%.2f°) слишком отличается от 360° (отклонение: %.2f° > %.2f°)", 
-			totalDiff, deviation, sumTolerance)
-		errors = append(errors, msg)
-		if logFile != nil {
-			fmt.Fprintf(logFile, "✗ %s\n", msg)
-		}
-		return false, errors
+	sumTolerance := profile.SumToleranceFor(params)
+	sumCheck := models.Check{
+		Name:     models.CheckSum,
+		Passed:   deviation <= sumTolerance,
+		Measured: deviation,
+		Limit:    sumTolerance,
+		Unit:     "°",
+		Message:  fmt.Sprintf("Сумма поворотов близка к %.0f° (отклонение: %.2f° ≤ %.2f°)", totalAngle, deviation, sumTolerance),
 	}
-	if logFile != nil {
-		fmt.Fprintf(logFile, "✓ Сумма поворотов близка к 360° (отклонение: %.2f° ≤ %.2f°)\n", deviation, sumTolerance)
+	if !sumCheck.Passed {
+		sumCheck.Reason = models.ReasonSumDeviation
+		sumCheck.Message = sumCheck.Reason.Format(totalDiff, totalAngle, deviation, sumTolerance)
+	}
+	if !add(sumCheck) {
+		return checks
 	}
 
 	// Проверка 6: Детальная информация о каждом повороте
 	if logFile != nil {
 		fmt.Fprintf(logFile, "\nДетали поворотов:\n")
 		for i, turn := range turns {
-			dev := math.Abs(turn.Diff - 90)
-			direction := "по часовой ✓"
-			if !turn.IsClockwise {
-				direction = "против часовой ✗"
-			}
-			fmt.Fprintf(logFile, "Поворот %d: %.2f° → %.2f° (Δ=%.2f°, знак=%.2f°, отклонение от 90°: %.2f°, %s)\n", 
-				i+1, turn.StartAngle, turn.EndAngle, turn.Diff, turn.SignedDiff, dev, direction)
+			dev := math.Abs(turn.Diff - profile.StepAngle)
+			fmt.Fprintf(logFile, "Поворот %d: %.2f° → %.2f° (Δ=%.2f°, знак=%.2f°, отклонение от %.0f°: %.2f°, %s)\n",
+				i+1, turn.StartAngle, turn.EndAngle, turn.Diff, turn.SignedDiff, profile.StepAngle, dev, turnDirectionLabel(turn, profile))
 		}
 	}
 
@@ -581,41 +594,112 @@ func validateTurnSequence(turns []models.Turn, logFile *os.File) (bool, []string
 		fmt.Fprintf(logFile, "\n✓ Все проверки пройдены успешно!\n")
 	}
 
-	return true, nil
+	return checks
 }
 
-// AnalyzeCompassData анализирует данные компаса и находит повороты на 90 градусов
-// Новая логика с детальной валидацией и отчётностью
-func AnalyzeCompassData(angles []float64, logFile *os.File) (bool, []models.Turn) {
-	// Параметры анализа
-	stabilityThreshold := 5.0 // Порог стабильности в градусах
-	turnTolerance := 15.0     // Допуск для определения поворота на 90±15 градусов (было 10, увеличено для учета реальных данных)
-	minStableLen := 2         // Минимальная длина стабильного сегмента (уменьшено с 3 до 2 для учета коротких переходных зон)
-	maxOutliers := 0          // Отключаем гистерезис - каждый нестабильный угол прерывает сегмент
+// AnalyzeCompassData анализирует данные компаса и находит повороты процедуры калибровки
+// (4 поворота на 90 градусов для профиля по умолчанию).
+// Новая логика с детальной валидацией и отчётностью.
+// Пороги алгоритма задаются params (см. models.DefaultAnalysisParams),
+// углы, количество и направление поворотов и ограничения времени - профилем процедуры.
+// Перед поиском сегментов ряд азимутов проходит предварительную обработку
+// (см. Preprocess); исходный и обработанный ряды возвращаются в Series.
+// Стабильные сегменты ищет алгоритм, заданный в профиле (см. Segmenter).
+// Метки времени записей data используются для проверки времени неподвижности
+// на курсах и длительности поворотов (см. validateTiming).
+// Результат содержит сегменты, все найденные повороты, выбранную
+// последовательность и результат каждой проверки (см. AnalysisResult)
+func AnalyzeCompassData(data []models.CompassData, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) AnalysisResult {
+	raw := data
+	angles := make([]float64, len(data))
+	for i, d := range data {
+		angles[i] = d.Angle
+	}
+
+	segmenter := segmenterFor(profile)
+
+	// Параметры анализа (профиль может задавать собственные пороги)
+	params = profile.ParamsFor(params)
+	stabilityThreshold := params.StabilityThreshold
+	turnTolerance := profile.StepToleranceFor(params)
+	stepCount := profile.StepCount
+	minStableLen := params.MinStableLen
+	maxOutliers := params.MaxOutliers
 
 	if logFile != nil {
 		fmt.Fprintf(logFile, "\n╔════════════════════════════════════════════════════════════╗\n")
 		fmt.Fprintf(logFile, "║         АНАЛИЗ ДАННЫХ КОМПАСА (новый алгоритм)          ║\n")
 		fmt.Fprintf(logFile, "╚════════════════════════════════════════════════════════════╝\n")
 		fmt.Fprintf(logFile, "\nВсего записей углов: %d\n", len(angles))
+		fmt.Fprintf(logFile, "\nПрофиль процедуры: %s\n", profile)
+		fmt.Fprintf(logFile, "Алгоритм поиска сегментов: %s\n", segmenter.Name())
 		fmt.Fprintf(logFile, "\nПараметры анализа:\n")
 		fmt.Fprintf(logFile, "  • Порог стабильности: %.2f°\n", stabilityThreshold)
-		fmt.Fprintf(logFile, "  • Допуск поворота (90±X): ±%.2f°\n", turnTolerance)
+		fmt.Fprintf(logFile, "  • Допуск поворота (%.0f±X): ±%.2f°\n", profile.StepAngle, turnTolerance)
 		fmt.Fprintf(logFile, "  • Минимальная длина сегмента: %d записей\n", minStableLen)
 		fmt.Fprintf(logFile, "  • Максимум выбросов (гистерезис): %d\n", maxOutliers)
+		fmt.Fprintf(logFile, "  • Допуск непрерывности цепочки: %.2f°\n", params.ContinuityTolerance)
+		fmt.Fprintf(logFile, "  • Допуск суммы поворотов (%.0f±X): ±%.2f°\n", profile.TotalAngle(), profile.SumToleranceFor(params))
+		fmt.Fprintf(logFile, "  • Допуск возврата к начальному курсу: ±%.2f°\n", profile.ReturnToleranceFor(params))
+		fmt.Fprintf(logFile, "  • Вердикт при нескольких циклах: %s\n", params.CycleVerdict)
+		fmt.Fprintf(logFile, "  • Порог перепроверки по оценке качества: %.0f/100\n", params.RetestQuality)
+		fmt.Fprintf(logFile, "  • Предварительная обработка: %s\n", params.Preprocess)
+		if profile.HasTimingLimits() {
+			fmt.Fprintf(logFile, "  • Время: неподвижность ≥ %.0f с, поворот ≤ %.0f с, цикл ≤ %.0f с (0 - без ограничения)\n",
+				profile.MinDwell, profile.MaxTurnDuration, profile.MaxTotalDuration)
+		}
 	}
 
-	// Этап 1: Поиск стабильных сегментов
-	segments := findStableSegments(angles, stabilityThreshold, minStableLen, maxOutliers, logFile)
+	// Этап 0: Предварительная обработка азимута (индексы сегментов и поворотов
+	// далее относятся к обработанному ряду)
+	data, stages := Preprocess(data, params.Preprocess, logFile)
+
+	// Этап 1: Поиск стабильных сегментов алгоритмом профиля (пороги в записях
+	// после передискретизации пересчитываются на шаг сетки)
+	segments := segmenter.Segment(data, gridParams(raw, stages, params, logFile), profile, logFile)
 
-	// Этап 2: Поиск поворотов на ~90° между сегментами
-	allTurns := find90DegreeTurns(segments, turnTolerance, logFile)
+	// Этап 2: Поиск поворотов на угол шага между сегментами
+	allTurns := findStepTurns(segments, profile, turnTolerance, logFile)
 	
-	// Этап 2.5: Поиск лучшей последовательности из 4 непрерывных поворотов
-	turns := findBestTurnSequence(allTurns, logFile)
+	// Этап 2.5: Поиск лучшей последовательности из StepCount непрерывных поворотов
+	turns := findBestTurnSequence(allTurns, profile, logFile)
+	turns = alignToCycles(allTurns, turns, profile, logFile)
+
+	result := AnalysisResult{
+		Profile:    profile.Name,
+		Segmenter:  segmenter.Name(),
+		Params:     params,
+		Samples:    len(raw),
+		Preprocess: stages,
+		Series:     newSeries(raw, data),
+		Segments:   segments,
+		Candidates: allTurns,
+		Turns:      turns,
+	}
 
 	// Этап 3: Валидация последовательности поворотов
-	isValid, validationErrors := validateTurnSequence(turns, logFile)
+	result.Checks = validateTurnSequence(turns, params, profile, logFile)
+
+	// Этап 4: Проверка возврата к начальному курсу, времени неподвижности и длительности поворотов
+	if len(turns) == stepCount {
+		closureChecks, closure := validateClosure(segments, turns, params, profile, logFile)
+		result.Checks = append(result.Checks, closureChecks...)
+		result.Closure = closure
+		result.Checks = append(result.Checks, validateTiming(data, segments, turns, profile, logFile)...)
+	}
+
+	// Этап 4.5: Все полные циклы поворотов в файле, повторяемость и вердикт по циклам
+	result.Cycles, result.Positions = analyzeCycles(data, segments, allTurns, turns, params, profile, logFile)
+	if check, ok := cyclesCheck(result.Cycles, params); ok {
+		result.Checks = append(result.Checks, check)
+		logCheck(logFile, check)
+	}
+	result.updateVerdict()
+
+	// Этап 5: Оценка качества и уверенности поворотов
+	scoreQuality(&result, profile, logFile)
+	isValid := result.IsValid
+	validationErrors := result.Errors()
 
 	// Итоговый отчёт
 	if logFile != nil {
@@ -623,35 +707,38 @@ func AnalyzeCompassData(angles []float64, logFile *os.File) (bool, []models.Turn
 		fmt.Fprintf(logFile, "║                    ИТОГОВЫЙ РЕЗУЛЬТАТ                     ║\n")
 		fmt.Fprintf(logFile, "╚════════════════════════════════════════════════════════════╝\n")
 		fmt.Fprintf(logFile, "\nНайдено стабильных сегментов: %d\n", len(segments))
-		fmt.Fprintf(logFile, "Найдено поворотов на ~90°: %d", len(allTurns))
-		if len(allTurns) > 4 {
-			fmt.Fprintf(logFile, " (используем первые 4)")
+		fmt.Fprintf(logFile, "Найдено поворотов на ~%.0f°: %d", profile.StepAngle, len(allTurns))
+		if len(allTurns) > stepCount {
+			fmt.Fprintf(logFile, " (используем первые %d)", stepCount)
 		}
 		fmt.Fprintf(logFile, "\n")
+		fmt.Fprintf(logFile, "Оценка качества: %.0f/100\n", result.Quality)
+		if len(result.Cycles) > 1 {
+			passed := 0
+			for _, c := range result.Cycles {
+				if c.IsValid {
+					passed++
+				}
+			}
+			fmt.Fprintf(logFile, "Полных циклов поворотов: %d (прошли проверки: %d, режим вердикта: %s)\n",
+				len(result.Cycles), passed, params.CycleVerdict)
+		}
 		
 		if isValid {
 			fmt.Fprintf(logFile, "\n✓✓✓ КАЛИБРОВКА УСПЕШНА ✓✓✓\n")
-			fmt.Fprintf(logFile, "\nПоследовательность поворотов (первые 4):\n")
-			for i, turn := range turns {
-				direction := "по часовой ✓"
-				if !turn.IsClockwise {
-					direction = "против часовой ✗"
-				}
-				fmt.Fprintf(logFile, "  %d. %.2f° → %.2f° (Δ = %.2f°, направление: %s)\n", 
-					i+1, turn.StartAngle, turn.EndAngle, turn.Diff, direction)
+			fmt.Fprintf(logFile, "\nПоследовательность поворотов (первые %d):\n", stepCount)
+			for i, turn := range result.Turns {
+				fmt.Fprintf(logFile, "  %d. %.2f° → %.2f° (Δ = %.2f°, направление: %s, уверенность %.0f)\n",
+					i+1, turn.StartAngle, turn.EndAngle, turn.Diff, turnDirectionLabel(turn, profile), turn.Confidence)
 			}
 			
-			// Если было больше 4 поворотов, показываем дополнительные
-			if len(allTurns) > 4 {
+			// Если было больше поворотов, чем в профиле, показываем дополнительные
+			if len(allTurns) > stepCount {
 				fmt.Fprintf(logFile, "\nДополнительные повороты (вне основного круга):\n")
-				for i := 4; i < len(allTurns); i++ {
+				for i := stepCount; i < len(allTurns); i++ {
 					turn := allTurns[i]
-					direction := "по часовой ✓"
-					if !turn.IsClockwise {
-						direction = "против часовой ✗"
-					}
-					fmt.Fprintf(logFile, "  %d. %.2f° → %.2f° (Δ = %.2f°, направление: %s)\n", 
-						i+1, turn.StartAngle, turn.EndAngle, turn.Diff, direction)
+					fmt.Fprintf(logFile, "  %d. %.2f° → %.2f° (Δ = %.2f°, направление: %s)\n",
+						i+1, turn.StartAngle, turn.EndAngle, turn.Diff, turnDirectionLabel(turn, profile))
 				}
 			}
 		} else {
@@ -664,12 +751,8 @@ func AnalyzeCompassData(angles []float64, logFile *os.File) (bool, []models.Turn
 			if len(turns) > 0 {
 				fmt.Fprintf(logFile, "\nЧастичные результаты (найденные повороты):\n")
 				for i, turn := range turns {
-					direction := "по часовой ✓"
-					if !turn.IsClockwise {
-						direction = "ПРОТИВ часовой ✗"
-					}
-					fmt.Fprintf(logFile, "  %d. %.2f° → %.2f° (Δ = %.2f°, знак=%.2f°, %s)\n", 
-						i+1, turn.StartAngle, turn.EndAngle, turn.Diff, turn.SignedDiff, direction)
+					fmt.Fprintf(logFile, "  %d. %.2f° → %.2f° (Δ = %.2f°, знак=%.2f°, %s)\n",
+						i+1, turn.StartAngle, turn.EndAngle, turn.Diff, turn.SignedDiff, turnDirectionLabel(turn, profile))
 				}
 			}
 		}
@@ -677,15 +760,19 @@ func AnalyzeCompassData(angles []float64, logFile *os.File) (bool, []models.Turn
 		fmt.Fprintf(logFile, "\n" + strings.Repeat("=", 60) + "\n")
 	}
 
-	return isValid, turns
+	return result
 }
 
-// GetSegments возвращает найденные стабильные сегменты для визуализации
-func GetSegments(angles []float64) []AngleSegment {
-	stabilityThreshold := 5.0
-	minStableLen := 2  // Те же параметры, что в AnalyzeCompassData
-	maxOutliers := 0   // Те же параметры, что в AnalyzeCompassData
-	return findStableSegments(angles, stabilityThreshold, minStableLen, maxOutliers, nil)
+// turnDirectionLabel возвращает направление поворота с отметкой соответствия профилю
+func turnDirectionLabel(turn models.Turn, profile models.ProcedureProfile) string {
+	direction := models.Clockwise
+	if !turn.IsClockwise {
+		direction = models.CounterClockwise
+	}
+	if profile.Matches(turn) {
+		return direction.String() + " ✓"
+	}
+	return direction.String() + " ✗"
 }
 
 // PrintAnalysis выводит результаты анализа
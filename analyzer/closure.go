@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// Closure - возврат компаса к начальному курсу после полного оборота
+type Closure struct {
+	StartHeading  float64 `json:"startHeading"`  // Курс до первого поворота, градусы
+	EndHeading    float64 `json:"endHeading"`    // Курс после последнего поворота, градусы
+	Error         float64 `json:"error"`         // Знаковое расхождение EndHeading - StartHeading, градусы
+	Visits        int     `json:"visits"`        // Сколько стабильных курсов цикла пришлось на начальную позицию
+	Repeatability float64 `json:"repeatability"` // Размах курсов в начальной позиции, градусы
+}
+
+// measureClosure сравнивает курс до первого поворота цепочки с курсом после
+// последнего. Повторяемость - наибольшее расхождение между курсами цикла,
+// которые относятся к начальной позиции (ближе половины угла шага к начальному курсу).
+func measureClosure(segments []AngleSegment, turns []models.Turn, profile models.ProcedureProfile) (Closure, bool) {
+	if len(turns) == 0 {
+		return Closure{}, false
+	}
+	first, last := turns[0].FromSegment, turns[len(turns)-1].ToSegment
+	if first < 0 || last >= len(segments) || first > last {
+		return Closure{}, false
+	}
+
+	closure := Closure{
+		StartHeading: segments[first].AvgAngle,
+		EndHeading:   segments[last].AvgAngle,
+	}
+	closure.Error = circular.Diff(closure.StartHeading, closure.EndHeading)
+
+	var visits []float64
+	for i := first; i <= last; i++ {
+		if circular.Distance(segments[i].AvgAngle, closure.StartHeading) < profile.StepAngle/2 {
+			visits = append(visits, segments[i].AvgAngle)
+		}
+	}
+	closure.Visits = len(visits)
+	for i := range visits {
+		for j := i + 1; j < len(visits); j++ {
+			if d := circular.Distance(visits[i], visits[j]); d > closure.Repeatability {
+				closure.Repeatability = d
+			}
+		}
+	}
+	return closure, true
+}
+
+// validateClosure проверяет, что после полного оборота компас вернулся
+// к начальному курсу: курс стабильного сегмента после последнего поворота
+// отличается от курса до первого поворота не больше допуска возврата
+// (ProcedureProfile.ReturnToleranceFor). В отличие от проверки суммы
+// поворотов, которая складывает углы отдельных поворотов, проверка
+// сравнивает сами курсы и находит накопленную ошибку цикла.
+// Для профилей, цикл которых не возвращает к начальному курсу
+// (сумма поворотов не кратна 360°), проверка пропускается.
+//
+// Параметры:
+//   - segments: стабильные сегменты, между которыми найдены повороты
+//   - turns: выбранная последовательность поворотов
+//   - params: параметры анализа (допуск возврата по умолчанию)
+//   - profile: профиль процедуры
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - []models.Check: результат проверки (пусто, если проверка пропущена)
+//   - *Closure: начальный и конечный курс, расхождение и повторяемость (nil, если проверка пропущена)
+func validateClosure(segments []AngleSegment, turns []models.Turn, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) ([]models.Check, *Closure) {
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== ПРОВЕРКА ВОЗВРАТА К НАЧАЛЬНОМУ КУРСУ ===\n")
+	}
+	if !profile.FullRotation() {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "ℹ Цикл профиля «%s» (%.0f°) не возвращает к начальному курсу - проверка пропущена\n",
+				profile.Name, profile.TotalAngle())
+		}
+		return nil, nil
+	}
+	closure, ok := measureClosure(segments, turns, profile)
+	if !ok {
+		return nil, nil
+	}
+
+	tolerance := profile.ReturnToleranceFor(params)
+	if logFile != nil {
+		fmt.Fprintf(logFile, "Начальный курс: %.2f°, курс после оборота: %.2f°, расхождение: %+.2f°\n",
+			closure.StartHeading, closure.EndHeading, closure.Error)
+		fmt.Fprintf(logFile, "Повторяемость в начальной позиции: %.2f° (курсов: %d)\n", closure.Repeatability, closure.Visits)
+	}
+
+	deviation := math.Abs(closure.Error)
+	check := models.Check{
+		Name:     models.CheckClosure,
+		Passed:   deviation <= tolerance,
+		Measured: deviation,
+		Limit:    tolerance,
+		Unit:     "°",
+	}
+	if check.Passed {
+		check.Message = fmt.Sprintf("Курс после полного оборота совпадает с начальным: расхождение %.2f° ≤ %.2f°",
+			check.Measured, tolerance)
+	} else {
+		check.Reason = models.ReasonReturnMismatch
+		check.Message = check.Reason.Format(closure.EndHeading, closure.StartHeading, check.Measured, tolerance)
+	}
+	logCheck(logFile, check)
+	return []models.Check{check}, &closure
+}
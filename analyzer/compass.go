@@ -100,7 +100,8 @@ package analyzer
 //   - models.CompassResult: результаты анализа с информацией о найденных поворотах
 //     (Angles) и ошибках (Errors), а также общий статус валидности (IsValid).
 //
-// TODO: Добавить проверку временных интервалов между найденными поворотами и общего времени анализа на основе меток времени в data.
+// Проверка временных интервалов (неподвижность на курсах, длительность поворотов
+// и всего цикла) реализована в validateTiming по ограничениям профиля процедуры.
 //
 // Пример:
 //
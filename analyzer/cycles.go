@@ -0,0 +1,344 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// Cycle - один полный цикл поворотов профиля (StepCount поворотов подряд)
+type Cycle struct {
+	Index    int            `json:"index"`             // Номер цикла в файле, с 1
+	Turns    []models.Turn  `json:"turns"`             // Повороты цикла
+	Sum      float64        `json:"sum"`               // Сумма поворотов, градусы
+	Checks   []models.Check `json:"checks"`            // Проверки цикла (последовательность, возврат, время)
+	Closure  *Closure       `json:"closure,omitempty"` // Возврат к начальному курсу
+	IsValid  bool           `json:"isValid"`           // Цикл прошел проверки
+	Selected bool           `json:"selected"`          // Цикл выбран основной последовательностью (AnalysisResult.Turns)
+}
+
+// Reason возвращает код первой причины отбраковки цикла
+func (c Cycle) Reason() models.ReasonCode {
+	for _, check := range c.Checks {
+		if check.Failed() {
+			return check.Reason
+		}
+	}
+	return ""
+}
+
+// PositionStats - курсы одной номинальной позиции стенда во всех циклах
+type PositionStats struct {
+	Position      int       `json:"position"`             // Номер позиции: 0 - начальная, k - после k поворотов
+	Nominal       float64   `json:"nominal"`              // Номинальный поворот от начальной позиции, градусы
+	Headings      []float64 `json:"headings"`             // Курс в позиции в каждом цикле, градусы
+	Mean          float64   `json:"mean"`                 // Круговое среднее курсов, градусы
+	Repeatability float64   `json:"repeatability"`        // Размах курсов между циклами, градусы
+	Reverse       []float64 `json:"reverse,omitempty"`    // Курсы в позиции при вращении в обратном направлении
+	Hysteresis    *float64  `json:"hysteresis,omitempty"` // Среднее обратного хода минус среднее прямого, градусы
+}
+
+// isChain проверяет, что каждый поворот последовательности начинается
+// на сегменте, где закончился предыдущий
+func isChain(turns []models.Turn) bool {
+	for j := 1; j < len(turns); j++ {
+		if turns[j].FromSegment != turns[j-1].ToSegment {
+			return false
+		}
+	}
+	return true
+}
+
+// cyclesFrom разбивает повороты на непересекающиеся цепочки из stepCount
+// поворотов подряд, отсчитывая разбиение от поворота anchor в обе стороны
+func cyclesFrom(allTurns []models.Turn, anchor, stepCount int) [][]models.Turn {
+	var cycles [][]models.Turn
+	for i := anchor - stepCount; i >= 0; {
+		if sequence := allTurns[i : i+stepCount]; isChain(sequence) {
+			cycles = append([][]models.Turn{sequence}, cycles...)
+			i -= stepCount
+		} else {
+			i--
+		}
+	}
+	for i := anchor; i+stepCount <= len(allTurns); {
+		if sequence := allTurns[i : i+stepCount]; isChain(sequence) {
+			cycles = append(cycles, sequence)
+			i += stepCount
+		} else {
+			i++
+		}
+	}
+	return cycles
+}
+
+// findCycles находит все полные циклы: непересекающиеся цепочки из StepCount
+// поворотов подряд (следующий цикл может начинаться на последнем сегменте
+// предыдущего). Из возможных разбиений выбирается то, что дает больше циклов,
+// а при равенстве - то, в котором выбранная последовательность selected
+// является одним из циклов.
+func findCycles(allTurns, selected []models.Turn, stepCount int) [][]models.Turn {
+	var best [][]models.Turn
+	for i := 0; i+stepCount <= len(allTurns); i++ {
+		if !isChain(allTurns[i : i+stepCount]) {
+			continue
+		}
+		cycles := cyclesFrom(allTurns, i, stepCount)
+		if len(cycles) > len(best) || (len(cycles) == len(best) && sameTurns(allTurns[i:i+stepCount], selected)) {
+			best = cycles
+		}
+	}
+	return best
+}
+
+// alignToCycles заменяет выбранную последовательность лучшим полным циклом,
+// если в файле несколько циклов, а последовательность пересекает границу
+// между ними (например, со второго поворота первого цикла по первый поворот
+// второго). Лучший цикл - с суммой поворотов, ближайшей к номинальной,
+// как в findBestTurnSequence. При одном цикле последовательность не меняется.
+func alignToCycles(allTurns, selected []models.Turn, profile models.ProcedureProfile, logFile *os.File) []models.Turn {
+	cycles := findCycles(allTurns, selected, profile.StepCount)
+	if len(cycles) < 2 {
+		return selected
+	}
+	best, bestDeviation := -1, math.Inf(1)
+	for i, turns := range cycles {
+		if sameTurns(turns, selected) {
+			return selected
+		}
+		var sum float64
+		for _, turn := range turns {
+			sum += turn.Diff
+		}
+		if deviation := math.Abs(sum - profile.TotalAngle()); deviation < bestDeviation {
+			best, bestDeviation = i, deviation
+		}
+	}
+	if logFile != nil {
+		fmt.Fprintf(logFile, "ℹ Последовательность пересекает границу между циклами - выбран цикл %d из %d (отклонение суммы %.2f°)\n",
+			best+1, len(cycles), bestDeviation)
+	}
+	return cycles[best]
+}
+
+// cycleHeadings возвращает курсы позиций цикла: до первого поворота и после
+// каждого поворота, кроме последнего (после последнего - снова начальная позиция)
+func cycleHeadings(turns []models.Turn) []float64 {
+	headings := make([]float64, len(turns))
+	for k, turn := range turns {
+		headings[k] = turn.StartAngle
+	}
+	return headings
+}
+
+// sameTurns сообщает, что две последовательности состоят из одних и тех же поворотов
+func sameTurns(a, b []models.Turn) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].FromSegment != b[i].FromSegment || a[i].ToSegment != b[i].ToSegment {
+			return false
+		}
+	}
+	return true
+}
+
+// spread возвращает наибольшее расстояние по окружности между углами набора
+func spread(angles []float64) float64 {
+	var max float64
+	for i := range angles {
+		for j := i + 1; j < len(angles); j++ {
+			max = math.Max(max, circular.Distance(angles[i], angles[j]))
+		}
+	}
+	return max
+}
+
+// analyzeCycles находит все полные циклы поворотов в файле и проверяет каждый
+// (последовательность, возврат к начальному курсу, время), считает
+// повторяемость курса в каждой номинальной позиции между циклами и, если
+// в файле есть циклы в обратном направлении, гистерезис - разницу между
+// курсами одной позиции при прямом и обратном вращении.
+//
+// Параметры:
+//   - data: записи компаса (для проверки времени)
+//   - segments: стабильные сегменты
+//   - allTurns: все повороты в направлении профиля
+//   - selected: последовательность, выбранная для основного результата
+//   - params: параметры анализа (режим вердикта по циклам)
+//   - profile: профиль процедуры
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - []Cycle: циклы по порядку в файле
+//   - []PositionStats: статистика позиций (пусто, если циклов меньше двух)
+func analyzeCycles(data []models.CompassData, segments []AngleSegment, allTurns, selected []models.Turn, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) ([]Cycle, []PositionStats) {
+	var cycles []Cycle
+	for i, turns := range findCycles(allTurns, selected, profile.StepCount) {
+		cycle := Cycle{
+			Index:    i + 1,
+			Turns:    turns,
+			Selected: sameTurns(turns, selected),
+		}
+		for _, turn := range turns {
+			cycle.Sum += turn.Diff
+		}
+		cycle.Checks = validateTurnSequence(turns, params, profile, nil)
+		closureChecks, closure := validateClosure(segments, turns, params, profile, nil)
+		cycle.Checks = append(cycle.Checks, closureChecks...)
+		cycle.Closure = closure
+		cycle.Checks = append(cycle.Checks, validateTiming(data, segments, turns, profile, nil)...)
+		cycle.IsValid = true
+		for _, c := range cycle.Checks {
+			if c.Failed() {
+				cycle.IsValid = false
+				break
+			}
+		}
+		cycles = append(cycles, cycle)
+	}
+	if len(cycles) == 0 {
+		return nil, nil
+	}
+
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== ЦИКЛЫ ПОВОРОТОВ ===\n")
+		fmt.Fprintf(logFile, "Найдено полных циклов: %d (режим вердикта: %s)\n", len(cycles), params.CycleVerdict)
+		for _, c := range cycles {
+			mark, closure, selectedMark := "✓", "", ""
+			if !c.IsValid {
+				mark = fmt.Sprintf("✗ [%s]", c.Reason())
+			}
+			if c.Closure != nil {
+				closure = fmt.Sprintf(", возврат %+.2f°", c.Closure.Error)
+			}
+			if c.Selected {
+				selectedMark = " (выбран)"
+			}
+			fmt.Fprintf(logFile, "Цикл %d%s: записи %d–%d, сумма %.2f°%s %s\n", c.Index, selectedMark,
+				c.Turns[0].StartIndex, c.Turns[len(c.Turns)-1].EndIndex, c.Sum, closure, mark)
+		}
+	}
+	if len(cycles) < 2 {
+		return cycles, nil
+	}
+
+	// Повторяемость: курсы одной позиции в разных циклах
+	positions := make([]PositionStats, profile.StepCount)
+	for k := range positions {
+		positions[k] = PositionStats{Position: k, Nominal: float64(k) * profile.StepAngle}
+		for _, c := range cycles {
+			positions[k].Headings = append(positions[k].Headings, cycleHeadings(c.Turns)[k])
+		}
+		positions[k].Mean = circular.Mean(positions[k].Headings)
+		positions[k].Repeatability = spread(positions[k].Headings)
+	}
+
+	// Гистерезис: те же позиции при вращении в обратном направлении
+	reverse := profile
+	reverse.Direction = models.CounterClockwise
+	if profile.Direction == models.CounterClockwise {
+		reverse.Direction = models.Clockwise
+	}
+	reverseTurns := findStepTurns(segments, reverse, profile.StepToleranceFor(params), nil)
+	reverseCycles := findCycles(reverseTurns, nil, profile.StepCount)
+	forward := make(map[int]bool)
+	for _, c := range cycles {
+		for _, turn := range c.Turns {
+			forward[turn.FromSegment], forward[turn.ToSegment] = true, true
+		}
+	}
+	for _, turns := range reverseCycles {
+		// Курсы обратного цикла: до каждого поворота и после последнего;
+		// сегменты, общие с прямыми циклами, не учитываются
+		var headings []float64
+		for _, turn := range turns {
+			if !forward[turn.FromSegment] {
+				headings = append(headings, turn.StartAngle)
+			}
+		}
+		if last := turns[len(turns)-1]; !forward[last.ToSegment] {
+			headings = append(headings, last.EndAngle)
+		}
+		for _, heading := range headings {
+			nearest := -1
+			for k := range positions {
+				if circular.Distance(heading, positions[k].Mean) < profile.StepAngle/2 &&
+					(nearest < 0 || circular.Distance(heading, positions[k].Mean) < circular.Distance(heading, positions[nearest].Mean)) {
+					nearest = k
+				}
+			}
+			if nearest >= 0 {
+				positions[nearest].Reverse = append(positions[nearest].Reverse, heading)
+			}
+		}
+	}
+	for k := range positions {
+		if len(positions[k].Reverse) > 0 {
+			h := circular.Diff(positions[k].Mean, circular.Mean(positions[k].Reverse))
+			positions[k].Hysteresis = &h
+		}
+	}
+
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\nПовторяемость курса по позициям (%d циклов):\n", len(cycles))
+		for _, p := range positions {
+			headings := make([]string, len(p.Headings))
+			for i, h := range p.Headings {
+				headings[i] = fmt.Sprintf("%.2f°", h)
+			}
+			fmt.Fprintf(logFile, "  Позиция %d (+%.0f°): %s, размах %.2f°", p.Position, p.Nominal, strings.Join(headings, ", "), p.Repeatability)
+			if p.Hysteresis != nil {
+				fmt.Fprintf(logFile, ", гистерезис %+.2f°", *p.Hysteresis)
+			}
+			fmt.Fprintf(logFile, "\n")
+		}
+		if len(reverseCycles) == 0 {
+			fmt.Fprintf(logFile, "ℹ Циклов в обратном направлении нет - гистерезис не вычислен\n")
+		}
+	}
+	return cycles, positions
+}
+
+// cyclesCheck выносит вердикт по всем циклам в режимах CycleVerdictAll
+// и CycleVerdictMajority. В режиме CycleVerdictBest и при одном цикле
+// вердикт определяют проверки выбранной последовательности, и проверка не нужна.
+func cyclesCheck(cycles []Cycle, params models.AnalysisParams) (models.Check, bool) {
+	if len(cycles) < 2 || params.CycleVerdict == models.CycleVerdictBest {
+		return models.Check{}, false
+	}
+
+	passed := 0
+	var failed []string
+	for _, c := range cycles {
+		if c.IsValid {
+			passed++
+		} else {
+			failed = append(failed, fmt.Sprintf("%d [%s]", c.Index, c.Reason()))
+		}
+	}
+	required := len(cycles)
+	if params.CycleVerdict == models.CycleVerdictMajority {
+		required = len(cycles)/2 + 1
+	}
+
+	check := models.Check{
+		Name:     models.CheckCycles,
+		Passed:   passed >= required,
+		Measured: float64(passed),
+		Limit:    float64(required),
+		Unit:     "шт",
+		Message: fmt.Sprintf("Проверки прошли %d из %d циклов поворотов (режим «%s», требуется %d)",
+			passed, len(cycles), params.CycleVerdict, required),
+	}
+	if !check.Passed {
+		check.Reason = models.ReasonCyclesFailed
+		check.Message = check.Reason.Format(passed, len(cycles), params.CycleVerdict, strings.Join(failed, ", "))
+	}
+	return check, true
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// DeviationPoint - расхождение компенсированного азимута с «Азимут RAW»
+// в одной позиции цикла поворотов
+type DeviationPoint struct {
+	Position    int     `json:"position"`    // Номер позиции в цепочке поворотов (с 1)
+	Heading     float64 `json:"heading"`     // Курс по «Азимут RAW» (круговое среднее), градусы
+	Compensated float64 `json:"compensated"` // Курс по компенсированному азимуту (круговое среднее), градусы
+	Error       float64 `json:"error"`       // Знаковое расхождение Compensated - Heading, градусы
+	Samples     int     `json:"samples"`     // Записей телеметрии в позиции
+	Flipped     int     `json:"flipped"`     // Записей, в которых компенсированный азимут повернут на 180°
+	Gross       int     `json:"gross"`       // Записей, в которых компенсированный азимут отличается от RAW больше чем на 90°
+}
+
+// Deviation - кривая девиации: расхождение компенсированного азимута
+// с «Азимут RAW» в зависимости от курса
+type Deviation struct {
+	Points []DeviationPoint `json:"points"` // Точки кривой по возрастанию курса
+	Max    float64          `json:"max"`    // Наибольшее по модулю расхождение, градусы
+	RMS    float64          `json:"rms"`    // Среднеквадратичное расхождение, градусы
+	Gross  int              `json:"gross"`  // Записей с расхождением больше grossDeviation
+}
+
+// grossDeviation - расхождение компенсированного азимута с «Азимут RAW»,
+// которое не объясняется девиацией: компенсация ошиблась грубо
+// или прибор повернул систему координат без кода поворота
+const grossDeviation = 90.0
+
+// measureDeviation строит кривую девиации: в каждой позиции цикла (см.
+// telemetryPositions) сравнивает круговые средние «Азимут RAW»
+// и компенсированного азимута (см. models.TelemetryRecord.CompensatedAzimuth).
+// Точки кривой упорядочены по курсу, чтобы по ним можно было построить
+// зависимость ошибки курса от курса. Записи, в которых расхождение больше
+// grossDeviation, не отбрасываются и не поворачиваются, а подсчитываются
+// и попадают в лог. Кривая только описывает работу
+// компенсации прибора и на вердикт не влияет.
+//
+// Параметры:
+//   - records: записи телеметрии компаса
+//   - positions: позиции цикла поворотов с записями телеметрии
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - *Deviation: точки кривой, наибольшее и среднеквадратичное расхождение (nil, если позиций нет)
+func measureDeviation(records []models.TelemetryRecord, positions []telemetryPosition, logFile *os.File) *Deviation {
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== КРИВАЯ ДЕВИАЦИИ (компенсированный азимут − Азимут RAW) ===\n")
+	}
+	if len(positions) == 0 {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "⚠ Нет позиций с записями телеметрии - кривая девиации не построена\n")
+		}
+		return nil
+	}
+
+	deviation := &Deviation{}
+	sumSquares := 0.0
+	for _, pos := range positions {
+		raw := make([]float64, len(pos.Records))
+		compensated := make([]float64, len(pos.Records))
+		point := DeviationPoint{Position: pos.Position, Samples: len(pos.Records)}
+		for i, idx := range pos.Records {
+			raw[i] = records[idx].AzimuthRaw
+			var flipped bool
+			compensated[i], flipped = records[idx].CompensatedAzimuth()
+			if flipped {
+				point.Flipped++
+			}
+			if math.Abs(circular.Diff(raw[i], compensated[i])) > grossDeviation {
+				point.Gross++
+			}
+		}
+		point.Heading = circular.Mean(raw)
+		point.Compensated = circular.Mean(compensated)
+		point.Error = circular.Diff(point.Heading, point.Compensated)
+
+		deviation.Points = append(deviation.Points, point)
+		deviation.Max = math.Max(deviation.Max, math.Abs(point.Error))
+		deviation.Gross += point.Gross
+		sumSquares += point.Error * point.Error
+	}
+	deviation.RMS = math.Sqrt(sumSquares / float64(len(deviation.Points)))
+	sort.SliceStable(deviation.Points, func(i, j int) bool {
+		return deviation.Points[i].Heading < deviation.Points[j].Heading
+	})
+
+	if logFile != nil {
+		for _, p := range deviation.Points {
+			fmt.Fprintf(logFile, "Курс %7.2f° (позиция %d): компенсированный %7.2f°, девиация %+.2f°, записей %d",
+				p.Heading, p.Position, p.Compensated, p.Error, p.Samples)
+			if p.Flipped > 0 {
+				fmt.Fprintf(logFile, " (повернуто на 180°: %d)", p.Flipped)
+			}
+			if p.Gross > 0 {
+				fmt.Fprintf(logFile, " ⚠ расхождение больше %.0f°: %d", grossDeviation, p.Gross)
+			}
+			fmt.Fprintln(logFile)
+		}
+		fmt.Fprintf(logFile, "Девиация: наибольшая %.2f°, СКО %.2f°\n", deviation.Max, deviation.RMS)
+		if deviation.Gross > 0 {
+			fmt.Fprintf(logFile, "⚠ Записей с расхождением больше %.0f°: %d - компенсация азимута ошибается грубо\n", grossDeviation, deviation.Gross)
+		}
+	}
+	return deviation
+}
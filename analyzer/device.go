@@ -0,0 +1,178 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"compass_analyzer/models"
+)
+
+// DevicePhase - строки подряд с одинаковыми словами состояния и калибровки
+type DevicePhase struct {
+	FirstLine   int                `json:"firstLine"`       // Первая строка файла
+	LastLine    int                `json:"lastLine"`        // Последняя строка файла
+	Start       string             `json:"start"`           // Время первой строки
+	End         string             `json:"end"`             // Время последней строки
+	Rows        int                `json:"rows"`            // Количество строк
+	Status      uint32             `json:"status"`          // Слово «Статус, hex»
+	Calibration uint32             `json:"calibration"`     // Слово «Калибровка, hex»
+	State       models.DeviceState `json:"state,omitempty"` // Состояние калибровки по словарю флагов
+	Flags       []string           `json:"flags"`           // Имена совпавших флагов словаря
+	Title       string             `json:"title"`           // Описание состояния для оператора
+	Unknown     bool               `json:"unknown"`         // Значения нет в словаре флагов
+}
+
+// DeviceReport - состояния калибровки, о которых сообщает сам прибор
+type DeviceReport struct {
+	Firmware    string        `json:"firmware"`    // Прошивка словаря флагов
+	Phases      []DevicePhase `json:"phases"`      // Смена состояний прибора по строкам файла
+	RunningFrom int           `json:"runningFrom"` // Первая строка «калибровка выполняется» (0 - нет)
+	RunningTo   int           `json:"runningTo"`   // Последняя строка «калибровка выполняется» (0 - нет)
+	DoneFrom    int           `json:"doneFrom"`    // Первая строка «калибровка завершена» (0 - нет)
+	ErrorRows   int           `json:"errorRows"`   // Строк, в которых прибор сообщает об ошибке
+	UnknownRows int           `json:"unknownRows"` // Строк со значениями, которых нет в словаре
+}
+
+// decodeDeviceStates переводит столбцы «Статус, hex» и «Калибровка, hex»
+// в именованные состояния по словарю флагов и находит строки, в которых
+// прибор сообщает, что калибровка выполняется, завершена или завершилась
+// ошибкой. Соседние строки с одинаковыми словами объединяются в фазы.
+func decodeDeviceStates(records []models.TelemetryRecord, dict models.FlagDictionary) DeviceReport {
+	report := DeviceReport{Firmware: dict.Firmware}
+	for i, r := range records {
+		decoded := dict.Decode(r.Status, r.Calibration)
+		state := decoded.State()
+		switch state {
+		case models.DeviceStateRunning:
+			if report.RunningFrom == 0 {
+				report.RunningFrom = r.Line
+			}
+			report.RunningTo = r.Line
+		case models.DeviceStateDone:
+			if report.DoneFrom == 0 {
+				report.DoneFrom = r.Line
+			}
+		case models.DeviceStateError:
+			report.ErrorRows++
+		}
+		if decoded.Unknown {
+			report.UnknownRows++
+		}
+
+		if n := len(report.Phases); n > 0 && i > 0 &&
+			records[i-1].Status == r.Status && records[i-1].Calibration == r.Calibration {
+			phase := &report.Phases[n-1]
+			phase.LastLine = r.Line
+			phase.End = r.TimeString
+			phase.Rows++
+			continue
+		}
+		phase := DevicePhase{
+			FirstLine:   r.Line,
+			LastLine:    r.Line,
+			Start:       r.TimeString,
+			End:         r.TimeString,
+			Rows:        1,
+			Status:      r.Status,
+			Calibration: r.Calibration,
+			State:       state,
+			Title:       decoded.String(),
+			Unknown:     decoded.Unknown,
+		}
+		for _, e := range append(append([]models.FlagEntry(nil), decoded.Calibration...), decoded.Status...) {
+			phase.Flags = append(phase.Flags, e.Name)
+		}
+		report.Phases = append(report.Phases, phase)
+	}
+	return report
+}
+
+// validateDeviceStates проверяет состояния, о которых сообщает сам прибор:
+//   - device_error: ни в одной строке прибор не сообщает об ошибке
+//     (флаги словаря с состоянием error). Пока ни одного кода ошибки
+//     прошивки в телеметрии не встречалось и значения флагов ошибки
+//     не подтверждены, это предупреждение и на вердикт не влияет;
+//   - device_done: прибор сообщил о завершении калибровки (предупреждение,
+//     не влияет на вердикт).
+//
+// Если в файле нет ни одного состояния калибровки (старая прошивка не
+// заполняет столбцы), проверка завершения пропускается.
+//
+// Параметры:
+//   - records: записи телеметрии компаса
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - []models.Check: результаты проверок
+//   - DeviceReport: фазы состояний прибора и строки калибровки
+func validateDeviceStates(records []models.TelemetryRecord, logFile *os.File) ([]models.Check, DeviceReport) {
+	report := decodeDeviceStates(records, models.Flags())
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== СОСТОЯНИЯ ПРИБОРА (словарь флагов %s) ===\n", report.Firmware)
+		for _, p := range report.Phases {
+			mark := " "
+			switch {
+			case p.State == models.DeviceStateError:
+				mark = "✗"
+			case p.Unknown:
+				mark = "?"
+			}
+			fmt.Fprintf(logFile, "%s строки %d–%d (%s – %s): 0x%02X/0x%02X %s\n",
+				mark, p.FirstLine, p.LastLine, p.Start, p.End, p.Status, p.Calibration, p.Title)
+		}
+		if report.RunningFrom > 0 {
+			fmt.Fprintf(logFile, "Калибровка выполняется: строки %d–%d\n", report.RunningFrom, report.RunningTo)
+		}
+		if report.DoneFrom > 0 {
+			fmt.Fprintf(logFile, "Калибровка завершена: со строки %d\n", report.DoneFrom)
+		}
+		if report.UnknownRows > 0 {
+			fmt.Fprintf(logFile, "⚠ Строк со значениями флагов, которых нет в словаре: %d\n", report.UnknownRows)
+		}
+	}
+
+	errorCheck := models.Check{
+		Name:     models.CheckDeviceError,
+		Passed:   report.ErrorRows == 0,
+		Measured: float64(report.ErrorRows),
+		Unit:     "шт",
+		Advisory: true,
+		Message:  "Прибор не сообщает об ошибках",
+	}
+	if !errorCheck.Passed {
+		var errors []string
+		for _, p := range report.Phases {
+			if p.State == models.DeviceStateError {
+				errors = append(errors, fmt.Sprintf("%s (строки %d–%d)", p.Title, p.FirstLine, p.LastLine))
+			}
+		}
+		errorCheck.Reason = models.ReasonDeviceError
+		errorCheck.Message = errorCheck.Reason.Format(report.ErrorRows, strings.Join(errors, "; "))
+	}
+	checks := []models.Check{errorCheck}
+
+	if report.RunningFrom > 0 || report.DoneFrom > 0 {
+		doneCheck := models.Check{
+			Name:     models.CheckDeviceDone,
+			Passed:   report.DoneFrom > 0,
+			Unit:     "шт",
+			Advisory: true,
+		}
+		if doneCheck.Passed {
+			doneCheck.Measured = float64(report.DoneFrom)
+			doneCheck.Message = fmt.Sprintf("Прибор сообщил о завершении калибровки в строке %d", report.DoneFrom)
+		} else {
+			doneCheck.Reason = models.ReasonDeviceIncomplete
+			doneCheck.Message = doneCheck.Reason.Format(report.RunningFrom, report.RunningTo)
+		}
+		checks = append(checks, doneCheck)
+	} else if logFile != nil {
+		fmt.Fprintf(logFile, "ℹ Прибор не сообщает о состоянии калибровки - проверка завершения пропущена\n")
+	}
+
+	for _, c := range checks {
+		logCheck(logFile, c)
+	}
+	return checks, report
+}
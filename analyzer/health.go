@@ -0,0 +1,281 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"compass_analyzer/models"
+)
+
+// Параметры проверки исправности датчиков
+const (
+	healthMinSamples      = 10   // Минимум записей для проверки зависшего азимута
+	healthMaxStuckShare   = 80.0 // Допустимая доля записей подряд с одним значением азимута, %
+	healthMaxZeroRun      = 2    // Допустимая серия строк с нулевыми показаниями всех датчиков
+	healthMaxRepeatedRows = 30   // Допустимая серия строк с одинаковыми показаниями датчиков
+)
+
+// healthColumn - столбец телеметрии с физическим диапазоном значений
+type healthColumn struct {
+	name     string
+	value    func(r models.TelemetryRecord) float64
+	min, max float64
+	maxStep  float64 // Наибольшее изменение между соседними строками; 0 - не проверяется
+}
+
+// healthColumns - проверяемые столбцы. Азимут между соседними строками
+// может измениться на любой угол (поворот стенда), поэтому скачки азимута
+// не проверяются. Компенсированные углы пересчитываются в систему координат
+// по коду «Поворот RAW» и при его смене переворачиваются на 180°, поэтому
+// для них проверяется только диапазон. Диапазоны температуры, влажности и давления - рабочие
+// диапазоны датчиков HDC1080 и DPS310.
+var healthColumns = []healthColumn{
+	{"Азимут RAW", func(r models.TelemetryRecord) float64 { return r.AzimuthRaw }, 0, 360, 0},
+	{"Дифферент RAW", func(r models.TelemetryRecord) float64 { return r.PitchRaw }, -90, 90, 90},
+	{"Крен RAW", func(r models.TelemetryRecord) float64 { return r.RollRaw }, -180, 180, 90},
+	{"Азимут", func(r models.TelemetryRecord) float64 { return r.Azimuth }, 0, 360, 0},
+	{"Дифферент", func(r models.TelemetryRecord) float64 { return r.Pitch }, -180, 180, 0},
+	{"Крен", func(r models.TelemetryRecord) float64 { return r.Roll }, -180, 180, 0},
+	{"Температура HDC1080", func(r models.TelemetryRecord) float64 { return r.TemperatureHDC }, -40, 125, 5},
+	{"Влажность HDC1080", func(r models.TelemetryRecord) float64 { return r.HumidityHDC }, 0, 100, 30},
+	{"Температура DPS310", func(r models.TelemetryRecord) float64 { return r.TemperatureDPS }, -40, 85, 5},
+	{"Давление DPS310", func(r models.TelemetryRecord) float64 { return r.PressureDPS }, 30, 120, 5},
+}
+
+// SensorHealth - результат проверки исправности датчиков
+type SensorHealth struct {
+	Samples       int      `json:"samples"`       // Количество записей
+	StuckShare    float64  `json:"stuckShare"`    // Доля самой длинной серии одинакового азимута, %
+	ZeroRows      int      `json:"zeroRows"`      // Строки с нулевыми показаниями всех датчиков
+	ZeroRun       int      `json:"zeroRun"`       // Самая длинная серия таких строк
+	RepeatedRun   int      `json:"repeatedRun"`   // Самая длинная серия строк с одинаковыми показаниями
+	Jumps         int      `json:"jumps"`         // Невозможные скачки показаний
+	OutOfRange    int      `json:"outOfRange"`    // Значения вне диапазона столбца
+	AbsentColumns []string `json:"absentColumns"` // Столбцы, равные нулю во всех строках (датчик не установлен)
+
+	Passed bool           `json:"passed"` // Датчики исправны
+	Checks []models.Check `json:"checks"` // Результаты отдельных проверок
+}
+
+// zeroSensors сообщает, что все показания датчиков ориентации в строке нулевые
+func zeroSensors(r models.TelemetryRecord) bool {
+	return r.AzimuthRaw == 0 && r.PitchRaw == 0 && r.RollRaw == 0 &&
+		r.Azimuth == 0 && r.Pitch == 0 && r.Roll == 0 &&
+		r.XYZMagn == [12]byte{} && r.XYZAxs == [6]byte{}
+}
+
+// sameSensors сообщает, что показания всех датчиков в двух строках совпадают
+// (время и служебные поля не сравниваются)
+func sameSensors(a, b models.TelemetryRecord) bool {
+	return a.AzimuthRaw == b.AzimuthRaw && a.PitchRaw == b.PitchRaw && a.RollRaw == b.RollRaw &&
+		a.Azimuth == b.Azimuth && a.Pitch == b.Pitch && a.Roll == b.Roll &&
+		a.TemperatureHDC == b.TemperatureHDC && a.HumidityHDC == b.HumidityHDC &&
+		a.TemperatureDPS == b.TemperatureDPS && a.PressureDPS == b.PressureDPS &&
+		a.XYZMagn == b.XYZMagn && a.XYZAxs == b.XYZAxs
+}
+
+// longestRun возвращает начало и длину самой длинной серии подряд идущих
+// строк, для которых in(i) истинно
+func longestRun(records []models.TelemetryRecord, in func(i int) bool) (start, length int) {
+	runStart, runLen := 0, 0
+	for i := range records {
+		if !in(i) {
+			runLen = 0
+			continue
+		}
+		if runLen == 0 {
+			runStart = i
+		}
+		runLen++
+		if runLen > length {
+			start, length = runStart, runLen
+		}
+	}
+	return start, length
+}
+
+// CheckSensorHealth проверяет исправность датчиков до анализа поворотов,
+// чтобы неисправный прибор не принимался за ошибку процедуры калибровки:
+//   - sensor_stuck: азимут не меняется (одно значение в большей части записей
+//     подряд) - зависший датчик дает один огромный «стабильный» сегмент;
+//   - sensor_dropout: серия строк, в которых все показания датчиков нулевые;
+//   - sensor_repeated: длинная серия строк с одинаковыми показаниями всех датчиков
+//     (прибор повторяет последнее измерение);
+//   - sensor_jump: изменение столбца между соседними строками больше физически
+//     возможного (крен, дифферент, температура, влажность, давление);
+//   - sensor_range: значение столбца вне физического диапазона.
+//
+// Столбцы, равные нулю во всех строках, считаются отсутствующими (датчик
+// не установлен) и не проверяются. Каждая проверка дает свой код причины
+// (models.ReasonSensorStuck и т.д.), отличный от причин процедуры.
+//
+// Параметры:
+//   - records: записи телеметрии компаса
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - SensorHealth: показатели исправности и результаты проверок
+func CheckSensorHealth(records []models.TelemetryRecord, logFile *os.File) SensorHealth {
+	health := SensorHealth{Samples: len(records)}
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== ПРОВЕРКА ИСПРАВНОСТИ ДАТЧИКОВ ===\n")
+	}
+	if len(records) == 0 {
+		health.Passed = true
+		return health
+	}
+
+	// Зависший азимут
+	start, length := longestRun(records, func(i int) bool {
+		return i > 0 && records[i].AzimuthRaw == records[i-1].AzimuthRaw
+	})
+	if length > 0 {
+		start, length = start-1, length+1
+	} else {
+		length = 1
+	}
+	health.StuckShare = float64(length) / float64(len(records)) * 100
+	if len(records) >= healthMinSamples {
+		check := models.Check{
+			Name:     models.CheckSensorStuck,
+			Passed:   health.StuckShare <= healthMaxStuckShare,
+			Measured: health.StuckShare,
+			Limit:    healthMaxStuckShare,
+			Unit:     "%",
+		}
+		if check.Passed {
+			check.Message = fmt.Sprintf("Азимут меняется: самая длинная серия одного значения - %d из %d записей (%.0f%% ≤ %.0f%%)",
+				length, len(records), health.StuckShare, healthMaxStuckShare)
+		} else {
+			check.Reason = models.ReasonSensorStuck
+			check.Message = check.Reason.Format(health.StuckShare, length, len(records), records[start].AzimuthRaw, healthMaxStuckShare)
+		}
+		health.Checks = append(health.Checks, check)
+	} else if logFile != nil {
+		fmt.Fprintf(logFile, "ℹ Записей меньше %d - проверка зависшего азимута пропущена\n", healthMinSamples)
+	}
+
+	// Нулевые показания
+	for _, r := range records {
+		if zeroSensors(r) {
+			health.ZeroRows++
+		}
+	}
+	start, health.ZeroRun = longestRun(records, func(i int) bool { return zeroSensors(records[i]) })
+	check := models.Check{
+		Name:     models.CheckSensorDropout,
+		Passed:   health.ZeroRun <= healthMaxZeroRun,
+		Measured: float64(health.ZeroRun),
+		Limit:    healthMaxZeroRun,
+		Unit:     "шт",
+	}
+	if check.Passed {
+		check.Message = fmt.Sprintf("Строк с нулевыми показаниями датчиков: %d (подряд не больше %d)", health.ZeroRows, healthMaxZeroRun)
+	} else {
+		end := start + health.ZeroRun - 1
+		check.Reason = models.ReasonSensorDropout
+		check.Message = check.Reason.Format(health.ZeroRun, records[start].Line, records[end].Line, healthMaxZeroRun)
+	}
+	health.Checks = append(health.Checks, check)
+
+	// Повторяющиеся строки
+	start, length = longestRun(records, func(i int) bool {
+		return i > 0 && !zeroSensors(records[i]) && sameSensors(records[i], records[i-1])
+	})
+	if length > 0 {
+		start, length = start-1, length+1
+	} else {
+		length = 1
+	}
+	health.RepeatedRun = length
+	check = models.Check{
+		Name:     models.CheckSensorRepeated,
+		Passed:   health.RepeatedRun <= healthMaxRepeatedRows,
+		Measured: float64(health.RepeatedRun),
+		Limit:    healthMaxRepeatedRows,
+		Unit:     "шт",
+	}
+	if check.Passed {
+		check.Message = fmt.Sprintf("Одинаковых строк подряд: %d ≤ %d", health.RepeatedRun, healthMaxRepeatedRows)
+	} else {
+		end := start + health.RepeatedRun - 1
+		check.Reason = models.ReasonSensorRepeated
+		check.Message = check.Reason.Format(health.RepeatedRun, records[start].Line, records[end].Line, healthMaxRepeatedRows)
+	}
+	health.Checks = append(health.Checks, check)
+
+	// Скачки и диапазоны по столбцам (строки с нулевыми показаниями - отдельная неисправность)
+	jumpCheck := models.Check{Name: models.CheckSensorJump, Passed: true, Unit: "шт"}
+	rangeCheck := models.Check{Name: models.CheckSensorRange, Passed: true, Unit: "шт"}
+	for _, col := range healthColumns {
+		absent := true
+		for _, r := range records {
+			if col.value(r) != 0 {
+				absent = false
+				break
+			}
+		}
+		if absent {
+			health.AbsentColumns = append(health.AbsentColumns, col.name)
+			continue
+		}
+
+		prev := -1
+		for i, r := range records {
+			if zeroSensors(r) {
+				continue
+			}
+			v := col.value(r)
+			if v < col.min || v > col.max {
+				health.OutOfRange++
+				if rangeCheck.Passed {
+					rangeCheck.Passed = false
+					rangeCheck.Reason = models.ReasonSensorRange
+					rangeCheck.Message = rangeCheck.Reason.Format(col.name, r.Line, col.min, col.max, v)
+				}
+			}
+			if col.maxStep > 0 && prev >= 0 {
+				from := col.value(records[prev])
+				if step := math.Abs(v - from); step > col.maxStep {
+					health.Jumps++
+					if jumpCheck.Passed {
+						jumpCheck.Passed = false
+						jumpCheck.Reason = models.ReasonSensorJump
+						jumpCheck.Message = jumpCheck.Reason.Format(col.name, r.Line, from, v, step, col.maxStep)
+					}
+				}
+			}
+			prev = i
+		}
+	}
+	jumpCheck.Measured = float64(health.Jumps)
+	rangeCheck.Measured = float64(health.OutOfRange)
+	if jumpCheck.Passed {
+		jumpCheck.Message = "Невозможных скачков показаний нет"
+	}
+	if rangeCheck.Passed {
+		rangeCheck.Message = "Все показания в физическом диапазоне столбцов"
+	}
+	health.Checks = append(health.Checks, jumpCheck, rangeCheck)
+
+	health.Passed = true
+	for _, c := range health.Checks {
+		if c.Failed() {
+			health.Passed = false
+		}
+	}
+
+	if logFile != nil {
+		if len(health.AbsentColumns) > 0 {
+			fmt.Fprintf(logFile, "ℹ Столбцы без показаний (датчик не установлен): %v\n", health.AbsentColumns)
+		}
+		for _, c := range health.Checks {
+			logCheck(logFile, c)
+		}
+		if health.Jumps > 1 || health.OutOfRange > 1 {
+			fmt.Fprintf(logFile, "  Всего скачков: %d, значений вне диапазона: %d\n", health.Jumps, health.OutOfRange)
+		}
+	}
+	return health
+}
@@ -0,0 +1,201 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// Пороги прежнего детектора (не настраиваются)
+const (
+	legacyStability     = 2.0  // Допуск стабильности между соседними записями окна, градусы
+	legacyTurnTolerance = 5.0  // Допуск угла поворота, градусы
+	legacySumTolerance  = 10.0 // Допуск суммы поворотов до кратного 360°, градусы
+	legacyWindow        = 3    // Длина окна (стабильного сегмента), записей
+)
+
+// legacySegmenter - имя поиска сегментов прежнего детектора в результате анализа
+const legacySegmenter = "legacy-window"
+
+// legacyStrategy - прежний детектор поворотов (раньше - compass_analyzer.go
+// в пакете main). Стабильным считается каждое окно из трех записей, соседние
+// записи которого отличаются не больше 2°; средний угол окна - обычное
+// среднее. Поворот - пара соседних стабильных окон, средние углы которых
+// отличаются на угол шага ±5°. Разница углов округляется до градуса,
+// направление поворота не проверяется. Последовательность годна, если
+// поворотов не меньше количества шагов профиля, повороты не пересекаются
+// по индексам и сумма всех поворотов отличается от кратного 360° не больше
+// чем на 10°. Параметры анализа не используются: детектор сохранен как есть,
+// чтобы на тех же файлах сравнивать с ним текущий алгоритм.
+type legacyStrategy struct{}
+
+func (legacyStrategy) Name() string { return StrategyLegacy }
+
+func (legacyStrategy) Description() string {
+	return fmt.Sprintf("прежний детектор: окна по %d записи, стабильность %.0f°, поворот ±%.0f°, сумма ±%.0f°, направление не проверяется",
+		legacyWindow, legacyStability, legacyTurnTolerance, legacySumTolerance)
+}
+
+func (legacyStrategy) Analyze(data []models.CompassData, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) AnalysisResult {
+	angles := dataAngles(data)
+	result := AnalysisResult{
+		Profile:   profile.Name,
+		Segmenter: legacySegmenter,
+		Params:    params,
+		Samples:   len(data),
+		Series:    newSeries(data, data),
+	}
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== ПРЕЖНИЙ ДЕТЕКТОР ПОВОРОТОВ ===\n")
+		fmt.Fprintf(logFile, "Профиль процедуры: %s\n", profile)
+		fmt.Fprintf(logFile, "Пороги: %s\n", legacyStrategy{}.Description())
+	}
+
+	result.Segments = legacySegments(angles)
+	result.Candidates = legacyTurns(result.Segments, profile.StepAngle)
+	if logFile != nil {
+		fmt.Fprintf(logFile, "Стабильных окон: %d, поворотов на %.0f°: %d\n", len(result.Segments), profile.StepAngle, len(result.Candidates))
+	}
+
+	result.Checks = legacyChecks(result.Candidates, profile)
+	for _, c := range result.Checks {
+		logCheck(logFile, c)
+	}
+	result.updateVerdict()
+	if result.IsValid {
+		// Прежний детектор возвращал повороты только для годной последовательности
+		result.Turns = result.Candidates
+	}
+	if logFile != nil {
+		if result.IsValid {
+			fmt.Fprintf(logFile, "✓ Последовательность поворотов годна\n")
+		} else {
+			fmt.Fprintf(logFile, "✗ Последовательность поворотов не годна\n")
+		}
+	}
+	return result
+}
+
+// legacyAngleDifference - модуль разницы углов через 0°/360°, округленный до градуса
+func legacyAngleDifference(a, b float64) float64 {
+	return math.Round(circular.Distance(a, b))
+}
+
+// legacySegments возвращает все стабильные окна из legacyWindow записей
+// (окна перекрываются, каждое начинается со следующей записи)
+func legacySegments(angles []float64) []AngleSegment {
+	var segments []AngleSegment
+	for i := 0; i+legacyWindow <= len(angles); i++ {
+		stable := true
+		for j := i + 1; j < i+legacyWindow; j++ {
+			if legacyAngleDifference(angles[j], angles[j-1]) > legacyStability {
+				stable = false
+				break
+			}
+		}
+		if !stable {
+			continue
+		}
+
+		window := append([]float64(nil), angles[i:i+legacyWindow]...)
+		sum := 0.0
+		for _, a := range window {
+			sum += a
+		}
+		seg := AngleSegment{
+			StartIndex: i,
+			EndIndex:   i + legacyWindow - 1,
+			AvgAngle:   sum / float64(len(window)),
+			AllAngles:  window,
+			IsStable:   true,
+		}
+		describeSegment(&seg)
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// legacyTurns находит повороты на угол шага между соседними стабильными окнами
+func legacyTurns(segments []AngleSegment, stepAngle float64) []models.Turn {
+	var turns []models.Turn
+	for i := 1; i < len(segments); i++ {
+		from, to := segments[i-1], segments[i]
+		diff := legacyAngleDifference(to.AvgAngle, from.AvgAngle)
+		if math.Abs(diff-stepAngle) > legacyTurnTolerance {
+			continue
+		}
+		signed := circular.Diff(from.AvgAngle, to.AvgAngle)
+		turns = append(turns, models.Turn{
+			StartAngle:  from.AvgAngle,
+			EndAngle:    to.AvgAngle,
+			Diff:        diff,
+			SignedDiff:  signed,
+			IsClockwise: signed > 0,
+			StartIndex:  from.StartIndex,
+			EndIndex:    to.EndIndex,
+			FromSegment: i - 1,
+			ToSegment:   i,
+		})
+	}
+	return turns
+}
+
+// legacyChecks проверяет последовательность поворотов как прежний детектор:
+// проверки выполняются по порядку до первой непройденной
+func legacyChecks(turns []models.Turn, profile models.ProcedureProfile) []models.Check {
+	countCheck := models.Check{
+		Name:     models.CheckTurnCount,
+		Passed:   len(turns) >= profile.StepCount,
+		Measured: float64(len(turns)),
+		Limit:    float64(profile.StepCount),
+		Unit:     "шт",
+	}
+	if !countCheck.Passed {
+		countCheck.Reason = models.ReasonTooFewTurns
+		countCheck.Message = countCheck.Reason.Format(len(turns), profile.StepCount)
+		return []models.Check{countCheck}
+	}
+	countCheck.Message = fmt.Sprintf("Найдено %d поворотов (требуется минимум %d)", len(turns), profile.StepCount)
+	checks := []models.Check{countCheck}
+
+	orderCheck := models.Check{Name: models.CheckIndexOrder, Passed: true, Unit: "шт"}
+	for i := 1; i < len(turns); i++ {
+		if turns[i].StartIndex <= turns[i-1].EndIndex {
+			orderCheck.Passed = false
+			orderCheck.Measured = float64(i + 1)
+			orderCheck.Reason = models.ReasonIndexOverlap
+			orderCheck.Message = orderCheck.Reason.Format(i+1, i)
+			return append(checks, orderCheck)
+		}
+	}
+	orderCheck.Message = "Повороты идут по порядку без пересечения индексов"
+	checks = append(checks, orderCheck)
+
+	total := 0.0
+	for _, turn := range turns {
+		total += turn.Diff
+	}
+	deviation := math.Mod(total, 360)
+	if deviation > 180 {
+		deviation = 360 - deviation
+	}
+	nominal := math.Round(total/360) * 360
+	sumCheck := models.Check{
+		Name:     models.CheckSum,
+		Passed:   deviation <= legacySumTolerance,
+		Measured: deviation,
+		Limit:    legacySumTolerance,
+		Unit:     "°",
+	}
+	if sumCheck.Passed {
+		sumCheck.Message = fmt.Sprintf("Сумма поворотов %.2f° близка к %.0f° (отклонение %.2f° ≤ %.2f°)",
+			total, nominal, deviation, legacySumTolerance)
+	} else {
+		sumCheck.Reason = models.ReasonSumDeviation
+		sumCheck.Message = sumCheck.Reason.Format(total, nominal, deviation, legacySumTolerance)
+	}
+	return append(checks, sumCheck)
+}
@@ -0,0 +1,70 @@
+package analyzer
+
+import "math"
+
+// solveLinearSystem решает систему линейных уравнений a·x = b методом Гаусса
+// с выбором главного элемента. Матрица a и вектор b не изменяются.
+// Возвращает false, если система вырождена.
+func solveLinearSystem(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n+1)
+		copy(m[i], a[i])
+		m[i][n] = b[i]
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := m[row][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[row][k] -= factor * m[col][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+	return x, true
+}
+
+// leastSquares находит решение переопределенной системы rows·x ≈ rhs
+// методом наименьших квадратов через нормальные уравнения
+func leastSquares(rows [][]float64, rhs []float64) ([]float64, bool) {
+	if len(rows) == 0 {
+		return nil, false
+	}
+	n := len(rows[0])
+	ata := make([][]float64, n)
+	for i := range ata {
+		ata[i] = make([]float64, n)
+	}
+	atb := make([]float64, n)
+
+	for r, row := range rows {
+		for i := 0; i < n; i++ {
+			atb[i] += row[i] * rhs[r]
+			for j := 0; j < n; j++ {
+				ata[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	return solveLinearSystem(ata, atb)
+}
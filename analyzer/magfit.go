@@ -0,0 +1,419 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"compass_analyzer/models"
+	"compass_analyzer/parser"
+)
+
+// Параметры отбора показаний магнитометра (допуски проверок - в профиле процедуры)
+const (
+	magFitMinSamples     = 8    // Минимум различных точек для аппроксимации эллипса
+	magFitMaxTilt        = 5.0  // Максимальный наклон прибора (по акселерометру), при котором точка используется, градусы
+	magFitMaxFieldChange = 30.0 // Допустимое отличие модуля поля от поля на этапе калибровки, %
+)
+
+// MagneticFit содержит результаты аппроксимации показаний магнитометра эллипсом.
+// При вращении компаса в горизонтальной плоскости конец вектора поля описывает
+// окружность; ее смещение - это «твердое железо», а вытянутость в эллипс -
+// «мягкое железо». Все величины в единицах магнитометра, если не указано иное.
+type MagneticFit struct {
+	Samples   int     `json:"samples"`   // Количество точек, использованных для аппроксимации
+	Rejected  int     `json:"rejected"`  // Точки, отброшенные из-за изменения модуля поля
+	Fitted    bool    `json:"fitted"`    // Удалось ли построить эллипс
+	CenterX   float64 `json:"centerX"`   // Смещение твердого железа по X
+	CenterY   float64 `json:"centerY"`   // Смещение твердого железа по Y
+	MajorAxis float64 `json:"majorAxis"` // Большая полуось эллипса
+	MinorAxis float64 `json:"minorAxis"` // Малая полуось эллипса
+	Rotation  float64 `json:"rotation"`  // Угол большой оси относительно X, градусы
+	SoftIron  float64 `json:"softIron"`  // Искажение мягкого железа, %
+	Residual  float64 `json:"residual"`  // СКО точек от эллипса, % радиуса поля
+	Coverage  float64 `json:"coverage"`  // Охват окружности точками, градусы
+
+	HasStoredOffset bool    `json:"hasStoredOffset"` // Прибор сохранил смещения магнитометра
+	StoredX         float64 `json:"storedX"`         // Сохраненное прибором смещение по X
+	StoredY         float64 `json:"storedY"`         // Сохраненное прибором смещение по Y
+	OffsetError     float64 `json:"offsetError"`     // Расхождение центра и сохраненного смещения, % радиуса поля
+
+	Passed   bool           `json:"passed"`   // Проверка поля пройдена (или не могла быть выполнена)
+	Checks   []models.Check `json:"checks"`   // Результаты отдельных проверок (только если эллипс построен)
+	Errors   []string       `json:"errors"`   // Причины отбраковки
+	Warnings []string       `json:"warnings"` // Нарушения допусков, не влияющие на вердикт (ProcedureProfile.MagFitAdvisory)
+	Notes    []string       `json:"notes"`    // Замечания, не влияющие на результат
+}
+
+// addCheck добавляет результат проверки поля; причина провала попадает
+// в Errors, нарушение проверки-предупреждения - в Warnings
+func (fit *MagneticFit) addCheck(c models.Check) {
+	fit.Checks = append(fit.Checks, c)
+	switch {
+	case c.Failed():
+		fit.Errors = append(fit.Errors, c.Message)
+	case !c.Passed:
+		fit.Warnings = append(fit.Warnings, c.Message)
+	}
+}
+
+// magPoint - точка магнитометра в горизонтальной плоскости
+type magPoint struct {
+	x, y float64
+}
+
+// magFitSpinStage - имя флага словаря (см. models.Flags) этапа калибровки,
+// на котором прибор вращают по кругу в горизонтальной плоскости
+const magFitSpinStage = "step_spin"
+
+// collectMagPoints отбирает показания магнитометра для аппроксимации.
+// Прибор выдает показания уже за вычетом сохраненного смещения, поэтому
+// исходные значения восстанавливаются прибавлением XYZmagnOffs.
+//
+// Используются только записи этапа горизонтального вращения (флаг
+// magFitSpinStage) и после завершения калибровки (состояние
+// models.DeviceStateDone) по словарю флагов прошивки, когда прибор стоит
+// ровно (по акселерометру);
+// подряд идущие одинаковые показания учитываются один раз. Точки, модуль
+// поля которых сильно отличается от поля на этапе калибровки (прибор снят
+// со стенда или поднесен к железу), отбрасываются и считаются в rejected.
+func collectMagPoints(records []models.TelemetryRecord) (points []magPoint, stored *models.MagVector, rejected int) {
+	var candidates []magPoint
+	var horizontal []bool
+
+	dict := models.Flags()
+	for _, r := range records {
+		v := parser.DecodeSensorVectors(r)
+
+		if v.MagnOffs != (models.MagVector{}) {
+			offs := v.MagnOffs
+			stored = &offs
+		}
+
+		decoded := dict.Decode(r.Status, r.Calibration)
+		spin := decoded.Has(magFitSpinStage)
+		if !spin && decoded.State() != models.DeviceStateDone {
+			continue
+		}
+		if v.Axs.Z <= 0 {
+			continue
+		}
+		tilt := math.Atan2(math.Hypot(float64(v.Axs.X), float64(v.Axs.Y)), float64(v.Axs.Z)) * 180 / math.Pi
+		if tilt > magFitMaxTilt {
+			continue
+		}
+
+		p := magPoint{
+			x: float64(v.Magn.X) + float64(v.MagnOffs.X),
+			y: float64(v.Magn.Y) + float64(v.MagnOffs.Y),
+		}
+		if len(candidates) > 0 && candidates[len(candidates)-1] == p {
+			continue
+		}
+		candidates = append(candidates, p)
+		horizontal = append(horizontal, spin)
+	}
+
+	if len(candidates) == 0 {
+		return nil, stored, 0
+	}
+
+	// Опорный центр - сохраненное смещение, иначе среднее точек
+	var cx, cy float64
+	if stored != nil {
+		cx, cy = float64(stored.X), float64(stored.Y)
+	} else {
+		for _, p := range candidates {
+			cx += p.x
+			cy += p.y
+		}
+		cx /= float64(len(candidates))
+		cy /= float64(len(candidates))
+	}
+
+	// Опорный модуль поля - медиана на этапе горизонтального вращения,
+	// а если его нет в файле - медиана по всем точкам
+	var radii, stageRadii []float64
+	for i, p := range candidates {
+		r := math.Hypot(p.x-cx, p.y-cy)
+		radii = append(radii, r)
+		if horizontal[i] {
+			stageRadii = append(stageRadii, r)
+		}
+	}
+	reference := medianValue(radii)
+	if len(stageRadii) >= 3 {
+		reference = medianValue(stageRadii)
+	}
+
+	for i, p := range candidates {
+		if math.Abs(radii[i]-reference) > reference*magFitMaxFieldChange/100 {
+			rejected++
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, stored, rejected
+}
+
+// medianValue возвращает медиану чисел, не изменяя исходный срез
+func medianValue(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// FitMagnetometer аппроксимирует показания магнитометра эллипсом и проверяет
+// качество калибровки по допускам профиля: искажение мягкого железа, СКО
+// аппроксимации и совпадение центра со смещением, сохраненным прибором.
+// Если точек недостаточно или они охватывают меньше profile.FitCoverage()
+// окружности, проверка не выполняется и не влияет на результат (см. Notes).
+// Вытянутость эллипса по неполной дуге - экстраполяция, поэтому мягкое
+// железо оценивается только при охвате не меньше profile.SoftIronCoverage().
+// Допуски поля еще не сверены с размеченным набором, поэтому во встроенных
+// профилях проверки - предупреждения (ProcedureProfile.MagFitAdvisory).
+//
+// Параметры:
+//   - records: записи телеметрии компаса
+//   - profile: профиль процедуры (допуски поля магнитометра)
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - MagneticFit: результаты аппроксимации и проверки
+func FitMagnetometer(records []models.TelemetryRecord, profile models.ProcedureProfile, logFile *os.File) MagneticFit {
+	fit := MagneticFit{Passed: true}
+
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== Проверка поля магнитометра (аппроксимация эллипсом) ===\n")
+	}
+
+	points, stored, rejected := collectMagPoints(records)
+	fit.Samples = len(points)
+	fit.Rejected = rejected
+	if stored != nil {
+		fit.HasStoredOffset = true
+		fit.StoredX = float64(stored.X)
+		fit.StoredY = float64(stored.Y)
+	}
+
+	if len(points) < magFitMinSamples {
+		fit.Notes = append(fit.Notes, fmt.Sprintf("Недостаточно показаний магнитометра для аппроксимации: %d < %d", len(points), magFitMinSamples))
+		logMagneticFit(logFile, fit, profile)
+		return fit
+	}
+
+	if !fitEllipse(points, &fit) {
+		fit.Notes = append(fit.Notes, "Показания магнитометра не образуют эллипс (вырожденная аппроксимация)")
+		logMagneticFit(logFile, fit, profile)
+		return fit
+	}
+
+	if fit.Coverage < profile.FitCoverage() {
+		fit.Fitted = false
+		fit.Notes = append(fit.Notes, fmt.Sprintf("Показания охватывают только %.0f° окружности (нужно ≥ %.0f°), проверка поля не выполняется",
+			fit.Coverage, profile.FitCoverage()))
+		logMagneticFit(logFile, fit, profile)
+		return fit
+	}
+	if !profile.HasMagFitLimits() {
+		fit.Notes = append(fit.Notes, fmt.Sprintf("Допуски поля магнитометра в профиле «%s» не заданы - проверка пропущена", profile.Name))
+	}
+
+	switch {
+	case profile.MaxSoftIron <= 0:
+	case fit.Coverage < profile.SoftIronCoverage():
+		fit.Notes = append(fit.Notes, fmt.Sprintf("Мягкое железо не оценивается: показания охватывают %.0f° окружности (нужно ≥ %.0f°)",
+			fit.Coverage, profile.SoftIronCoverage()))
+	default:
+		softIron := models.Check{
+			Name:     models.CheckSoftIron,
+			Passed:   fit.SoftIron <= profile.MaxSoftIron,
+			Measured: fit.SoftIron,
+			Limit:    profile.MaxSoftIron,
+			Unit:     "%",
+			Message:  fmt.Sprintf("Искажение мягкого железа %.1f%% ≤ %.1f%%", fit.SoftIron, profile.MaxSoftIron),
+			Advisory: profile.MagFitAdvisory,
+		}
+		if !softIron.Passed {
+			softIron.Reason = models.ReasonSoftIron
+			softIron.Message = softIron.Reason.Format(
+				fit.SoftIron, profile.MaxSoftIron, fit.MajorAxis, fit.MinorAxis)
+		}
+		fit.addCheck(softIron)
+	}
+
+	if profile.MaxFitResidual > 0 {
+		residual := models.Check{
+			Name:     models.CheckFitResidual,
+			Passed:   fit.Residual <= profile.MaxFitResidual,
+			Measured: fit.Residual,
+			Limit:    profile.MaxFitResidual,
+			Unit:     "%",
+			Message:  fmt.Sprintf("СКО показаний магнитометра от эллипса %.1f%% ≤ %.1f%% радиуса поля", fit.Residual, profile.MaxFitResidual),
+			Advisory: profile.MagFitAdvisory,
+		}
+		if !residual.Passed {
+			residual.Reason = models.ReasonFitResidual
+			residual.Message = residual.Reason.Format(
+				fit.Residual, profile.MaxFitResidual)
+		}
+		fit.addCheck(residual)
+	}
+
+	if fit.HasStoredOffset {
+		radius := math.Sqrt(fit.MajorAxis * fit.MinorAxis)
+		fit.OffsetError = math.Hypot(fit.CenterX-fit.StoredX, fit.CenterY-fit.StoredY) / radius * 100
+		if profile.MaxOffsetError > 0 {
+			offset := models.Check{
+				Name:     models.CheckOffset,
+				Passed:   fit.OffsetError <= profile.MaxOffsetError,
+				Measured: fit.OffsetError,
+				Limit:    profile.MaxOffsetError,
+				Unit:     "%",
+				Message:  fmt.Sprintf("Центр эллипса совпадает со смещением прибора: %.1f%% ≤ %.1f%% радиуса поля", fit.OffsetError, profile.MaxOffsetError),
+				Advisory: profile.MagFitAdvisory,
+			}
+			if !offset.Passed {
+				offset.Reason = models.ReasonOffsetMismatch
+				offset.Message = offset.Reason.Format(
+					fit.CenterX, fit.CenterY, fit.StoredX, fit.StoredY, fit.OffsetError, profile.MaxOffsetError)
+			}
+			fit.addCheck(offset)
+		}
+	} else {
+		fit.Notes = append(fit.Notes, "Прибор не сохранил смещения магнитометра (XYZmagnOffs = 0), сравнение не выполняется")
+	}
+
+	fit.Passed = len(fit.Errors) == 0
+	logMagneticFit(logFile, fit, profile)
+	return fit
+}
+
+// fitEllipse аппроксимирует точки коникой A·x² + B·xy + C·y² + D·x + E·y = 1
+// методом наименьших квадратов и заполняет геометрию эллипса в fit.
+// Для устойчивости точки предварительно центрируются и масштабируются.
+func fitEllipse(points []magPoint, fit *MagneticFit) bool {
+	var meanX, meanY float64
+	for _, p := range points {
+		meanX += p.x
+		meanY += p.y
+	}
+	meanX /= float64(len(points))
+	meanY /= float64(len(points))
+
+	var scale float64
+	for _, p := range points {
+		scale = math.Max(scale, math.Max(math.Abs(p.x-meanX), math.Abs(p.y-meanY)))
+	}
+	if scale == 0 {
+		return false
+	}
+
+	rows := make([][]float64, len(points))
+	rhs := make([]float64, len(points))
+	for i, p := range points {
+		x := (p.x - meanX) / scale
+		y := (p.y - meanY) / scale
+		rows[i] = []float64{x * x, x * y, y * y, x, y}
+		rhs[i] = 1
+	}
+	coef, ok := leastSquares(rows, rhs)
+	if !ok {
+		return false
+	}
+	a, b, c, d, e := coef[0], coef[1], coef[2], coef[3], coef[4]
+
+	// Эллипс только при положительно определенной квадратичной форме
+	det := 4*a*c - b*b
+	if det <= 0 {
+		return false
+	}
+
+	// Центр: градиент квадратичной формы равен нулю
+	u0 := (b*e - 2*c*d) / det
+	v0 := (b*d - 2*a*e) / det
+	k := 1 + a*u0*u0 + b*u0*v0 + c*v0*v0
+	if k <= 0 {
+		return false
+	}
+
+	// Собственные значения матрицы [[a, b/2], [b/2, c]] задают полуоси
+	mean := (a + c) / 2
+	diff := math.Sqrt((a-c)*(a-c)/4 + b*b/4)
+	lambdaMin, lambdaMax := mean-diff, mean+diff
+	if lambdaMin <= 0 {
+		return false
+	}
+
+	fit.Fitted = true
+	fit.CenterX = meanX + u0*scale
+	fit.CenterY = meanY + v0*scale
+	fit.MajorAxis = math.Sqrt(k/lambdaMin) * scale
+	fit.MinorAxis = math.Sqrt(k/lambdaMax) * scale
+	fit.Rotation = normalizeAngle(0.5*math.Atan2(b, a-c)*180/math.Pi + 90)
+	fit.SoftIron = (1 - fit.MinorAxis/fit.MajorAxis) * 100
+
+	// СКО: отклонение «эллиптического радиуса» точки от 1
+	var sumSq float64
+	angles := make([]float64, len(points))
+	for i, p := range points {
+		u := (p.x-meanX)/scale - u0
+		v := (p.y-meanY)/scale - v0
+		r := math.Sqrt((a*u*u + b*u*v + c*v*v) / k)
+		sumSq += (r - 1) * (r - 1)
+		angles[i] = normalizeAngle(math.Atan2(v, u) * 180 / math.Pi)
+	}
+	fit.Residual = math.Sqrt(sumSq/float64(len(points))) * 100
+
+	// Охват: 360° минус наибольший промежуток между соседними направлениями
+	sort.Float64s(angles)
+	maxGap := 360 - angles[len(angles)-1] + angles[0]
+	for i := 1; i < len(angles); i++ {
+		maxGap = math.Max(maxGap, angles[i]-angles[i-1])
+	}
+	fit.Coverage = 360 - maxGap
+
+	return true
+}
+
+// logMagneticFit записывает результаты проверки поля в лог
+func logMagneticFit(logFile *os.File, fit MagneticFit, profile models.ProcedureProfile) {
+	if logFile == nil {
+		return
+	}
+	fmt.Fprintf(logFile, "Точек для аппроксимации: %d\n", fit.Samples)
+	if fit.Rejected > 0 {
+		fmt.Fprintf(logFile, "Отброшено точек с изменившимся модулем поля (прибор снят со стенда): %d\n", fit.Rejected)
+	}
+	if fit.Fitted {
+		fmt.Fprintf(logFile, "Центр (твердое железо): X=%.1f, Y=%.1f\n", fit.CenterX, fit.CenterY)
+		fmt.Fprintf(logFile, "Полуоси: %.1f / %.1f, поворот большой оси: %.1f°\n", fit.MajorAxis, fit.MinorAxis, fit.Rotation)
+		fmt.Fprintf(logFile, "Мягкое железо: %.2f%% (допуск %.1f%%)\n", fit.SoftIron, profile.MaxSoftIron)
+		fmt.Fprintf(logFile, "СКО аппроксимации: %.2f%% радиуса (допуск %.1f%%)\n", fit.Residual, profile.MaxFitResidual)
+		fmt.Fprintf(logFile, "Охват окружности: %.0f°\n", fit.Coverage)
+		if fit.HasStoredOffset {
+			fmt.Fprintf(logFile, "Смещение прибора: X=%.0f, Y=%.0f, расхождение с центром: %.2f%% радиуса (допуск %.1f%%)\n",
+				fit.StoredX, fit.StoredY, fit.OffsetError, profile.MaxOffsetError)
+		}
+	}
+	for _, note := range fit.Notes {
+		fmt.Fprintf(logFile, "ℹ %s\n", note)
+	}
+	for _, warning := range fit.Warnings {
+		fmt.Fprintf(logFile, "  ⚠ Предупреждение: %s\n", warning)
+	}
+	for _, err := range fit.Errors {
+		fmt.Fprintf(logFile, "✗ %s\n", err)
+	}
+	if fit.Passed && len(fit.Warnings) == 0 && fit.Fitted && len(fit.Checks) > 0 {
+		fmt.Fprintf(logFile, "✓ Поле магнитометра в норме\n")
+	}
+}
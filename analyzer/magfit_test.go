@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"compass_analyzer/models"
+)
+
+// Эллипс поля с известной геометрией: смещение твердого железа (как в строке 60
+// SB_CMPS.csv), полуоси 1000 и 800 (мягкое железо 20%), большая ось под 30°
+const (
+	testCenterX  = -3810.0
+	testCenterY  = 2064.0
+	testMajor    = 1000.0
+	testMinor    = 800.0
+	testRotation = 30.0
+)
+
+// ellipsePoints возвращает count точек эллипса на дуге arc градусов
+func ellipsePoints(count int, arc float64) []magPoint {
+	rot := testRotation * math.Pi / 180
+	points := make([]magPoint, count)
+	for i := range points {
+		t := arc * float64(i) / float64(count) * math.Pi / 180
+		u, v := testMajor*math.Cos(t), testMinor*math.Sin(t)
+		points[i] = magPoint{
+			x: testCenterX + u*math.Cos(rot) - v*math.Sin(rot),
+			y: testCenterY + u*math.Sin(rot) + v*math.Cos(rot),
+		}
+	}
+	return points
+}
+
+// ellipseRecords возвращает записи этапа горизонтального вращения с показаниями
+// магнитометра на эллипсе: прибор стоит ровно и уже сохранил смещение
+// (показания - за вычетом смещения, как их выдает прибор)
+func ellipseRecords(count int, arc float64) []models.TelemetryRecord {
+	putInt32 := func(b []byte, v float64) { binary.LittleEndian.PutUint32(b, uint32(int32(math.Round(v)))) }
+	var offs [12]byte
+	putInt32(offs[0:4], testCenterX)
+	putInt32(offs[4:8], testCenterY)
+
+	var spin uint32
+	for _, e := range models.Flags().Calibration {
+		if e.Name == magFitSpinStage {
+			spin = e.Value
+		}
+	}
+
+	var records []models.TelemetryRecord
+	for i, p := range ellipsePoints(count, arc) {
+		r := models.TelemetryRecord{Line: i + 2, Calibration: spin, XYZMagnOffs: offs}
+		putInt32(r.XYZMagn[0:4], p.x-testCenterX)
+		putInt32(r.XYZMagn[4:8], p.y-testCenterY)
+		binary.LittleEndian.PutUint16(r.XYZAxs[4:6], 263)
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestFitEllipse(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		arc  float64
+	}{
+		{"полная окружность", 360},
+		{"дуга 270°", 270},
+	} {
+		var fit MagneticFit
+		if !fitEllipse(ellipsePoints(72, tt.arc), &fit) {
+			t.Fatalf("%s: эллипс не построен", tt.name)
+		}
+		for _, v := range []struct {
+			what      string
+			got, want float64
+			tolerance float64
+		}{
+			{"центр X", fit.CenterX, testCenterX, 0.1},
+			{"центр Y", fit.CenterY, testCenterY, 0.1},
+			{"большая полуось", fit.MajorAxis, testMajor, 0.1},
+			{"малая полуось", fit.MinorAxis, testMinor, 0.1},
+			{"поворот", fit.Rotation, testRotation, 0.1},
+			{"мягкое железо", fit.SoftIron, 20, 0.01},
+			{"СКО", fit.Residual, 0, 0.01},
+		} {
+			if math.Abs(v.got-v.want) > v.tolerance {
+				t.Errorf("%s: %s = %.3f, ожидалось %.3f", tt.name, v.what, v.got, v.want)
+			}
+		}
+		// Охват считается по направлениям из центра, на эллипсе шаг между ними неравномерен
+		if want := tt.arc - tt.arc/72; math.Abs(fit.Coverage-want) > 3 {
+			t.Errorf("%s: охват %.1f°, ожидалось %.1f°", tt.name, fit.Coverage, want)
+		}
+	}
+}
+
+func TestFitMagnetometer(t *testing.T) {
+	profile, err := models.FindProfile("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	blocking := profile
+	blocking.MagFitAdvisory = false
+
+	for _, tt := range []struct {
+		name     string
+		arc      float64
+		profile  models.ProcedureProfile
+		softIron bool // Проверка мягкого железа выполнена
+		passed   bool
+	}{
+		{"полная окружность, предупреждение", 360, profile, true, true},
+		{"полная окружность, брак", 360, blocking, true, false},
+		// По дуге 270° эллипс строится, но мягкое железо не оценивается
+		{"дуга 270°", 270, blocking, false, true},
+	} {
+		fit := FitMagnetometer(ellipseRecords(72, tt.arc), tt.profile, nil)
+		if !fit.Fitted || !fit.HasStoredOffset {
+			t.Fatalf("%s: эллипс не построен: %+v", tt.name, fit.Notes)
+		}
+		if math.Abs(fit.SoftIron-20) > 0.5 || fit.OffsetError > 1 {
+			t.Errorf("%s: мягкое железо %.2f%%, расхождение смещения %.2f%%", tt.name, fit.SoftIron, fit.OffsetError)
+		}
+		checked := false
+		for _, c := range fit.Checks {
+			if c.Name == models.CheckSoftIron {
+				checked = true
+				if c.Passed || c.Reason != models.ReasonSoftIron || c.Advisory != tt.profile.MagFitAdvisory {
+					t.Errorf("%s: проверка мягкого железа %+v", tt.name, c)
+				}
+			}
+		}
+		if checked != tt.softIron {
+			t.Errorf("%s: проверка мягкого железа выполнена = %v, ожидалось %v", tt.name, checked, tt.softIron)
+		}
+		if !tt.softIron && !strings.Contains(strings.Join(fit.Notes, "\n"), "Мягкое железо не оценивается") {
+			t.Errorf("%s: нет замечания о неполном охвате: %v", tt.name, fit.Notes)
+		}
+		if fit.Passed != tt.passed {
+			t.Errorf("%s: Passed = %v, ожидалось %v (ошибки %v, предупреждения %v)", tt.name, fit.Passed, tt.passed, fit.Errors, fit.Warnings)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// Названия этапов предварительной обработки для логов и отчетов
+const (
+	StageRewrap   = "rewrap"   // Приведение азимутов к диапазону [0, 360)
+	StageSpikes   = "spikes"   // Отбрасывание одиночных выбросов
+	StageMedian   = "median"   // Круговой медианный фильтр
+	StageResample = "resample" // Передискретизация на равномерную сетку времени
+)
+
+// resampleMaxSamples - наибольшее число записей сетки передискретизации:
+// поиск сегментов на более длинном ряду занимает слишком много времени
+const resampleMaxSamples = 100000
+
+// PreprocessStage - результат одного этапа предварительной обработки
+type PreprocessStage struct {
+	Name    string `json:"name"`              // Этап (StageRewrap и т.д.)
+	Changed int    `json:"changed"`           // Сколько записей изменено этапом
+	Samples int    `json:"samples"`           // Сколько записей после этапа
+	Skipped string `json:"skipped,omitempty"` // Почему этап пропущен
+}
+
+// Series - ряды азимута для графиков: исходные записи и записи после
+// предварительной обработки. Индексы сегментов и поворотов результата
+// относятся к ряду Filtered. Время - секунды от первой исходной записи
+// (номер записи, если у записей нет меток времени).
+type Series struct {
+	RawTimes []float64 `json:"rawTimes"`
+	Raw      []float64 `json:"raw"`
+	Times    []float64 `json:"times"`
+	Filtered []float64 `json:"filtered"`
+}
+
+// seriesTimes возвращает время записей в секундах от начала ряда start
+func seriesTimes(data []models.CompassData, start time.Time, timed bool) []float64 {
+	times := make([]float64, len(data))
+	for i, d := range data {
+		if timed {
+			times[i] = d.Time.Sub(start).Seconds()
+		} else {
+			times[i] = float64(i)
+		}
+	}
+	return times
+}
+
+// newSeries собирает ряды для графиков из исходных и обработанных записей
+func newSeries(raw, filtered []models.CompassData) *Series {
+	timed := hasTimestamps(raw) && hasTimestamps(filtered)
+	var start time.Time
+	if len(raw) > 0 {
+		start = raw[0].Time
+	}
+	return &Series{
+		RawTimes: seriesTimes(raw, start, timed),
+		Raw:      dataAngles(raw),
+		Times:    seriesTimes(filtered, start, timed),
+		Filtered: dataAngles(filtered),
+	}
+}
+
+// Preprocess выполняет предварительную обработку ряда азимутов перед поиском
+// сегментов. Этапы выполняются по порядку:
+//   - rewrap: азимуты приводятся к диапазону [0, 360) (выполняется всегда);
+//   - spikes: одиночная запись, отличающаяся от обоих соседей больше
+//     SpikeThreshold при соседях, близких друг к другу, заменяется их
+//     круговым средним (скачок 0° → 180° → 1°);
+//   - median: круговой медианный фильтр с окном MedianWindow записей
+//     (переход 0° → 359° → 1° не искажается, в отличие от обычной медианы);
+//   - resample: ряд разворачивается через 0°/360°, линейно интерполируется
+//     на равномерную сетку с шагом ResampleInterval секунд и сворачивается обратно.
+//
+// Исходные записи не изменяются. Для каждого этапа в лог записывается,
+// сколько записей он изменил.
+//
+// Параметры:
+//   - data: исходные записи компаса
+//   - params: включенные этапы и их параметры
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - []models.CompassData: записи после обработки
+//   - []PreprocessStage: результаты этапов по порядку выполнения
+func Preprocess(data []models.CompassData, params models.PreprocessParams, logFile *os.File) ([]models.CompassData, []PreprocessStage) {
+	out := append([]models.CompassData(nil), data...)
+	var stages []PreprocessStage
+
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== Предварительная обработка азимута ===\n")
+		fmt.Fprintf(logFile, "Параметры: %s\n", params)
+	}
+
+	stages = append(stages, rewrapAngles(out))
+	if params.SpikeThreshold > 0 {
+		stages = append(stages, rejectSpikes(out, params.SpikeThreshold))
+	}
+	if params.MedianWindow > 0 {
+		stages = append(stages, medianFilter(out, params.MedianWindow))
+	}
+	if params.ResampleInterval > 0 {
+		var stage PreprocessStage
+		out, stage = resample(out, params.ResampleInterval)
+		stages = append(stages, stage)
+	}
+
+	if logFile != nil {
+		for _, stage := range stages {
+			switch {
+			case stage.Skipped != "":
+				fmt.Fprintf(logFile, "ℹ Этап %s пропущен: %s\n", stage.Name, stage.Skipped)
+			case stage.Name == StageResample:
+				fmt.Fprintf(logFile, "✓ Этап %s: %d → %d записей (интерполировано %d)\n", stage.Name, len(data), stage.Samples, stage.Changed)
+			default:
+				fmt.Fprintf(logFile, "✓ Этап %s: изменено %d из %d записей\n", stage.Name, stage.Changed, stage.Samples)
+			}
+		}
+	}
+	return out, stages
+}
+
+// rewrapAngles приводит азимуты к диапазону [0, 360)
+func rewrapAngles(data []models.CompassData) PreprocessStage {
+	stage := PreprocessStage{Name: StageRewrap, Samples: len(data)}
+	for i := range data {
+		if angle := circular.Normalize(data[i].Angle); angle != data[i].Angle {
+			data[i].Angle = angle
+			stage.Changed++
+		}
+	}
+	return stage
+}
+
+// rejectSpikes заменяет одиночные выбросы круговым средним соседних записей
+func rejectSpikes(data []models.CompassData, threshold float64) PreprocessStage {
+	stage := PreprocessStage{Name: StageSpikes, Samples: len(data)}
+	for i := 1; i+1 < len(data); i++ {
+		prev, cur, next := data[i-1].Angle, data[i].Angle, data[i+1].Angle
+		if circular.Distance(prev, next) <= threshold &&
+			circular.Distance(cur, prev) > threshold && circular.Distance(cur, next) > threshold {
+			data[i].Angle = circular.Mean([]float64{prev, next})
+			stage.Changed++
+		}
+	}
+	return stage
+}
+
+// medianFilter заменяет каждый азимут круговой медианой окна window записей
+// с центром в этой записи (у краев ряда окно укорачивается)
+func medianFilter(data []models.CompassData, window int) PreprocessStage {
+	stage := PreprocessStage{Name: StageMedian, Samples: len(data)}
+	angles := dataAngles(data)
+	half := window / 2
+	for i := range data {
+		from, to := i-half, i+half+1
+		if from < 0 {
+			from = 0
+		}
+		if to > len(angles) {
+			to = len(angles)
+		}
+		median := circular.Median(angles[from:to])
+		if circular.Distance(median, data[i].Angle) > 1e-9 {
+			data[i].Angle = median
+			stage.Changed++
+		}
+	}
+	return stage
+}
+
+// resample интерполирует ряд на равномерную сетку времени с шагом interval
+// секунд. Азимуты интерполируются по развернутому ряду (через 0°/360°),
+// поэтому между 359° и 1° получается 0°, а не 180°. Для записей без меток
+// времени или не упорядоченных по времени, а также если сетка получилась
+// бы длиннее resampleMaxSamples записей, этап пропускается.
+func resample(data []models.CompassData, interval float64) ([]models.CompassData, PreprocessStage) {
+	stage := PreprocessStage{Name: StageResample, Samples: len(data)}
+	if len(data) < 2 || !hasTimestamps(data) {
+		stage.Skipped = "у записей нет меток времени"
+		return data, stage
+	}
+
+	for i := 1; i < len(data); i++ {
+		if data[i].Time.Before(data[i-1].Time) {
+			stage.Skipped = "записи не упорядочены по времени"
+			return data, stage
+		}
+	}
+
+	total := elapsed(data, 0, len(data)-1)
+	if samples := total/interval + 1; interval <= 0 || samples > resampleMaxSamples {
+		stage.Skipped = fmt.Sprintf("сетка %g с на %.0f с записи дает больше %d записей", interval, total, resampleMaxSamples)
+		return data, stage
+	}
+
+	unwrapped := unwrapAngles(dataAngles(data))
+	start := data[0].Time
+	step := time.Duration(interval * float64(time.Second))
+
+	var out []models.CompassData
+	j := 0
+	for k := 0; float64(k)*interval <= total; k++ {
+		t := float64(k) * interval
+		for j+1 < len(data)-1 && elapsed(data, 0, j+1) < t {
+			j++
+		}
+		t0, t1 := elapsed(data, 0, j), elapsed(data, 0, j+1)
+		angle := unwrapped[j]
+		if t1 > t0 {
+			frac := math.Max(0, math.Min(1, (t-t0)/(t1-t0)))
+			angle += frac * (unwrapped[j+1] - unwrapped[j])
+		}
+		at := start.Add(time.Duration(k) * step)
+		out = append(out, models.CompassData{
+			Time:       at,
+			Angle:      circular.Normalize(angle),
+			TimeString: at.Format("02.01.2006 15:04:05"),
+		})
+	}
+
+	// Изменены все записи сетки, не совпадающие с исходной записью по времени и углу
+	i := 0
+	for _, d := range out {
+		for i < len(data) && data[i].Time.Before(d.Time) {
+			i++
+		}
+		if i >= len(data) || !data[i].Time.Equal(d.Time) || circular.Distance(data[i].Angle, d.Angle) > 1e-9 {
+			stage.Changed++
+		}
+	}
+	stage.Samples = len(out)
+	return out, stage
+}
+
+// gridParams пересчитывает пороги, заданные в записях (MinStableLen и MaxOutliers),
+// на шаг сетки передискретизации так, чтобы сохранилась их длительность:
+// пороги описывают записи исходного файла, а после передискретизации запись
+// ряда - это шаг сетки (сегмент из 2 записей по 20 с на сетке 5 с - 8 записей).
+// Интервал записей файла - медиана интервалов между соседними записями.
+// Если передискретизация не выполнялась, параметры не меняются.
+func gridParams(raw []models.CompassData, stages []PreprocessStage, params models.AnalysisParams, logFile *os.File) models.AnalysisParams {
+	resampled := false
+	for _, stage := range stages {
+		if stage.Name == StageResample && stage.Skipped == "" {
+			resampled = true
+		}
+	}
+	if !resampled {
+		return params
+	}
+	var intervals []float64
+	for i := 1; i < len(raw); i++ {
+		if d := elapsed(raw, i-1, i); d > 0 {
+			intervals = append(intervals, d)
+		}
+	}
+	if len(intervals) == 0 {
+		return params
+	}
+
+	interval := medianValue(intervals)
+	ratio := interval / params.Preprocess.ResampleInterval
+	scaled := params
+	scaled.MinStableLen = int(math.Max(1, math.Round(float64(params.MinStableLen)*ratio)))
+	scaled.MaxOutliers = int(math.Round(float64(params.MaxOutliers) * ratio))
+	if logFile != nil {
+		fmt.Fprintf(logFile, "ℹ Записи файла через %.3g с, сетка %g с: минимальная длина сегмента %d → %d записей, максимум выбросов %d → %d\n",
+			interval, params.Preprocess.ResampleInterval, params.MinStableLen, scaled.MinStableLen, params.MaxOutliers, scaled.MaxOutliers)
+	}
+	return scaled
+}
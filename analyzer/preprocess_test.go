@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// angleSeries возвращает записи через interval секунд с азимутами angles
+func angleSeries(interval float64, angles ...float64) []models.CompassData {
+	data := compassSeries(true, angles)
+	start := data[0].Time
+	for i := range data {
+		data[i].Time = start.Add(time.Duration(interval * float64(i) * float64(time.Second)))
+	}
+	return data
+}
+
+// assertAngles сравнивает азимуты записей с ожидаемыми по окружности
+func assertAngles(t *testing.T, what string, data []models.CompassData, want []float64) {
+	t.Helper()
+	if len(data) != len(want) {
+		t.Fatalf("%s: %d записей, ожидалось %d", what, len(data), len(want))
+	}
+	for i, w := range want {
+		if math.Abs(circular.Diff(w, data[i].Angle)) > 1e-6 {
+			t.Errorf("%s: запись %d = %.3f°, ожидалось %.3f°", what, i, data[i].Angle, w)
+		}
+	}
+}
+
+func TestRejectSpikes(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		angles  []float64
+		want    []float64
+		changed int
+	}{
+		{"одиночный выброс", []float64{10, 11, 190, 11, 10}, []float64{10, 11, 11, 11, 10}, 1},
+		// Соседи 359° и 1° близки по окружности - выброс заменяется их средним 0°
+		{"выброс у 0°/360°", []float64{359, 359, 180, 1, 1}, []float64{359, 359, 0, 1, 1}, 1},
+		// Переход 0 → 359 → 1 - шум, а не выброс
+		{"переход через 0°", []float64{0, 359, 1, 0}, []float64{0, 359, 1, 0}, 0},
+		// Поворот: соседи далеко друг от друга - запись не трогается
+		{"поворот", []float64{10, 10, 55, 100, 100}, []float64{10, 10, 55, 100, 100}, 0},
+	} {
+		data := angleSeries(20, tt.angles...)
+		stage := rejectSpikes(data, 10)
+		assertAngles(t, tt.name, data, tt.want)
+		if stage.Changed != tt.changed {
+			t.Errorf("%s: изменено %d записей, ожидалось %d", tt.name, stage.Changed, tt.changed)
+		}
+	}
+}
+
+func TestMedianFilter(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		angles  []float64
+		want    []float64
+		changed int
+	}{
+		{"выброс", []float64{10, 10, 40, 10, 10}, []float64{10, 10, 10, 10, 10}, 1},
+		// Обычная медиана окна {0, 359, 1} дала бы 1°, среднее - 120°
+		{"переход 0 → 359 → 1", []float64{0, 359, 1, 0, 359}, []float64{0, 0, 0, 0, 0}, 3},
+	} {
+		data := angleSeries(20, tt.angles...)
+		stage := medianFilter(data, 3)
+		assertAngles(t, tt.name, data, tt.want)
+		if stage.Changed != tt.changed {
+			t.Errorf("%s: изменено %d записей, ожидалось %d", tt.name, stage.Changed, tt.changed)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	// Записи через 20 с, сетка 10 с: между 359° и 1° - 0°, а не 180°
+	data := angleSeries(20, 350, 359, 1, 11)
+	out, stage := resample(data, 10)
+	assertAngles(t, "сетка 10 с", out, []float64{350, 354.5, 359, 0, 1, 6, 11})
+	if stage.Samples != 7 || stage.Changed != 3 {
+		t.Errorf("записей %d, изменено %d, ожидалось 7 и 3", stage.Samples, stage.Changed)
+	}
+	for i, d := range out {
+		if got := d.Time.Sub(out[0].Time).Seconds(); got != float64(i)*10 {
+			t.Errorf("запись %d: время %.0f с, ожидалось %d с", i, got, i*10)
+		}
+	}
+
+	// Без меток времени этап пропускается
+	untimed := compassSeries(false, []float64{350, 359, 1})
+	if out, stage := resample(untimed, 10); stage.Skipped == "" || len(out) != len(untimed) {
+		t.Errorf("без меток времени: %+v", stage)
+	}
+
+	// Сетка длиннее resampleMaxSamples записей не строится
+	long := angleSeries(2*resampleMaxSamples, 10, 20)
+	if out, stage := resample(long, 1); stage.Skipped == "" || len(out) != len(long) {
+		t.Errorf("сетка на %d записей: %+v", 2*resampleMaxSamples, stage)
+	}
+}
+
+func TestGridParams(t *testing.T) {
+	params := models.DefaultAnalysisParams()
+	params.MinStableLen, params.MaxOutliers = 2, 1
+	params.Preprocess.ResampleInterval = 5
+
+	raw := angleSeries(20, 10, 10, 10, 100, 100, 100)
+	_, stages := Preprocess(raw, params.Preprocess, nil)
+	scaled := gridParams(raw, stages, params, nil)
+	// 2 записи по 20 с - 40 с, на сетке 5 с - 8 записей
+	if scaled.MinStableLen != 8 || scaled.MaxOutliers != 4 {
+		t.Errorf("MinStableLen = %d, MaxOutliers = %d, ожидалось 8 и 4", scaled.MinStableLen, scaled.MaxOutliers)
+	}
+
+	params.Preprocess.ResampleInterval = 0
+	_, stages = Preprocess(raw, params.Preprocess, nil)
+	if got := gridParams(raw, stages, params, nil); got.MinStableLen != 2 || got.MaxOutliers != 1 {
+		t.Errorf("без передискретизации пороги изменились: %d, %d", got.MinStableLen, got.MaxOutliers)
+	}
+}
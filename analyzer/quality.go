@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"compass_analyzer/models"
+)
+
+// Веса составляющих оценки качества
+const (
+	confidenceWeightAngle    = 0.5 // Отклонение поворота от номинального угла
+	confidenceWeightSpread   = 0.3 // Разброс углов на курсах до и после поворота
+	confidenceWeightOutliers = 0.2 // Выбросы внутри сегментов
+
+	qualityWeightTurns   = 0.7 // Средняя уверенность поворотов цикла
+	qualityWeightClosure = 0.3 // Ошибка возврата к начальному курсу после оборота
+)
+
+// clamp01 ограничивает значение диапазоном [0, 1]
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// marginTerm оценивает запас до допуска, 0–1: 1 - отклонения нет,
+// 0 - отклонение на границе допуска или больше
+func marginTerm(deviation, tolerance float64) float64 {
+	if tolerance <= 0 {
+		if deviation == 0 {
+			return 1
+		}
+		return 0
+	}
+	return clamp01(1 - deviation/tolerance)
+}
+
+// turnConfidence оценивает уверенность в повороте, 0–100:
+// насколько угол поворота близок к номинальному (относительно допуска),
+// насколько стабильны курсы до и после поворота (относительно порога
+// стабильности) и сколько выбросов пропущено внутри этих сегментов
+func turnConfidence(turn models.Turn, segments []AngleSegment, params models.AnalysisParams, profile models.ProcedureProfile) float64 {
+	angleTerm := clamp01(1 - math.Abs(turn.Diff-profile.StepAngle)/profile.StepToleranceFor(params))
+
+	spread, outliers := 0.0, 0
+	for _, idx := range []int{turn.FromSegment, turn.ToSegment} {
+		if idx < 0 || idx >= len(segments) {
+			continue
+		}
+		spread = math.Max(spread, segments[idx].StdDev)
+		outliers += segments[idx].Outliers
+	}
+	spreadTerm := clamp01(1 - spread/params.StabilityThreshold)
+	outlierTerm := 1 / (1 + float64(outliers))
+
+	return 100 * (confidenceWeightAngle*angleTerm + confidenceWeightSpread*spreadTerm + confidenceWeightOutliers*outlierTerm)
+}
+
+// scoreQuality вычисляет уверенность каждого поворота (models.Turn.Confidence)
+// и оценку качества калибровки 0–100 (AnalysisResult.Quality).
+// Оценка складывается из средней уверенности поворотов цикла (недостающие
+// повороты считаются с нулевой уверенностью) и ошибки замыкания: расхождения
+// курса после оборота с начальным (AnalysisResult.Closure, см. validateClosure)
+// относительно допуска возврата. Сумма углов поворотов близка к номинальной
+// по построению цепочки и почти ничего не говорит о замыкании, поэтому по ней
+// замыкание оценивается только для профилей, цикл которых не возвращает
+// к начальному курсу. Вызывается после validateClosure. Компас, прошедший
+// проверки с малым запасом, получает низкую оценку и может быть отправлен
+// на перепроверку (см. NeedsRetest).
+func scoreQuality(result *AnalysisResult, profile models.ProcedureProfile, logFile *os.File) {
+	params := result.Params
+
+	var confidenceSum, totalDiff float64
+	for i := range result.Turns {
+		confidence := turnConfidence(result.Turns[i], result.Segments, params, profile)
+		result.Turns[i].Confidence = confidence
+		confidenceSum += confidence
+		totalDiff += result.Turns[i].Diff
+	}
+	turnsTerm := confidenceSum / 100 / float64(profile.StepCount)
+
+	closureTerm := 0.0
+	switch {
+	case len(result.Turns) != profile.StepCount:
+	case result.Closure != nil:
+		closureTerm = marginTerm(math.Abs(result.Closure.Error), profile.ReturnToleranceFor(params))
+	case !profile.FullRotation():
+		closureTerm = marginTerm(math.Abs(totalDiff-profile.TotalAngle()), profile.SumToleranceFor(params))
+	}
+
+	result.Quality = 100 * (qualityWeightTurns*clamp01(turnsTerm) + qualityWeightClosure*closureTerm)
+
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== ОЦЕНКА КАЧЕСТВА КАЛИБРОВКИ ===\n")
+		for i, turn := range result.Turns {
+			fmt.Fprintf(logFile, "Поворот %d: Δ=%.2f°, уверенность %.0f/100\n", i+1, turn.Diff, turn.Confidence)
+		}
+		if result.Closure != nil {
+			fmt.Fprintf(logFile, "Возврат к начальному курсу (%+.2f°): %.0f/100\n", result.Closure.Error, closureTerm*100)
+		} else {
+			fmt.Fprintf(logFile, "Замыкание цикла: %.0f/100\n", closureTerm*100)
+		}
+		fmt.Fprintf(logFile, "Оценка качества: %.0f/100\n", result.Quality)
+		if result.NeedsRetest() {
+			fmt.Fprintf(logFile, "⚠ Оценка ниже %.0f - компас прошел проверки с малым запасом, рекомендуется перепроверка\n",
+				params.RetestQuality)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+
+	"compass_analyzer/models"
+)
+
+// TestScoreQualityClosure проверяет, что замыкание оценивается по возврату
+// к начальному курсу, а не по сумме поворотов: повороты ровно по 90°
+// дают сумму 360° при любом уходе курса
+func TestScoreQualityClosure(t *testing.T) {
+	profile, err := models.FindProfile("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	params := models.DefaultAnalysisParams()
+	tolerance := profile.ReturnToleranceFor(params)
+
+	segments := make([]AngleSegment, profile.StepCount+1)
+	var turns []models.Turn
+	for i := 0; i < profile.StepCount; i++ {
+		turns = append(turns, models.Turn{Diff: profile.StepAngle, FromSegment: i, ToSegment: i + 1, IsClockwise: true})
+	}
+
+	for _, tt := range []struct {
+		name    string
+		closure *Closure
+		quality float64
+	}{
+		{"курс вернулся", &Closure{Error: 0}, 100},
+		{"уход на половину допуска", &Closure{Error: tolerance / 2}, 100 - 100*qualityWeightClosure/2},
+		{"уход на допуск", &Closure{Error: -tolerance}, 100 - 100*qualityWeightClosure},
+		{"возврат не измерен", nil, 100 - 100*qualityWeightClosure},
+	} {
+		result := AnalysisResult{Params: params, Segments: segments, Turns: append([]models.Turn(nil), turns...), Closure: tt.closure}
+		scoreQuality(&result, profile, nil)
+		if math.Abs(result.Quality-tt.quality) > 1e-9 {
+			t.Errorf("%s: оценка %.2f, ожидалось %.2f", tt.name, result.Quality, tt.quality)
+		}
+	}
+
+	// Профиль без полного оборота: замыкание - по сумме поворотов
+	partial := profile
+	partial.StepCount = 3
+	result := AnalysisResult{Params: params, Segments: segments, Turns: append([]models.Turn(nil), turns[:3]...)}
+	scoreQuality(&result, partial, nil)
+	if math.Abs(result.Quality-100) > 1e-9 {
+		t.Errorf("3 × 90°: оценка %.2f, ожидалось 100", result.Quality)
+	}
+}
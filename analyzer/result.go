@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+
+	"compass_analyzer/models"
+)
+
+// AnalysisResult - полный результат анализа данных одного компаса:
+// найденные сегменты, все повороты-кандидаты, выбранная последовательность
+// и результаты каждой проверки. Вердикт IsValid - true, если не провалена
+// ни одна проверка, влияющая на вердикт.
+type AnalysisResult struct {
+	Profile     string                `json:"profile"`               // Имя профиля процедуры
+	Segmenter   string                `json:"segmenter"`             // Алгоритм поиска стабильных сегментов
+	Params      models.AnalysisParams `json:"params"`                // Параметры анализа
+	Samples     int                   `json:"samples"`               // Количество исходных записей
+	Preprocess  []PreprocessStage     `json:"preprocess"`            // Этапы предварительной обработки
+	Series      *Series               `json:"series,omitempty"`      // Исходный и обработанный ряды азимута для графиков
+	Segments    []AngleSegment        `json:"segments"`              // Стабильные сегменты
+	Candidates  []models.Turn         `json:"candidates"`            // Все найденные повороты на угол шага
+	Turns       []models.Turn         `json:"turns"`                 // Выбранная последовательность поворотов
+	Checks      []models.Check        `json:"checks"`                // Результаты проверок по порядку выполнения
+	Health      *SensorHealth         `json:"health,omitempty"`      // Проверка исправности датчиков (только для телеметрии)
+	Device      *DeviceReport         `json:"device,omitempty"`      // Состояния калибровки, о которых сообщает прибор (только для телеметрии)
+	Window      *CalibrationWindow    `json:"window,omitempty"`      // Окно калибровки, в котором искались повороты (только для телеметрии)
+	Closure     *Closure              `json:"closure,omitempty"`     // Возврат к начальному курсу после полного оборота
+	Cycles      []Cycle               `json:"cycles,omitempty"`      // Все полные циклы поворотов в файле
+	Positions   []PositionStats       `json:"positions,omitempty"`   // Повторяемость позиций между циклами (от двух циклов)
+	Tilt        []TiltStats           `json:"tilt,omitempty"`        // Наклон стенда в каждой позиции (только для телеметрии)
+	Deviation   *Deviation            `json:"deviation,omitempty"`   // Кривая девиации компенсированного азимута (только для телеметрии)
+	Drift       *ThermalDrift         `json:"drift,omitempty"`       // Температурный дрейф курса (только для телеметрии)
+	MagneticFit *MagneticFit          `json:"magneticFit,omitempty"` // Проверка поля магнитометра (только для телеметрии)
+	Quality     float64               `json:"quality"`               // Оценка качества калибровки, 0–100 (см. scoreQuality)
+	IsValid     bool                  `json:"isValid"`               // Итоговый вердикт
+}
+
+// NeedsRetest сообщает, что компас прошел проверки, но с малым запасом:
+// оценка качества ниже порога перепроверки из параметров анализа
+func (r AnalysisResult) NeedsRetest() bool {
+	return r.IsValid && r.Quality < r.Params.RetestQuality
+}
+
+// FailedChecks возвращает проверки, которые не пройдены и влияют на вердикт
+func (r AnalysisResult) FailedChecks() []models.Check {
+	var failed []models.Check
+	for _, c := range r.Checks {
+		if c.Failed() {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Errors возвращает описания причин отбраковки в порядке проверок
+func (r AnalysisResult) Errors() []string {
+	var errors []string
+	for _, c := range r.FailedChecks() {
+		errors = append(errors, c.Message)
+	}
+	return errors
+}
+
+// Reason возвращает код первой причины отбраковки (пустая строка, если компас годен)
+func (r AnalysisResult) Reason() models.ReasonCode {
+	for _, c := range r.Checks {
+		if c.Failed() {
+			return c.Reason
+		}
+	}
+	return ""
+}
+
+// Check возвращает проверку по имени
+func (r AnalysisResult) Check(name string) (models.Check, bool) {
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return models.Check{}, false
+}
+
+// updateVerdict пересчитывает IsValid по результатам проверок
+func (r *AnalysisResult) updateVerdict() {
+	r.IsValid = len(r.FailedChecks()) == 0
+}
+
+// logCheck записывает результат проверки в лог в формате остальных этапов анализа
+func logCheck(logFile *os.File, c models.Check) {
+	if logFile == nil {
+		return
+	}
+	switch {
+	case c.Passed:
+		fmt.Fprintf(logFile, "✓ %s\n", c.Message)
+	case c.Advisory:
+		fmt.Fprintf(logFile, "  ⚠ Предупреждение: %s\n", c.Message)
+	default:
+		fmt.Fprintf(logFile, "✗ %s\n", c.Message)
+	}
+}
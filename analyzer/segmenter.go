@@ -0,0 +1,346 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// Segmenter - алгоритм поиска стабильных сегментов (курсов, на которых компас
+// неподвижен). Алгоритм выбирается полем Segmenter профиля процедуры
+// (см. models.SegmenterNames), все реализации возвращают сегменты в одном
+// формате, поэтому поиск поворотов и проверки от алгоритма не зависят.
+type Segmenter interface {
+	// Name возвращает имя алгоритма (models.SegmenterGreedy и т.д.)
+	Name() string
+	// Segment находит стабильные сегменты в записях компаса
+	Segment(data []models.CompassData, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) []AngleSegment
+}
+
+// Segmenters возвращает все алгоритмы поиска сегментов в порядке models.SegmenterNames
+func Segmenters() []Segmenter {
+	return []Segmenter{greedySegmenter{}, changePointSegmenter{}, windowSegmenter{}}
+}
+
+// FindSegmenter ищет алгоритм поиска сегментов по имени без учета регистра.
+// Пустое имя означает алгоритм по умолчанию (models.SegmenterGreedy).
+func FindSegmenter(name string) (Segmenter, error) {
+	if name == "" {
+		name = models.SegmenterGreedy
+	}
+	for _, s := range Segmenters() {
+		if strings.EqualFold(s.Name(), name) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("алгоритм поиска сегментов «%s» не найден (доступны: %s)",
+		name, strings.Join(models.SegmenterNames(), ", "))
+}
+
+// segmenterFor возвращает алгоритм поиска сегментов профиля.
+// Профили проверяются при загрузке (models.ProcedureProfile.Validate),
+// поэтому для неизвестного имени используется алгоритм по умолчанию.
+func segmenterFor(profile models.ProcedureProfile) Segmenter {
+	s, err := FindSegmenter(profile.Segmenter)
+	if err != nil {
+		return greedySegmenter{}
+	}
+	return s
+}
+
+// dataAngles возвращает азимуты записей компаса
+func dataAngles(data []models.CompassData) []float64 {
+	angles := make([]float64, len(data))
+	for i, d := range data {
+		angles[i] = d.Angle
+	}
+	return angles
+}
+
+// newSegment создает стабильный сегмент [start:end] из углов segAngles
+// (без пропущенных выбросов) и заполняет его статистику
+func newSegment(segAngles []float64, start, end, outliers int) AngleSegment {
+	seg := AngleSegment{
+		StartIndex: start,
+		EndIndex:   end,
+		AvgAngle:   circularMean(segAngles),
+		AllAngles:  segAngles,
+		IsStable:   true,
+		Outliers:   outliers,
+	}
+	describeSegment(&seg)
+	return seg
+}
+
+// logSegments записывает найденные сегменты в лог
+func logSegments(logFile *os.File, segments []AngleSegment) {
+	if logFile == nil {
+		return
+	}
+	fmt.Fprintf(logFile, "\n=== Результаты поиска стабильных сегментов ===\n")
+	fmt.Fprintf(logFile, "Найдено сегментов: %d\n", len(segments))
+	for i, seg := range segments {
+		fmt.Fprintf(logFile, "Сегмент %d: индексы %d-%d (длина: %d), репрез. угол: %.2f°, разброс σ=%.2f° (MAD %.2f°)\n",
+			i+1, seg.StartIndex, seg.EndIndex, len(seg.AllAngles), seg.AvgAngle, seg.StdDev, seg.MAD)
+	}
+}
+
+// greedySegmenter - исходный алгоритм: наращивание сегмента, пока угол близок
+// к среднему сегмента, с пропуском выбросов и слиянием близких сегментов
+// (см. findStableSegments)
+type greedySegmenter struct{}
+
+func (greedySegmenter) Name() string { return models.SegmenterGreedy }
+
+func (greedySegmenter) Segment(data []models.CompassData, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) []AngleSegment {
+	return findStableSegments(dataAngles(data), params.StabilityThreshold, params.MinStableLen, params.MaxOutliers, logFile)
+}
+
+// Параметры поиска точек смены курса
+const (
+	changePointNoiseFloor  = 1.0 // Минимальная оценка шума, градусы (разрешение азимута в файле данных)
+	changePointPenaltyGain = 2.0 // Множитель штрафа за разбиение σ²·ln(n)
+)
+
+// changePointSegmenter ищет точки смены курса методом PELT: ряд азимутов
+// (развернутый через 0°/360°) разбивается на участки постоянного уровня так,
+// чтобы сумма квадратов отклонений от среднего участка плюс штраф за каждое
+// разбиение была минимальной. В отличие от наращивания по среднему, решение
+// принимается по всему ряду сразу, и медленный дрейф не «протаскивает»
+// сегмент через поворот. Стабильными считаются участки не короче
+// MinStableLen записей с круговым СКО не больше StabilityThreshold.
+type changePointSegmenter struct{}
+
+func (changePointSegmenter) Name() string { return models.SegmenterChangePoint }
+
+func (changePointSegmenter) Segment(data []models.CompassData, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) []AngleSegment {
+	angles := dataAngles(data)
+	var segments []AngleSegment
+	if len(angles) < params.MinStableLen {
+		return segments
+	}
+
+	unwrapped := unwrapAngles(angles)
+	sigma := noiseLevel(unwrapped, params.StabilityThreshold)
+	penalty := changePointPenaltyGain * sigma * sigma * math.Log(float64(len(angles)))
+	bounds := pelt(unwrapped, penalty)
+
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== Поиск точек смены курса (PELT) ===\n")
+		fmt.Fprintf(logFile, "Параметры: шум σ=%.2f°, штраф за разбиение=%.1f, stabilityThreshold=%.2f°, minStableLen=%d\n",
+			sigma, penalty, params.StabilityThreshold, params.MinStableLen)
+		fmt.Fprintf(logFile, "Всего углов для анализа: %d, участков: %d\n", len(angles), len(bounds)-1)
+	}
+
+	for k := 0; k+1 < len(bounds); k++ {
+		start, end := bounds[k], bounds[k+1]-1
+		length := end - start + 1
+		if length < params.MinStableLen {
+			continue
+		}
+		seg := newSegment(append([]float64(nil), angles[start:end+1]...), start, end, 0)
+		if seg.StdDev > params.StabilityThreshold {
+			if logFile != nil {
+				fmt.Fprintf(logFile, "  ✗ Участок [%d:%d] отклонён: σ=%.2f° > %.2f°\n",
+					start, end, seg.StdDev, params.StabilityThreshold)
+			}
+			continue
+		}
+		if logFile != nil {
+			fmt.Fprintf(logFile, "  ✓ Участок [%d:%d] принят: длина=%d, avg=%.2f°, σ=%.2f°\n",
+				start, end, length, seg.AvgAngle, seg.StdDev)
+		}
+		segments = append(segments, seg)
+	}
+
+	segments = mergeCloseSegments(segments, params.StabilityThreshold, logFile)
+	logSegments(logFile, segments)
+	return segments
+}
+
+// unwrapAngles разворачивает ряд азимутов: переход через 0°/360° заменяется
+// продолжением ряда (359°, 1° → 359°, 361°)
+func unwrapAngles(angles []float64) []float64 {
+	unwrapped := make([]float64, len(angles))
+	if len(angles) == 0 {
+		return unwrapped
+	}
+	unwrapped[0] = angles[0]
+	for i := 1; i < len(angles); i++ {
+		unwrapped[i] = unwrapped[i-1] + circular.Diff(angles[i-1], angles[i])
+	}
+	return unwrapped
+}
+
+// noiseLevel оценивает шум азимута на неподвижном компасе по медиане разностей
+// соседних записей, не превышающих порог стабильности (разности во время
+// поворотов не учитываются), не меньше changePointNoiseFloor
+func noiseLevel(series []float64, threshold float64) float64 {
+	var diffs []float64
+	for i := 1; i < len(series); i++ {
+		if d := math.Abs(series[i] - series[i-1]); d <= threshold {
+			diffs = append(diffs, d)
+		}
+	}
+	if len(diffs) == 0 {
+		return changePointNoiseFloor
+	}
+	sort.Float64s(diffs)
+	// Для нормального шума медиана |x|·1.4826 - оценка СКО, разность двух записей имеет СКО σ·√2
+	sigma := 1.4826 * diffs[len(diffs)/2] / math.Sqrt2
+	return math.Max(sigma, changePointNoiseFloor)
+}
+
+// pelt разбивает ряд на участки постоянного уровня (Killick et al., 2012)
+// со стоимостью участка - суммой квадратов отклонений от его среднего.
+// Возвращает границы участков: 0, b1, ..., len(series); участок k - [b(k), b(k+1))
+func pelt(series []float64, penalty float64) []int {
+	n := len(series)
+	sum := make([]float64, n+1)
+	sumSq := make([]float64, n+1)
+	for i, v := range series {
+		sum[i+1] = sum[i] + v
+		sumSq[i+1] = sumSq[i] + v*v
+	}
+	cost := func(s, t int) float64 {
+		length := float64(t - s)
+		total := sum[t] - sum[s]
+		return sumSq[t] - sumSq[s] - total*total/length
+	}
+
+	best := make([]float64, n+1)
+	last := make([]int, n+1)
+	best[0] = -penalty
+	candidates := []int{0}
+	for t := 1; t <= n; t++ {
+		best[t] = math.Inf(1)
+		for _, s := range candidates {
+			if c := best[s] + cost(s, t) + penalty; c < best[t] {
+				best[t], last[t] = c, s
+			}
+		}
+		// Отсечение PELT: точка s больше не может стать последней границей
+		pruned := candidates[:0]
+		for _, s := range candidates {
+			if best[s]+cost(s, t) <= best[t] {
+				pruned = append(pruned, s)
+			}
+		}
+		candidates = append(pruned, t)
+	}
+
+	var bounds []int
+	for t := n; t > 0; t = last[t] {
+		bounds = append(bounds, t)
+	}
+	bounds = append(bounds, 0)
+	sort.Ints(bounds)
+	return bounds
+}
+
+// Параметры поиска курсов по времени удержания
+const (
+	defaultDwellWindow = 20.0 // Окно удержания курса, секунды, если в профиле не задан MinDwell
+)
+
+// windowSegmenter ищет курсы, удерживаемые не меньше заданного времени:
+// опорный угол - круговая медиана записей первого окна, сегмент продолжается,
+// пока азимут остается в пределах StabilityThreshold от опорного угла
+// (допускается до MaxOutliers выбросов подряд). Опорный угол не пересчитывается,
+// поэтому медленный дрейф заканчивает сегмент. Окно - MinDwell профиля
+// (по умолчанию defaultDwellWindow). Если у записей нет меток времени,
+// окно задается количеством записей MinStableLen.
+type windowSegmenter struct{}
+
+func (windowSegmenter) Name() string { return models.SegmenterWindow }
+
+func (windowSegmenter) Segment(data []models.CompassData, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) []AngleSegment {
+	angles := dataAngles(data)
+	var segments []AngleSegment
+	if len(angles) < 2 {
+		return segments
+	}
+
+	window := profile.MinDwell
+	if window <= 0 {
+		window = defaultDwellWindow
+	}
+	timed := hasTimestamps(data)
+	// windowEnd возвращает последний индекс окна, начинающегося с записи start
+	windowEnd := func(start int) int {
+		if !timed {
+			return start + params.MinStableLen - 1
+		}
+		end := start
+		for end+1 < len(data) && elapsed(data, start, end+1) <= window {
+			end++
+		}
+		return end
+	}
+	// longEnough проверяет, что сегмент [start:end] удерживался не меньше окна
+	longEnough := func(start, end int) bool {
+		if !timed {
+			return end-start+1 >= params.MinStableLen
+		}
+		return end > start && elapsed(data, start, end) >= window
+	}
+
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== Поиск курсов по времени удержания ===\n")
+		if timed {
+			fmt.Fprintf(logFile, "Параметры: окно %.0f с, stabilityThreshold=%.2f°, maxOutliers=%d\n",
+				window, params.StabilityThreshold, params.MaxOutliers)
+		} else {
+			fmt.Fprintf(logFile, "ℹ У записей нет меток времени - окно %d записей, stabilityThreshold=%.2f°, maxOutliers=%d\n",
+				params.MinStableLen, params.StabilityThreshold, params.MaxOutliers)
+		}
+		fmt.Fprintf(logFile, "Всего углов для анализа: %d\n", len(angles))
+	}
+
+	i := 0
+	for i < len(angles) {
+		first := windowEnd(i)
+		if first >= len(angles) {
+			break
+		}
+		anchor := medianAngle(angles[i : first+1])
+
+		var segAngles []float64
+		end, outliers, run := i, 0, 0
+		for j := i; j < len(angles); j++ {
+			if normalizeAngleDifference(angles[j], anchor) <= params.StabilityThreshold {
+				segAngles = append(segAngles, angles[j])
+				end, run = j, 0
+				continue
+			}
+			if run >= params.MaxOutliers {
+				break
+			}
+			run++
+			outliers++
+		}
+		// Выбросы после последней стабильной записи в сегмент не входят
+		outliers -= run
+
+		if end == i || !longEnough(i, end) {
+			i++
+			continue
+		}
+		seg := newSegment(segAngles, i, end, outliers)
+		if logFile != nil {
+			fmt.Fprintf(logFile, "  ✓ Курс [%d:%d] принят: опорный угол %.2f°, длина=%d, σ=%.2f°, outliers=%d\n",
+				i, end, anchor, end-i+1, seg.StdDev, outliers)
+		}
+		segments = append(segments, seg)
+		i = end + 1
+	}
+
+	segments = mergeCloseSegments(segments, params.StabilityThreshold, logFile)
+	logSegments(logFile, segments)
+	return segments
+}
@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// holdAngles - компас неподвижен на курсе heading: шум ±1° (разрешение азимута в файле)
+func holdAngles(heading float64, count int) []float64 {
+	noise := []float64{0, 1, 0, -1}
+	angles := make([]float64, count)
+	for i := range angles {
+		angles[i] = math.Mod(heading+noise[i%len(noise)]+360, 360)
+	}
+	return angles
+}
+
+// driftAngles - курс медленно уходит от from на step градусов за запись
+func driftAngles(from, step float64, count int) []float64 {
+	angles := make([]float64, count)
+	for i := range angles {
+		angles[i] = math.Mod(from+step*float64(i)+360, 360)
+	}
+	return angles
+}
+
+// compassSeries собирает записи из участков; timed - записи через 5 с
+// (окно удержания встроенного профиля - 10 с), иначе без меток времени
+func compassSeries(timed bool, parts ...[]float64) []models.CompassData {
+	start := time.Unix(1747982290, 0)
+	var data []models.CompassData
+	for _, part := range parts {
+		for _, angle := range part {
+			d := models.CompassData{Angle: angle}
+			if timed {
+				d.Time = start.Add(time.Duration(len(data)) * 5 * time.Second)
+			}
+			data = append(data, d)
+		}
+	}
+	return data
+}
+
+func TestSegmenters(t *testing.T) {
+	params := models.DefaultAnalysisParams()
+	profile, err := models.FindProfile("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	type segment struct {
+		start, end int
+		heading    float64
+	}
+	for _, tt := range []struct {
+		name string
+		data []models.CompassData
+		want []segment
+	}{
+		{"поворот на 90°", compassSeries(true, holdAngles(10, 8), holdAngles(100, 8)),
+			[]segment{{0, 7, 10}, {8, 15, 100}}},
+		// Курс колеблется около 0°: 0, 1, 0, 359 - один сегмент со средним 0°, а не 180°
+		{"переход через 0°/360°", compassSeries(true, holdAngles(0, 8), holdAngles(90, 8)),
+			[]segment{{0, 7, 0}, {8, 15, 90}}},
+		{"без меток времени", compassSeries(false, holdAngles(10, 8), holdAngles(100, 8)),
+			[]segment{{0, 7, 10}, {8, 15, 100}}},
+	} {
+		for _, s := range Segmenters() {
+			got := s.Segment(tt.data, params, profile, nil)
+			if len(got) != len(tt.want) {
+				t.Errorf("%s, %s: %d сегментов, ожидалось %d", tt.name, s.Name(), len(got), len(tt.want))
+				continue
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.StartIndex != w.start || g.EndIndex != w.end || math.Abs(circular.Diff(w.heading, g.AvgAngle)) > 0.5 {
+					t.Errorf("%s, %s: сегмент %d [%d:%d] %.2f°, ожидалось [%d:%d] %.2f°",
+						tt.name, s.Name(), i+1, g.StartIndex, g.EndIndex, g.AvgAngle, w.start, w.end, w.heading)
+				}
+			}
+		}
+	}
+}
+
+// TestSegmentersDrift - медленный дрейф курса (0,5° за запись, 24° за позицию)
+// перед поворотом: новые алгоритмы не должны принимать дрейф за один
+// неподвижный курс и «протаскивать» сегмент через поворот
+func TestSegmentersDrift(t *testing.T) {
+	params := models.DefaultAnalysisParams()
+	profile, err := models.FindProfile("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	const turn = 48 // Первая запись после поворота
+	data := compassSeries(true, driftAngles(10, 0.5, turn), holdAngles(100, 8))
+
+	for _, name := range []string{models.SegmenterChangePoint, models.SegmenterWindow} {
+		s, err := FindSegmenter(name)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		segments := s.Segment(data, params, profile, nil)
+		if len(segments) < 3 {
+			t.Fatalf("%s: %d сегментов, ожидалось не меньше 3 (дрейф разбит на части и курс после поворота)", name, len(segments))
+		}
+		for _, seg := range segments {
+			if seg.StartIndex < turn && seg.EndIndex >= turn {
+				t.Errorf("%s: сегмент [%d:%d] проходит через поворот", name, seg.StartIndex, seg.EndIndex)
+			}
+			for _, angle := range seg.AllAngles {
+				if d := math.Abs(circular.Diff(seg.AvgAngle, angle)); d > 2*params.StabilityThreshold {
+					t.Errorf("%s: в сегменте [%d:%d] угол %.1f° отходит от среднего %.1f° на %.1f°",
+						name, seg.StartIndex, seg.EndIndex, angle, seg.AvgAngle, d)
+					break
+				}
+			}
+		}
+		last := segments[len(segments)-1]
+		if last.StartIndex != turn || math.Abs(circular.Diff(100, last.AvgAngle)) > 0.5 {
+			t.Errorf("%s: последний сегмент [%d:%d] %.2f°, ожидалось [%d:%d] 100°",
+				name, last.StartIndex, last.EndIndex, last.AvgAngle, turn, len(data)-1)
+		}
+	}
+}
+
+func TestPelt(t *testing.T) {
+	series := append(append(holdAngles(10, 8), holdAngles(100, 6)...), holdAngles(190, 10)...)
+	got := pelt(series, 2*math.Log(float64(len(series))))
+	want := []int{0, 8, 14, 24}
+	if len(got) != len(want) {
+		t.Fatalf("границы %v, ожидалось %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("границы %v, ожидалось %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"compass_analyzer/models"
+)
+
+// Имена алгоритмов анализа поворотов (см. Strategies)
+const (
+	StrategyCurrent = "current" // Текущий алгоритм (AnalyzeCompassData)
+	StrategyLegacy  = "legacy"  // Прежний детектор с фиксированными порогами
+)
+
+// Strategy - алгоритм анализа поворотов целиком: поиск сегментов, поворотов
+// и проверки последовательности. В отличие от Segmenter, который заменяет
+// только поиск сегментов, стратегии позволяют сравнить на одних данных
+// алгоритмы с разной логикой (команда compare). Проверка поля
+// магнитометра от стратегии не зависит и в сравнение не входит.
+type Strategy interface {
+	// Name возвращает имя алгоритма (StrategyCurrent и т.д.)
+	Name() string
+	// Description возвращает описание алгоритма для оператора
+	Description() string
+	// Analyze анализирует записи компаса по профилю процедуры
+	Analyze(data []models.CompassData, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) AnalysisResult
+}
+
+// Strategies возвращает все алгоритмы анализа поворотов
+func Strategies() []Strategy {
+	return []Strategy{currentStrategy{}, legacyStrategy{}}
+}
+
+// StrategyNames возвращает имена всех алгоритмов анализа поворотов
+func StrategyNames() []string {
+	var names []string
+	for _, s := range Strategies() {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+// FindStrategy ищет алгоритм анализа поворотов по имени без учета регистра.
+// Пустое имя означает текущий алгоритм.
+func FindStrategy(name string) (Strategy, error) {
+	if name == "" {
+		name = StrategyCurrent
+	}
+	for _, s := range Strategies() {
+		if strings.EqualFold(s.Name(), name) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("алгоритм анализа «%s» не найден (доступны: %s)",
+		name, strings.Join(StrategyNames(), ", "))
+}
+
+// currentStrategy - текущий алгоритм анализа (AnalyzeCompassData)
+type currentStrategy struct{}
+
+func (currentStrategy) Name() string { return StrategyCurrent }
+
+func (currentStrategy) Description() string {
+	return "текущий алгоритм: параметры анализа и профиль процедуры, все проверки"
+}
+
+func (currentStrategy) Analyze(data []models.CompassData, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) AnalysisResult {
+	return AnalyzeCompassData(data, params, profile, logFile)
+}
@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+
+	"compass_analyzer/models"
+	"compass_analyzer/parser"
+)
+
+// AnalyzeTelemetry выполняет полный анализ записей телеметрии компаса
+// без журнала блока AB (см. AnalyzeTelemetryWithJournal).
+func AnalyzeTelemetry(records []models.TelemetryRecord, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) AnalysisResult {
+	return AnalyzeTelemetryWithJournal(records, nil, params, profile, logFile)
+}
+
+// AnalyzeTelemetryWithJournal выполняет полный анализ записей телеметрии компаса:
+// проверку исправности датчиков (см. CheckSensorHealth), разбор состояний,
+// о которых сообщает прибор (см. validateDeviceStates), выбор окна калибровки
+// (см. FindCalibrationWindow), поиск поворотов
+// процедуры калибровки по столбцу азимута params.AzimuthColumn («Азимут RAW»
+// или компенсированный азимут) с проверкой времени процедуры
+// (см. AnalyzeCompassData), кривую девиации компенсированного азимута
+// (см. measureDeviation), проверку наклона стенда
+// в каждой позиции по столбцам «Дифферент RAW» и «Крен RAW» (см. validateTilt),
+// проверку температурного дрейфа курса (см. validateThermalDrift) и проверку поля магнитометра аппроксимацией эллипсом (см. FitMagnetometer).
+//
+// Повороты, девиация, наклон и дрейф оцениваются только по записям окна
+// калибровки - так же, как по файлу, обрезанному вручную; исправность
+// датчиков, состояния прибора и поле магнитометра (ему нужно вращение
+// по кругу из самой калибровки) - по всему файлу. Если окно не найдено или
+// его поиск отключен (params.CalibrationWindow), анализируется весь файл.
+//
+// Компас считается откалиброванным, только если пройдены все проверки:
+// прибор, прошедший повороты процедуры, но с плохой аппроксимацией поля,
+// отбраковывается (если проверки поля в профиле не предупреждения -
+// ProcedureProfile.MagFitAdvisory; так же для наклона и дрейфа - TiltAdvisory
+// и DriftAdvisory). Проверки исправности датчиков идут первыми, поэтому
+// у прибора с неисправным датчиком причина отбраковки - неисправность
+// (models.ReasonCode.SensorFault), а не ошибка процедуры; за ними идут
+// состояния, о которых сообщает сам прибор (только предупреждения).
+//
+// Параметры:
+//   - records: записи телеметрии, упорядоченные по времени
+//   - journal: сообщения журнала блока AB для поиска окна (может быть nil)
+//   - params: пороги алгоритма поиска поворотов
+//   - profile: профиль процедуры калибровки (углы, количество и направление поворотов)
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - AnalysisResult: результат анализа; проверки датчиков и состояний прибора
+//     идут перед проверками поворотов, проверки наклона, дрейфа и поля
+//     магнитометра - после них; подробности - в Health, Device, Window,
+//     Tilt, Deviation, Drift и MagneticFit
+func AnalyzeTelemetryWithJournal(records []models.TelemetryRecord, journal []parser.JournalEntry, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) AnalysisResult {
+	health := CheckSensorHealth(records, logFile)
+	deviceChecks, device := validateDeviceStates(records, logFile)
+
+	procedure, window := CalibrationRecords(records, journal, params)
+	logCalibrationWindow(logFile, window, params, len(records))
+
+	data := make([]models.CompassData, len(procedure))
+	for i, r := range procedure {
+		data[i] = r.CompassDataFor(params.AzimuthColumn)
+	}
+
+	result := AnalyzeCompassData(data, params, profile, logFile)
+	turnsValid := result.IsValid
+	result.Health = &health
+	result.Device = &device
+	result.Window = window
+	procedureChecks := result.Checks
+	result.Checks = append(append([]models.Check(nil), health.Checks...), deviceChecks...)
+	result.Checks = append(result.Checks, procedureChecks...)
+
+	positions := telemetryPositions(data, result.Series, result.Segments, result.Turns)
+	result.Deviation = measureDeviation(procedure, positions, logFile)
+	result.Tilt = measureTilt(procedure, positions)
+	tiltChecks := validateTilt(result.Tilt, profile, logFile)
+	result.Checks = append(result.Checks, tiltChecks...)
+	tiltPassed := true
+	for _, c := range tiltChecks {
+		if c.Failed() {
+			tiltPassed = false
+		}
+	}
+
+	result.Drift = measureThermalDrift(procedure, positions, profile.DriftTempSpan())
+	driftChecks := validateThermalDrift(result.Drift, profile, logFile)
+	result.Checks = append(result.Checks, driftChecks...)
+
+	fit := FitMagnetometer(records, profile, logFile)
+	result.MagneticFit = &fit
+	result.Checks = append(result.Checks, fit.Checks...)
+	result.updateVerdict()
+
+	if logFile != nil && turnsValid && !health.Passed {
+		fmt.Fprintf(logFile, "\n✗ Повороты найдены, но датчики неисправны - компас отбракован\n")
+	}
+	if logFile != nil && turnsValid && !tiltPassed {
+		fmt.Fprintf(logFile, "\n✗ Повороты найдены, но стенд наклонен - компас отбракован\n")
+	}
+	if logFile != nil && turnsValid && len(driftChecks) > 0 && driftChecks[0].Failed() {
+		fmt.Fprintf(logFile, "\n✗ Повороты найдены, но курс дрейфует с температурой - компас отбракован\n")
+	}
+	if logFile != nil && turnsValid && !fit.Passed {
+		fmt.Fprintf(logFile, "\n✗ Повороты найдены, но проверка поля магнитометра не пройдена - компас отбракован\n")
+	}
+
+	return result
+}
+
+// telemetryPosition - позиция цикла поворотов (стабильный сегмент)
+// и записи телеметрии, которые к ней относятся
+type telemetryPosition struct {
+	Position int     // Номер позиции в цепочке поворотов (с 1)
+	Heading  float64 // Курс сегмента, градусы
+	Records  []int   // Индексы записей телеметрии
+}
+
+// telemetryPositions возвращает позиции цепочки поворотов (сегмент до первого
+// поворота и после каждого поворота); если поворотов нет - все стабильные
+// сегменты. Индексы сегментов относятся к ряду после предварительной обработки,
+// поэтому записи телеметрии выбираются по времени ряда series (по номеру
+// записи, если у записей нет меток времени). Позиции без записей пропускаются.
+func telemetryPositions(data []models.CompassData, series *Series, segments []AngleSegment, turns []models.Turn) []telemetryPosition {
+	if len(data) == 0 || series == nil {
+		return nil
+	}
+	var chain []int
+	if len(turns) > 0 {
+		chain = append(chain, turns[0].FromSegment)
+		for _, turn := range turns {
+			chain = append(chain, turn.ToSegment)
+		}
+	} else {
+		for i := range segments {
+			chain = append(chain, i)
+		}
+	}
+
+	times := seriesTimes(data, data[0].Time, hasTimestamps(data))
+	var positions []telemetryPosition
+	for i, segIdx := range chain {
+		if segIdx < 0 || segIdx >= len(segments) {
+			continue
+		}
+		seg := segments[segIdx]
+		from, to := series.Times[seg.StartIndex], series.Times[seg.EndIndex]
+		pos := telemetryPosition{Position: i + 1, Heading: seg.AvgAngle}
+		for j, t := range times {
+			if t >= from && t <= to {
+				pos.Records = append(pos.Records, j)
+			}
+		}
+		if len(pos.Records) > 0 {
+			positions = append(positions, pos)
+		}
+	}
+	return positions
+}
@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"compass_analyzer/circular"
+	"compass_analyzer/models"
+)
+
+// Параметры оценки температурного дрейфа курса (допуск и изменение
+// температуры, при котором дрейф оценивается, задает профиль процедуры)
+const (
+	driftMinSamples = 5 // Минимум записей в позициях для оценки дрейфа
+	// driftSignificance - во сколько стандартных ошибок наклон должен превысить
+	// допуск: при нескольких записях в позиции, разрешении курса 1° и изменении
+	// температуры на 1 °C шум курса сам дает наклон порядка 0,5 °/°C
+	driftSignificance = 2.0
+)
+
+// ThermalDrift - зависимость курса от температуры внутри стабильных позиций
+type ThermalDrift struct {
+	Sensor      string  `json:"sensor"`      // Датчик температуры («HDC1080» или «DPS310»)
+	Samples     int     `json:"samples"`     // Записей, использованных для оценки
+	MinTemp     float64 `json:"minTemp"`     // Наименьшая температура за процедуру, °C
+	MaxTemp     float64 `json:"maxTemp"`     // Наибольшая температура за процедуру, °C
+	Span        float64 `json:"span"`        // Наибольшее изменение температуры внутри одной позиции, °C
+	Coefficient float64 `json:"coefficient"` // Дрейф курса, °/°C (наклон регрессии)
+	StdErr      float64 `json:"stdErr"`      // Стандартная ошибка наклона от шума курса, °/°C
+	Correlation float64 `json:"correlation"` // Коэффициент корреляции курса и температуры
+	Estimated   bool    `json:"estimated"`   // Температура менялась достаточно для оценки дрейфа
+}
+
+// driftSensors - датчики температуры в порядке предпочтения
+var driftSensors = []struct {
+	name  string
+	value func(r models.TelemetryRecord) float64
+}{
+	{"HDC1080", func(r models.TelemetryRecord) float64 { return r.TemperatureHDC }},
+	{"DPS310", func(r models.TelemetryRecord) float64 { return r.TemperatureDPS }},
+}
+
+// measureThermalDrift оценивает дрейф курса от температуры внутри позиций
+// цикла (см. telemetryPositions). Компас в позиции неподвижен, поэтому
+// изменение курса внутри позиции - дрейф. Курс и температура в каждой
+// позиции отсчитываются от своих средних (курс - по окружности), и по всем
+// позициям вместе строится регрессия отклонения курса на отклонение
+// температуры без свободного члена: наклон - дрейф в °/°C. Так разные
+// курсы позиций не влияют на оценку. По разбросу курса вокруг регрессии
+// считается стандартная ошибка наклона. Берется первый установленный датчик
+// температуры (HDC1080, затем DPS310): столбец, равный нулю во всех
+// строках, считается отсутствующим. Дрейф оценивается, только если
+// температура внутри какой-либо позиции изменилась не меньше чем на minTempSpan °C.
+func measureThermalDrift(records []models.TelemetryRecord, positions []telemetryPosition, minTempSpan float64) *ThermalDrift {
+	for _, sensor := range driftSensors {
+		present := false
+		for _, r := range records {
+			if sensor.value(r) != 0 {
+				present = true
+				break
+			}
+		}
+		if !present {
+			continue
+		}
+
+		drift := &ThermalDrift{Sensor: sensor.name, MinTemp: math.Inf(1), MaxTemp: math.Inf(-1)}
+		for _, r := range records {
+			drift.MinTemp = math.Min(drift.MinTemp, sensor.value(r))
+			drift.MaxTemp = math.Max(drift.MaxTemp, sensor.value(r))
+		}
+
+		var sumHT, sumTT, sumHH float64
+		for _, pos := range positions {
+			headings := make([]float64, len(pos.Records))
+			temps := make([]float64, len(pos.Records))
+			for i, idx := range pos.Records {
+				headings[i] = records[idx].AzimuthRaw
+				temps[i] = sensor.value(records[idx])
+			}
+			heading := circular.Mean(headings)
+			temp, _ := meanStdDev(temps)
+			low, high := temps[0], temps[0]
+			for i := range temps {
+				dh := circular.Diff(heading, headings[i])
+				dt := temps[i] - temp
+				sumHT += dh * dt
+				sumTT += dt * dt
+				sumHH += dh * dh
+				low, high = math.Min(low, temps[i]), math.Max(high, temps[i])
+			}
+			drift.Span = math.Max(drift.Span, high-low)
+			drift.Samples += len(temps)
+		}
+
+		drift.Estimated = drift.Samples >= driftMinSamples && drift.Span >= minTempSpan && sumTT > 0
+		if drift.Estimated {
+			drift.Coefficient = sumHT / sumTT
+			if sumHH > 0 {
+				drift.Correlation = sumHT / math.Sqrt(sumTT*sumHH)
+			}
+			// Степени свободы: средние позиций и наклон
+			if dof := drift.Samples - len(positions) - 1; dof > 0 {
+				residual := math.Max(sumHH-drift.Coefficient*sumHT, 0) / float64(dof)
+				drift.StdErr = math.Sqrt(residual / sumTT)
+			}
+		}
+		return drift
+	}
+	return nil
+}
+
+// validateThermalDrift проверяет, что курс компаса в неподвижных позициях
+// не уходит вместе с температурой больше ProcedureProfile.MaxThermalDrift
+// °/°C. Процедура калибровки длится минуты, и прибор с температурной
+// нестабильностью иначе дает необъяснимый брак по поворотам. Дрейф
+// считается превышением, только если наклон больше допуска на
+// driftSignificance стандартных ошибок, - иначе его не отличить от шума
+// курса. Если допуск в профиле не задан, датчиков температуры нет или
+// температура внутри позиций почти не менялась (меньше
+// ProcedureProfile.MinDriftTempSpan), проверка пропускается. Если в профиле
+// задан DriftAdvisory, превышение выводится как предупреждение и на вердикт
+// не влияет.
+//
+// Параметры:
+//   - drift: оценка дрейфа (см. measureThermalDrift), может быть nil
+//   - profile: профиль процедуры калибровки (допуск дрейфа)
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - []models.Check: результат проверки (пусто, если проверка пропущена)
+func validateThermalDrift(drift *ThermalDrift, profile models.ProcedureProfile, logFile *os.File) []models.Check {
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== ПРОВЕРКА ТЕМПЕРАТУРНОГО ДРЕЙФА КУРСА ===\n")
+	}
+	if profile.MaxThermalDrift <= 0 {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "ℹ Допуск дрейфа в профиле «%s» не задан - проверка пропущена\n", profile.Name)
+		}
+		return nil
+	}
+	if drift == nil {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "ℹ Датчики температуры не установлены - проверка пропущена\n")
+		}
+		return nil
+	}
+	if logFile != nil {
+		fmt.Fprintf(logFile, "Датчик %s: температура %.1f…%.1f °C, изменение внутри позиции до %.2f °C, записей %d\n",
+			drift.Sensor, drift.MinTemp, drift.MaxTemp, drift.Span, drift.Samples)
+	}
+	if !drift.Estimated {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "ℹ Температура внутри позиций менялась меньше чем на %.1f °C - дрейф не оценивается\n", profile.DriftTempSpan())
+		}
+		return nil
+	}
+
+	limit := profile.MaxThermalDrift
+	check := models.Check{
+		Name:     models.CheckThermalDrift,
+		Passed:   math.Abs(drift.Coefficient)-driftSignificance*drift.StdErr <= limit,
+		Advisory: profile.DriftAdvisory,
+		Measured: math.Abs(drift.Coefficient),
+		Limit:    limit,
+		Unit:     "°/°C",
+	}
+	if check.Passed {
+		check.Message = fmt.Sprintf("Дрейф курса %+.3f ± %.3f °/°C (корреляция %.2f, %s) не превышает %.2f °/°C",
+			drift.Coefficient, drift.StdErr, drift.Correlation, drift.Sensor, limit)
+	} else {
+		check.Reason = models.ReasonThermalDrift
+		check.Message = check.Reason.Format(drift.Coefficient, limit, drift.Sensor,
+			drift.Correlation, drift.Coefficient*(drift.MaxTemp-drift.MinTemp), drift.MaxTemp-drift.MinTemp)
+	}
+	logCheck(logFile, check)
+	return []models.Check{check}
+}
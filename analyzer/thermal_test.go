@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"compass_analyzer/models"
+)
+
+// driftRecords возвращает записи четырех позиций цикла (курсы 0°, 90°, 180°,
+// 270°) по perPosition записей: температура внутри каждой позиции равномерно
+// растет на span °C, курс уходит на slope °/°C, к нему добавляется шум
+// со СКО noise, и курс округляется до 1°, как в «Азимут RAW»
+func driftRecords(perPosition int, span, slope, noise float64, rng *rand.Rand) ([]models.TelemetryRecord, []telemetryPosition) {
+	var records []models.TelemetryRecord
+	var positions []telemetryPosition
+	for p, heading := range []float64{0, 90, 180, 270} {
+		pos := telemetryPosition{Position: p + 1, Heading: heading}
+		for i := 0; i < perPosition; i++ {
+			dt := span * (float64(i)/float64(perPosition-1) - 0.5)
+			azimuth := math.Round(heading + slope*dt + noise*rng.NormFloat64())
+			pos.Records = append(pos.Records, len(records))
+			records = append(records, models.TelemetryRecord{
+				Line:           len(records) + 2,
+				AzimuthRaw:     math.Mod(azimuth+360, 360),
+				TemperatureHDC: 25 + float64(p)*span + dt,
+			})
+		}
+		positions = append(positions, pos)
+	}
+	return records, positions
+}
+
+func TestThermalDrift(t *testing.T) {
+	profile, err := models.FindProfile("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	blocking := profile
+	blocking.DriftAdvisory = false
+
+	for _, tt := range []struct {
+		name    string
+		span    float64
+		slope   float64
+		profile models.ProcedureProfile
+		passed  bool
+		failed  bool // Проверка влияет на вердикт
+	}{
+		{"дрейф 2 °/°C, брак", 3, 2, blocking, false, true},
+		{"дрейф 2 °/°C, предупреждение", 3, 2, profile, false, false},
+		{"дрейф 0,2 °/°C", 3, 0.2, blocking, true, false},
+		{"дрейф -2 °/°C", 3, -2, blocking, false, true},
+	} {
+		records, positions := driftRecords(8, tt.span, tt.slope, 0.5, rand.New(rand.NewSource(1)))
+		drift := measureThermalDrift(records, positions, tt.profile.DriftTempSpan())
+		if drift == nil || !drift.Estimated || drift.Sensor != "HDC1080" {
+			t.Fatalf("%s: дрейф не оценен: %+v", tt.name, drift)
+		}
+		if math.Abs(drift.Coefficient-tt.slope) > 3*drift.StdErr+0.1 {
+			t.Errorf("%s: дрейф %.3f ± %.3f °/°C, ожидалось %.3f", tt.name, drift.Coefficient, drift.StdErr, tt.slope)
+		}
+		checks := validateThermalDrift(drift, tt.profile, nil)
+		if len(checks) != 1 {
+			t.Fatalf("%s: проверок %d, ожидалась 1", tt.name, len(checks))
+		}
+		c := checks[0]
+		if c.Passed != tt.passed || c.Failed() != tt.failed {
+			t.Errorf("%s: Passed = %v, Failed = %v, ожидалось %v, %v (%s)", tt.name, c.Passed, c.Failed(), tt.passed, tt.failed, c.Message)
+		}
+		if !c.Passed && c.Reason != models.ReasonThermalDrift {
+			t.Errorf("%s: причина %s, ожидалась %s", tt.name, c.Reason, models.ReasonThermalDrift)
+		}
+	}
+}
+
+// TestThermalDriftNoise проверяет, что шум курса без дрейфа (8 записей
+// в позиции, разрешение 1°, температура меняется на 1 °C) не отбраковывает
+// компас, хотя наклон регрессии по шуму бывает больше допуска
+func TestThermalDriftNoise(t *testing.T) {
+	profile, err := models.FindProfile("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	profile.DriftAdvisory = false
+
+	rng := rand.New(rand.NewSource(1))
+	overLimit := 0
+	for run := 0; run < 200; run++ {
+		records, positions := driftRecords(8, 1, 0, 0.7, rng)
+		drift := measureThermalDrift(records, positions, profile.DriftTempSpan())
+		if drift == nil || !drift.Estimated {
+			t.Fatalf("прогон %d: дрейф не оценен: %+v", run, drift)
+		}
+		if math.Abs(drift.Coefficient) > profile.MaxThermalDrift {
+			overLimit++
+		}
+		for _, c := range validateThermalDrift(drift, profile, nil) {
+			if c.Failed() {
+				t.Errorf("прогон %d: шум отбраковал компас: %s", run, c.Message)
+			}
+		}
+	}
+	if overLimit == 0 {
+		t.Errorf("наклон по шуму ни разу не превысил %.2f °/°C - тест не проверяет учет шума", profile.MaxThermalDrift)
+	}
+}
+
+func TestThermalDriftSkipped(t *testing.T) {
+	profile, err := models.FindProfile("")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	unlimited := profile
+	unlimited.MaxThermalDrift = 0
+
+	records, positions := driftRecords(8, 0.5, 2, 0, rand.New(rand.NewSource(1)))
+	if drift := measureThermalDrift(records, positions, profile.DriftTempSpan()); drift == nil || drift.Estimated {
+		t.Errorf("температура менялась на 0,5 °C, дрейф не должен оцениваться: %+v", drift)
+	}
+	records, positions = driftRecords(8, 3, 2, 0, rand.New(rand.NewSource(1)))
+	drift := measureThermalDrift(records, positions, unlimited.DriftTempSpan())
+	if checks := validateThermalDrift(drift, unlimited, nil); len(checks) != 0 {
+		t.Errorf("допуск не задан, проверка не должна выполняться: %+v", checks)
+	}
+}
@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"compass_analyzer/models"
+)
+
+// TiltStats - наклон стенда в одной позиции (стабильном сегменте) цикла поворотов
+type TiltStats struct {
+	Position    int     `json:"position"`    // Номер позиции в цепочке поворотов (с 1)
+	Heading     float64 `json:"heading"`     // Курс позиции, градусы
+	Samples     int     `json:"samples"`     // Записей телеметрии в позиции
+	Pitch       float64 `json:"pitch"`       // Средний дифферент, градусы
+	Roll        float64 `json:"roll"`        // Средний крен, градусы
+	PitchStdDev float64 `json:"pitchStdDev"` // СКО дифферента, градусы
+	RollStdDev  float64 `json:"rollStdDev"`  // СКО крена, градусы
+	Tilt        float64 `json:"tilt"`        // Наклон от горизонтали по среднему дифференту и крену, градусы
+}
+
+// tiltAngle возвращает угол наклона плоскости стенда от горизонтали
+// по дифференту и крену, градусы
+func tiltAngle(pitch, roll float64) float64 {
+	p, r := pitch*math.Pi/180, roll*math.Pi/180
+	return math.Acos(math.Cos(p)*math.Cos(r)) * 180 / math.Pi
+}
+
+// meanStdDev возвращает среднее и СКО значений
+func meanStdDev(values []float64) (mean, stdDev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		stdDev += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(stdDev / float64(len(values)))
+}
+
+// measureTilt считает дифферент и крен в каждой позиции цикла (см. telemetryPositions).
+// Используются столбцы «Дифферент RAW» и «Крен RAW»: компенсированные углы
+// переворачиваются при смене кода поворота.
+func measureTilt(records []models.TelemetryRecord, positions []telemetryPosition) []TiltStats {
+	var stats []TiltStats
+	for _, pos := range positions {
+		pitch := make([]float64, len(pos.Records))
+		roll := make([]float64, len(pos.Records))
+		for i, idx := range pos.Records {
+			pitch[i], roll[i] = records[idx].PitchRaw, records[idx].RollRaw
+		}
+		s := TiltStats{Position: pos.Position, Heading: pos.Heading, Samples: len(pos.Records)}
+		s.Pitch, s.PitchStdDev = meanStdDev(pitch)
+		s.Roll, s.RollStdDev = meanStdDev(roll)
+		s.Tilt = tiltAngle(s.Pitch, s.Roll)
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// tiltSpread возвращает позиции с наименьшим и наибольшим значением угла
+// в порядке цепочки поворотов
+func tiltSpread(stats []TiltStats, value func(TiltStats) float64) (from, to TiltStats) {
+	low, high := stats[0], stats[0]
+	for _, s := range stats[1:] {
+		if value(s) < value(low) {
+			low = s
+		}
+		if value(s) > value(high) {
+			high = s
+		}
+	}
+	if low.Position > high.Position {
+		return high, low
+	}
+	return low, high
+}
+
+// validateTilt проверяет наклон стенда во время процедуры калибровки:
+//   - tilt_level: наклон в каждой позиции (по среднему дифференту и крену)
+//     не больше profile.MaxTilt - стенд выставлен по уровню;
+//   - tilt_change: средний дифферент и средний крен между позициями меняются
+//     не больше profile.MaxTiltChange - компас не качается на стенде
+//     и не сдвигается при поворотах.
+//
+// Компас на наклонном стенде может пройти проверки курса, но калибровка
+// будет неверной. Если в профиле задан TiltAdvisory, нарушения выводятся
+// как предупреждения и не влияют на вердикт.
+//
+// Параметры:
+//   - stats: наклон в каждой позиции (см. measureTilt)
+//   - profile: профиль процедуры с ограничениями наклона
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - []models.Check: результаты проверок (только для заданных ограничений)
+func validateTilt(stats []TiltStats, profile models.ProcedureProfile, logFile *os.File) []models.Check {
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== ПРОВЕРКА НАКЛОНА СТЕНДА ===\n")
+		for _, s := range stats {
+			fmt.Fprintf(logFile, "Позиция %d (%.2f°): дифферент %+.2f° (СКО %.2f°), крен %+.2f° (СКО %.2f°), наклон %.2f°, записей %d\n",
+				s.Position, s.Heading, s.Pitch, s.PitchStdDev, s.Roll, s.RollStdDev, s.Tilt, s.Samples)
+		}
+	}
+	if !profile.HasTiltLimits() {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "ℹ Ограничения наклона в профиле «%s» не заданы - проверка пропущена\n", profile.Name)
+		}
+		return nil
+	}
+	if len(stats) == 0 {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "⚠ Нет позиций с записями телеметрии - проверка наклона пропущена\n")
+		}
+		return nil
+	}
+
+	var checks []models.Check
+
+	// Проверка 1: наклон в каждой позиции
+	if profile.MaxTilt > 0 {
+		worst := stats[0]
+		for _, s := range stats[1:] {
+			if s.Tilt > worst.Tilt {
+				worst = s
+			}
+		}
+		check := models.Check{
+			Name:     models.CheckTiltLevel,
+			Passed:   worst.Tilt <= profile.MaxTilt,
+			Measured: worst.Tilt,
+			Limit:    profile.MaxTilt,
+			Unit:     "°",
+			Advisory: profile.TiltAdvisory,
+		}
+		if check.Passed {
+			check.Message = fmt.Sprintf("Наклон стенда во всех позициях не больше %.2f° (наибольший %.2f° в позиции %d)",
+				profile.MaxTilt, worst.Tilt, worst.Position)
+		} else {
+			check.Reason = models.ReasonTiltLevel
+			check.Message = check.Reason.Format(worst.Position, worst.Heading, worst.Tilt, profile.MaxTilt, worst.Pitch, worst.Roll)
+		}
+		checks = append(checks, check)
+	}
+
+	// Проверка 2: изменение дифферента и крена между позициями
+	if profile.MaxTiltChange > 0 {
+		check := models.Check{
+			Name:     models.CheckTiltChange,
+			Limit:    profile.MaxTiltChange,
+			Unit:     "°",
+			Advisory: profile.TiltAdvisory,
+		}
+		axis, value := "Дифферент", func(s TiltStats) float64 { return s.Pitch }
+		from, to := tiltSpread(stats, value)
+		roll := func(s TiltStats) float64 { return s.Roll }
+		if rollFrom, rollTo := tiltSpread(stats, roll); math.Abs(roll(rollTo)-roll(rollFrom)) > math.Abs(value(to)-value(from)) {
+			axis, value, from, to = "Крен", roll, rollFrom, rollTo
+		}
+		check.Measured = math.Abs(value(to) - value(from))
+		check.Passed = check.Measured <= profile.MaxTiltChange
+		if check.Passed {
+			check.Message = fmt.Sprintf("Дифферент и крен между позициями меняются не больше %.2f° (наибольшее изменение %.2f°)",
+				profile.MaxTiltChange, check.Measured)
+		} else {
+			check.Reason = models.ReasonTiltChange
+			check.Message = check.Reason.Format(axis, check.Measured, profile.MaxTiltChange,
+				from.Position, to.Position, value(from), value(to))
+		}
+		checks = append(checks, check)
+	}
+
+	for _, c := range checks {
+		logCheck(logFile, c)
+	}
+	return checks
+}
@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"compass_analyzer/models"
+)
+
+// sampleTime возвращает время записи для отчетов: исходную строку времени
+// из файла данных, а если ее нет - время в формате ЧЧ:ММ:СС
+func sampleTime(sample models.CompassData) string {
+	if sample.TimeString != "" {
+		return sample.TimeString
+	}
+	return sample.Time.Format("15:04:05")
+}
+
+// hasTimestamps проверяет, что у всех записей есть метки времени
+func hasTimestamps(data []models.CompassData) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, d := range data {
+		if d.Time.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// elapsed возвращает время между записями from и to, секунды
+func elapsed(data []models.CompassData, from, to int) float64 {
+	return data[to].Time.Sub(data[from].Time).Seconds()
+}
+
+// validateTiming проверяет временные ограничения профиля для выбранной
+// цепочки поворотов:
+//   - время неподвижности на каждом курсе (стабильный сегмент до первого поворота
+//     и после каждого поворота) не меньше profile.MinDwell;
+//   - длительность каждого поворота (от конца сегмента до начала следующего)
+//     не больше profile.MaxTurnDuration;
+//   - длительность всего цикла (от начала первого поворота до конца последнего)
+//     не больше profile.MaxTotalDuration.
+//
+// Нарушения описываются временем записей из файла данных, а не индексами.
+// Если ограничения в профиле не заданы или у записей нет меток времени,
+// проверка пропускается.
+//
+// Параметры:
+//   - data: записи компаса с метками времени
+//   - segments: стабильные сегменты, между которыми найдены повороты
+//   - turns: выбранная последовательность поворотов
+//   - profile: профиль процедуры с ограничениями времени
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - []models.Check: результаты проверок (только для заданных ограничений)
+func validateTiming(data []models.CompassData, segments []AngleSegment, turns []models.Turn, profile models.ProcedureProfile, logFile *os.File) []models.Check {
+	var checks []models.Check
+
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n=== ПРОВЕРКА ВРЕМЕНИ ПРОЦЕДУРЫ ===\n")
+	}
+	if !profile.HasTimingLimits() {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "ℹ Ограничения времени в профиле «%s» не заданы - проверка пропущена\n", profile.Name)
+		}
+		return nil
+	}
+	if !hasTimestamps(data) {
+		if logFile != nil {
+			fmt.Fprintf(logFile, "⚠ В данных нет меток времени - проверка времени пропущена\n")
+		}
+		return nil
+	}
+	if len(turns) == 0 {
+		return nil
+	}
+
+	// violation добавляет описание нарушения к проверке
+	violation := func(c *models.Check, reason models.ReasonCode, msg string) {
+		if c.Passed {
+			c.Passed = false
+			c.Reason = reason
+			c.Message = msg
+		} else {
+			c.Message += "; " + msg
+		}
+	}
+
+	// Проверка 1: время неподвижности на каждом курсе цепочки
+	if profile.MinDwell > 0 {
+		headings := []int{turns[0].FromSegment}
+		for _, turn := range turns {
+			headings = append(headings, turn.ToSegment)
+		}
+		dwellCheck := models.Check{
+			Name:     models.CheckDwell,
+			Passed:   true,
+			Measured: -1,
+			Limit:    profile.MinDwell,
+			Unit:     "с",
+			Message:  fmt.Sprintf("Неподвижность на каждом курсе не меньше %.0f с", profile.MinDwell),
+		}
+		for i, segIdx := range headings {
+			if segIdx < 0 || segIdx >= len(segments) {
+				continue
+			}
+			seg := segments[segIdx]
+			dwell := elapsed(data, seg.StartIndex, seg.EndIndex)
+			if logFile != nil {
+				fmt.Fprintf(logFile, "Курс %d (%.2f°): %s – %s, неподвижность %.0f с\n",
+					i+1, seg.AvgAngle, sampleTime(data[seg.StartIndex]), sampleTime(data[seg.EndIndex]), dwell)
+			}
+			if dwellCheck.Measured < 0 || dwell < dwellCheck.Measured {
+				dwellCheck.Measured = dwell
+			}
+			if dwell < profile.MinDwell {
+				violation(&dwellCheck, models.ReasonShortDwell, models.ReasonShortDwell.Format(
+					i+1, seg.AvgAngle, sampleTime(data[seg.StartIndex]), sampleTime(data[seg.EndIndex]), dwell, profile.MinDwell))
+			}
+		}
+		if dwellCheck.Measured < 0 {
+			dwellCheck.Measured = 0
+		}
+		checks = append(checks, dwellCheck)
+		logCheck(logFile, dwellCheck)
+	}
+
+	// Проверка 2: длительность каждого поворота
+	if profile.MaxTurnDuration > 0 {
+		turnCheck := models.Check{
+			Name:    models.CheckTurnDuration,
+			Passed:  true,
+			Limit:   profile.MaxTurnDuration,
+			Unit:    "с",
+			Message: fmt.Sprintf("Каждый поворот выполнен не дольше %.0f с", profile.MaxTurnDuration),
+		}
+		for i, turn := range turns {
+			duration := elapsed(data, turn.StartIndex, turn.EndIndex)
+			if logFile != nil {
+				fmt.Fprintf(logFile, "Поворот %d: %s – %s, длительность %.0f с\n",
+					i+1, sampleTime(data[turn.StartIndex]), sampleTime(data[turn.EndIndex]), duration)
+			}
+			if duration > turnCheck.Measured {
+				turnCheck.Measured = duration
+			}
+			if duration > profile.MaxTurnDuration {
+				violation(&turnCheck, models.ReasonSlowTurn, models.ReasonSlowTurn.Format(
+					i+1, sampleTime(data[turn.StartIndex]), sampleTime(data[turn.EndIndex]), duration, profile.MaxTurnDuration))
+			}
+		}
+		checks = append(checks, turnCheck)
+		logCheck(logFile, turnCheck)
+	}
+
+	// Проверка 3: общая длительность цикла
+	if profile.MaxTotalDuration > 0 {
+		first, last := turns[0].StartIndex, turns[len(turns)-1].EndIndex
+		total := elapsed(data, first, last)
+		if logFile != nil {
+			fmt.Fprintf(logFile, "Цикл поворотов: %s – %s, длительность %s\n",
+				sampleTime(data[first]), sampleTime(data[last]), time.Duration(total*float64(time.Second)))
+		}
+		totalCheck := models.Check{
+			Name:     models.CheckTotalDuration,
+			Passed:   total <= profile.MaxTotalDuration,
+			Measured: total,
+			Limit:    profile.MaxTotalDuration,
+			Unit:     "с",
+			Message:  fmt.Sprintf("Цикл поворотов выполнен не дольше %.0f с", profile.MaxTotalDuration),
+		}
+		if !totalCheck.Passed {
+			totalCheck.Reason = models.ReasonSlowCycle
+			totalCheck.Message = totalCheck.Reason.Format(
+				sampleTime(data[first]), sampleTime(data[last]), total, profile.MaxTotalDuration)
+		}
+		checks = append(checks, totalCheck)
+		logCheck(logFile, totalCheck)
+	}
+
+	return checks
+}
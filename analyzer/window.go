@@ -0,0 +1,219 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"compass_analyzer/models"
+	"compass_analyzer/parser"
+)
+
+// Источники окна калибровки в порядке предпочтения
+const (
+	WindowSourceFlags   = "flags"   // Флаги «Калибровка, hex»: прибор сообщил о завершении калибровки
+	WindowSourceJournal = "journal" // Журнал блока AB: этап «Calibration finished or not required»
+	WindowSourceMotion  = "motion"  // Движение: после возврата прибора из перевернутого положения
+)
+
+// windowMinRows - минимум записей в окне калибровки
+const windowMinRows = 2
+
+// CalibrationWindow - записи файла после калибровки прибора, на которых
+// оператор выполняет проверочные повороты. Заменяет ручную обрезку файла
+// (SB_CMPS_CALIB_CUTTED.csv).
+type CalibrationWindow struct {
+	Source    string `json:"source"`    // Источник окна (WindowSourceFlags, WindowSourceJournal, WindowSourceMotion)
+	FirstLine int    `json:"firstLine"` // Первая строка файла в окне
+	LastLine  int    `json:"lastLine"`  // Последняя строка файла в окне
+	Start     string `json:"start"`     // Время первой записи окна
+	End       string `json:"end"`       // Время последней записи окна
+	Rows      int    `json:"rows"`      // Записей в окне
+	Total     int    `json:"total"`     // Записей в файле
+	From      int    `json:"-"`         // Индекс первой записи окна
+	To        int    `json:"-"`         // Индекс последней записи окна
+}
+
+// String возвращает окно одной строкой для логов и отчетов
+func (w CalibrationWindow) String() string {
+	return fmt.Sprintf("строки %d–%d (%s – %s), %d из %d записей, %s",
+		w.FirstLine, w.LastLine, w.Start, w.End, w.Rows, w.Total, w.SourceTitle())
+}
+
+// SourceTitle возвращает описание источника окна для оператора
+func (w CalibrationWindow) SourceTitle() string {
+	switch w.Source {
+	case WindowSourceFlags:
+		return "по флагам калибровки прибора"
+	case WindowSourceJournal:
+		return "по журналу блока AB"
+	case WindowSourceMotion:
+		return "по движению (после переворота прибора)"
+	}
+	return w.Source
+}
+
+// Records возвращает записи телеметрии окна
+func (w CalibrationWindow) Records(records []models.TelemetryRecord) []models.TelemetryRecord {
+	return records[w.From : w.To+1]
+}
+
+// newWindow создает окно из записей records[from..to]
+func newWindow(records []models.TelemetryRecord, source string, from, to int) *CalibrationWindow {
+	if from <= 0 || to-from+1 < windowMinRows {
+		return nil
+	}
+	return &CalibrationWindow{
+		Source:    source,
+		FirstLine: records[from].Line,
+		LastLine:  records[to].Line,
+		Start:     records[from].TimeString,
+		End:       records[to].TimeString,
+		Rows:      to - from + 1,
+		Total:     len(records),
+		From:      from,
+		To:        to,
+	}
+}
+
+// FindCalibrationWindow находит записи, на которых выполняются проверочные
+// повороты, - то, что операторы раньше вырезали из файла вручную. Источники
+// перебираются по порядку, пока один из них не даст окно:
+//   - флаги калибровки (см. models.Flags): последний непрерывный участок
+//     состояния «калибровка завершена»;
+//   - журнал блока AB: записи после последнего сообщения
+//     «Calibration finished or not required» и до остановки записи;
+//   - движение: записи после того, как прибор в последний раз вернули
+//     из перевернутого положения (шаг калибровки «переворот прибора» -
+//     ось Z акселерометра направлена вверх).
+//
+// Первая запись с состоянием «калибровка завершена» делается в момент
+// расчета коэффициентов, пока прибор еще в положении последнего шага, поэтому
+// окно начинается со следующей записи - так же обрезаны файлы вручную.
+// Окно, начинающееся с первой записи файла, не ищется: в таком файле нет
+// калибровки, и он анализируется целиком.
+//
+// Параметры:
+//   - records: записи телеметрии, упорядоченные по времени
+//   - journal: сообщения журнала блока AB (может быть nil)
+//
+// Возвращает:
+//   - *CalibrationWindow: окно калибровки (nil, если ни один источник его не дал)
+func FindCalibrationWindow(records []models.TelemetryRecord, journal []parser.JournalEntry) *CalibrationWindow {
+	if window := flagsWindow(records, models.Flags()); window != nil {
+		return window
+	}
+	if window := journalWindow(records, journal); window != nil {
+		return window
+	}
+	return motionWindow(records)
+}
+
+// CalibrationRecords возвращает записи, по которым ищутся повороты: записи
+// окна калибровки (см. FindCalibrationWindow) или весь файл, если окно
+// не найдено или его поиск отключен (params.CalibrationWindow).
+//
+// Параметры:
+//   - records: записи телеметрии, упорядоченные по времени
+//   - journal: сообщения журнала блока AB (может быть nil)
+//   - params: параметры анализа
+//
+// Возвращает:
+//   - []models.TelemetryRecord: записи для поиска поворотов
+//   - *CalibrationWindow: выбранное окно (nil - анализируется весь файл)
+func CalibrationRecords(records []models.TelemetryRecord, journal []parser.JournalEntry, params models.AnalysisParams) ([]models.TelemetryRecord, *CalibrationWindow) {
+	if params.CalibrationWindow == models.CalibrationWindowOff {
+		return records, nil
+	}
+	window := FindCalibrationWindow(records, journal)
+	if window == nil {
+		return records, nil
+	}
+	return window.Records(records), window
+}
+
+// flagsWindow - окно по последнему участку состояния «калибровка завершена»
+func flagsWindow(records []models.TelemetryRecord, dict models.FlagDictionary) *CalibrationWindow {
+	from, to := -1, -1
+	for i, r := range records {
+		if dict.Decode(r.Status, r.Calibration).State() != models.DeviceStateDone {
+			continue
+		}
+		if to != i-1 {
+			from = i
+		}
+		to = i
+	}
+	if from <= 0 {
+		return nil
+	}
+	return newWindow(records, WindowSourceFlags, from+1, to)
+}
+
+// journalWindow - окно между завершением калибровки и остановкой записи по журналу
+func journalWindow(records []models.TelemetryRecord, journal []parser.JournalEntry) *CalibrationWindow {
+	finished := -1
+	for i, e := range journal {
+		if strings.Contains(e.Message, parser.JournalCalibrationFinished) {
+			finished = i
+		}
+	}
+	if finished < 0 {
+		return nil
+	}
+	start := journal[finished].Time
+	stop, stopped := start, false
+	for _, e := range journal[finished+1:] {
+		if strings.Contains(e.Message, parser.JournalRecordingStop) {
+			stop, stopped = e.Time, true
+			break
+		}
+	}
+
+	from, to := -1, -1
+	for i, r := range records {
+		if !r.Time.After(start) || (stopped && r.Time.After(stop)) {
+			continue
+		}
+		if from < 0 {
+			from = i
+		}
+		to = i
+	}
+	if from < 0 {
+		return nil
+	}
+	return newWindow(records, WindowSourceJournal, from, to)
+}
+
+// motionWindow - окно после последней записи, в которой прибор перевернут
+// (ось Z акселерометра отрицательна). Вращение по кругу для этого не годится:
+// при записи раз в 20 с оборот попадает в файл лишь несколькими точками.
+func motionWindow(records []models.TelemetryRecord) *CalibrationWindow {
+	flipped := -1
+	for i, r := range records {
+		if parser.DecodeSensorVectors(r).Axs.Z < 0 {
+			flipped = i
+		}
+	}
+	if flipped < 0 {
+		return nil
+	}
+	return newWindow(records, WindowSourceMotion, flipped+1, len(records)-1)
+}
+
+// logCalibrationWindow записывает выбранное окно в лог
+func logCalibrationWindow(logFile *os.File, window *CalibrationWindow, params models.AnalysisParams, total int) {
+	if logFile == nil {
+		return
+	}
+	fmt.Fprintf(logFile, "\n=== ОКНО КАЛИБРОВКИ ===\n")
+	switch {
+	case params.CalibrationWindow == models.CalibrationWindowOff:
+		fmt.Fprintf(logFile, "ℹ Поиск окна отключен (calibration_window: off) - анализируется весь файл, %d записей\n", total)
+	case window == nil:
+		fmt.Fprintf(logFile, "ℹ Окно калибровки не найдено (нет флагов калибровки, журнала и переворота прибора) - анализируется весь файл, %d записей\n", total)
+	default:
+		fmt.Fprintf(logFile, "✓ Повороты ищутся в окне: %s\n", window)
+	}
+}
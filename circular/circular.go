@@ -0,0 +1,173 @@
+// Package circular реализует статистику для углов (круговую статистику).
+//
+// Обычные среднее, медиана и СКО неверны для азимутов: среднее углов 350° и 10°
+// равно 180°, хотя оба угла смотрят почти на север. Функции пакета работают
+// с углами в градусах и корректно обрабатывают переход через 0°/360°.
+package circular
+
+import (
+	"math"
+	"sort"
+)
+
+// Normalize приводит угол к диапазону [0, 360)
+func Normalize(angle float64) float64 {
+	angle = math.Mod(angle, 360)
+	if angle < 0 {
+		angle += 360
+	}
+	return angle
+}
+
+// Diff возвращает знаковую разницу углов to - from в диапазоне [-180, 180].
+// Положительное значение означает поворот по часовой стрелке.
+//
+// Пример:
+//
+//	Diff(350, 10) // вернет 20
+//	Diff(10, 350) // вернет -20
+func Diff(from, to float64) float64 {
+	diff := math.Mod(to-from, 360)
+	if diff > 180 {
+		diff -= 360
+	} else if diff < -180 {
+		diff += 360
+	}
+	return diff
+}
+
+// Distance возвращает кратчайшее расстояние между углами по окружности, [0, 180]
+func Distance(a, b float64) float64 {
+	return math.Abs(Diff(a, b))
+}
+
+// resultant возвращает сумму единичных векторов углов
+func resultant(angles []float64) (sumSin, sumCos float64) {
+	for _, angle := range angles {
+		rad := angle * math.Pi / 180
+		sumSin += math.Sin(rad)
+		sumCos += math.Cos(rad)
+	}
+	return sumSin, sumCos
+}
+
+// Mean вычисляет круговое среднее - направление суммы единичных векторов углов.
+// Для пустого набора возвращает 0.
+//
+// Пример:
+//
+//	Mean([]float64{350, 10}) // вернет 0
+func Mean(angles []float64) float64 {
+	if len(angles) == 0 {
+		return 0
+	}
+	sumSin, sumCos := resultant(angles)
+	return Normalize(math.Atan2(sumSin, sumCos) * 180 / math.Pi)
+}
+
+// ResultantLength вычисляет среднюю длину результирующего вектора R, [0, 1]:
+// 1 - все углы совпадают, 0 - углы равномерно распределены по окружности
+func ResultantLength(angles []float64) float64 {
+	if len(angles) == 0 {
+		return 0
+	}
+	sumSin, sumCos := resultant(angles)
+	return math.Min(1, math.Hypot(sumSin, sumCos)/float64(len(angles)))
+}
+
+// Variance вычисляет круговую дисперсию 1 - R, [0, 1]
+func Variance(angles []float64) float64 {
+	if len(angles) == 0 {
+		return 0
+	}
+	return 1 - ResultantLength(angles)
+}
+
+// StdDev вычисляет круговое СКО sqrt(-2·ln R) в градусах.
+// Для малого разброса совпадает с обычным СКО; для углов, почти равномерно
+// распределенных по окружности, значение ограничено 180°.
+func StdDev(angles []float64) float64 {
+	if len(angles) == 0 {
+		return 0
+	}
+	r := ResultantLength(angles)
+	if r <= 0 {
+		return 180
+	}
+	if r >= 1 {
+		return 0
+	}
+	return math.Min(180, math.Sqrt(-2*math.Log(r))*180/math.Pi)
+}
+
+// Median вычисляет круговую медиану - угол из набора, для которого сумма
+// расстояний по окружности до остальных углов минимальна.
+// При равенстве выбирается угол, ближайший к круговому среднему.
+//
+// Пример:
+//
+//	Median([]float64{355, 358, 2, 4, 5}) // вернет 2
+func Median(angles []float64) float64 {
+	if len(angles) == 0 {
+		return 0
+	}
+	mean := Mean(angles)
+	best, bestSum := Normalize(angles[0]), math.Inf(1)
+	for _, candidate := range angles {
+		var sum float64
+		for _, angle := range angles {
+			sum += Distance(candidate, angle)
+		}
+		const eps = 1e-9
+		if sum < bestSum-eps || (math.Abs(sum-bestSum) <= eps && Distance(candidate, mean) < Distance(best, mean)) {
+			best, bestSum = Normalize(candidate), sum
+		}
+	}
+	return best
+}
+
+// MAD вычисляет медианное абсолютное отклонение углов от круговой медианы, градусы
+func MAD(angles []float64) float64 {
+	if len(angles) == 0 {
+		return 0
+	}
+	median := Median(angles)
+	deviations := make([]float64, len(angles))
+	for i, angle := range angles {
+		deviations[i] = Distance(median, angle)
+	}
+	sort.Float64s(deviations)
+	mid := len(deviations) / 2
+	if len(deviations)%2 == 0 {
+		return (deviations[mid-1] + deviations[mid]) / 2
+	}
+	return deviations[mid]
+}
+
+// Stats - сводная круговая статистика набора углов
+type Stats struct {
+	Count     int     `json:"count"`     // Количество углов
+	Mean      float64 `json:"mean"`      // Круговое среднее, градусы
+	Median    float64 `json:"median"`    // Круговая медиана, градусы
+	Resultant float64 `json:"resultant"` // Длина результирующего вектора R, [0, 1]
+	Variance  float64 `json:"variance"`  // Круговая дисперсия 1 - R
+	StdDev    float64 `json:"stdDev"`    // Круговое СКО, градусы
+	MAD       float64 `json:"mad"`       // Медианное абсолютное отклонение, градусы
+}
+
+// Describe вычисляет сводную круговую статистику набора углов
+func Describe(angles []float64) Stats {
+	if len(angles) == 0 {
+		return Stats{}
+	}
+	r := ResultantLength(angles)
+	return Stats{
+		Count:     len(angles),
+		Mean:      Mean(angles),
+		Median:    Median(angles),
+		Resultant: r,
+		Variance:  1 - r,
+		StdDev:    StdDev(angles),
+		MAD:       MAD(angles),
+	}
+}
@@ -0,0 +1,76 @@
+package circular
+
+import (
+	"math"
+	"testing"
+)
+
+const eps = 1e-6
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < eps
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		from, to, want float64
+	}{
+		{350, 10, 20},
+		{10, 350, -20},
+		{0, 90, 90},
+		{270, 0, 90},
+		{90, 270, 180},
+		{720, 10, 10},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.from, tt.to); !near(got, tt.want) {
+			t.Errorf("Diff(%v, %v) = %v, ожидалось %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// Наборы углов, пересекающие 0°/360°: линейные среднее и медиана дают около 180°
+func TestAcrossNorth(t *testing.T) {
+	angles := []float64{355, 358, 2, 4, 5}
+
+	if got := Mean(angles); Distance(got, 0.8) > 0.1 {
+		t.Errorf("Mean = %v, ожидалось около 0.8", got)
+	}
+	if got := Median(angles); !near(got, 2) {
+		t.Errorf("Median = %v, ожидалось 2", got)
+	}
+	// Отклонения от медианы 2°: 7, 4, 0, 2, 3 - медиана 3
+	if got := MAD(angles); !near(got, 3) {
+		t.Errorf("MAD = %v, ожидалось 3", got)
+	}
+	if got := StdDev(angles); got < 3 || got > 5 {
+		t.Errorf("StdDev = %v, ожидалось около 4", got)
+	}
+}
+
+func TestDispersion(t *testing.T) {
+	same := []float64{123, 123, 123}
+	if r := ResultantLength(same); !near(r, 1) {
+		t.Errorf("ResultantLength одинаковых углов = %v, ожидалось 1", r)
+	}
+	if v := Variance(same); !near(v, 0) {
+		t.Errorf("Variance одинаковых углов = %v, ожидалось 0", v)
+	}
+	if s := StdDev(same); !near(s, 0) {
+		t.Errorf("StdDev одинаковых углов = %v, ожидалось 0", s)
+	}
+
+	opposite := []float64{0, 90, 180, 270}
+	if r := ResultantLength(opposite); !near(r, 0) {
+		t.Errorf("ResultantLength равномерных углов = %v, ожидалось 0", r)
+	}
+	if s := StdDev(opposite); s != 180 {
+		t.Errorf("StdDev равномерных углов = %v, ожидалось 180", s)
+	}
+}
+
+func TestDescribeEmpty(t *testing.T) {
+	if got := Describe(nil); got != (Stats{}) {
+		t.Errorf("Describe(nil) = %+v, ожидалась пустая статистика", got)
+	}
+}
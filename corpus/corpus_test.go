@@ -0,0 +1,214 @@
+package corpus
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"compass_analyzer/analyzer"
+	"compass_analyzer/models"
+	"compass_analyzer/parser"
+)
+
+// TestCorpusRegression прогоняет анализ по размеченному набору manifest.json
+// с параметрами по умолчанию и падает, если вердикт какого-либо компаса
+// отличается от принятого в манифесте (матрица ошибок против меток оператора -
+// только сводка). После проверенного изменения алгоритма принятые вердикты
+// обновляются командой: compass_analyzer corpus -accept
+func TestCorpusRegression(t *testing.T) {
+	manifest, err := LoadManifest("manifest.json")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	profile, err := models.FindProfile(manifest.Profile)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// Замечания парсера о файлах без заголовка не относятся к проверке
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	report := Run(manifest, models.DefaultAnalysisParams(), profile, nil)
+	var out strings.Builder
+	report.Print(&out)
+	t.Log("\n" + out.String())
+
+	for _, res := range report.Flips() {
+		t.Errorf("%s: вердикт %s → %s %s (оператор: %s)", res.Entry.Path, res.Entry.Verdict, res.Verdict, res.Reason, res.Entry.ExpectedLabel())
+	}
+	if report.Matrix.Total() != len(manifest.Units) {
+		t.Errorf("в матрице %d компасов, в манифесте %d", report.Matrix.Total(), len(manifest.Units))
+	}
+}
+
+// TestCalibrationWindow проверяет на компасах набора, что окно калибровки
+// по флагам прибора совпадает с окном, которое оператор вырезал вручную
+// (SB_CMPS_CALIB_CUTTED.csv): те же первая и последняя запись и их число.
+// Время сравнивается по отсчетам, а не по строке: при сохранении в Excel
+// из вырезанного файла пропали секунды.
+func TestCalibrationWindow(t *testing.T) {
+	manifest, err := LoadManifest("manifest.json")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	for _, path := range []string{"Брак/1908", "Брак/2070", "Успех/1903", "Успешно/1736", "Успешно/1874"} {
+		dir := manifest.UnitPath(Entry{Path: path})
+		csvPath := filepath.Join(dir, "SB_CMPS.csv")
+		records, err := parser.ReadTelemetry(csvPath)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		cut, err := parser.ReadTelemetry(filepath.Join(dir, "SB_CMPS_CALIB_CUTTED.csv"))
+		if err != nil || len(cut) == 0 {
+			t.Fatalf("%s: вырезанный файл: %v", path, err)
+		}
+
+		window := analyzer.FindCalibrationWindow(records, parser.ReadJournalFor(csvPath))
+		if window == nil || window.Source != analyzer.WindowSourceFlags {
+			t.Errorf("%s: окно по флагам не найдено: %+v", path, window)
+			continue
+		}
+		first, last := cut[0], cut[len(cut)-1]
+		if !records[window.From].Time.Equal(first.Time) || !records[window.To].Time.Equal(last.Time) || window.Rows != len(cut) {
+			t.Errorf("%s: окно %s, вырезано вручную %s – %s, %d записей",
+				path, window, first.TimeString, last.TimeString, len(cut))
+		}
+	}
+}
+
+func TestConfusion(t *testing.T) {
+	var c Confusion
+	for _, tt := range []struct{ label, verdict Label }{
+		{Good, Good}, {Good, Good}, {Good, Bad}, {Bad, Good}, {Bad, Bad}, {Bad, Bad}, {Bad, Bad},
+	} {
+		c.add(tt.label, tt.verdict)
+	}
+	want := Confusion{TruePass: 2, FalseReject: 1, FalseAccept: 1, TrueReject: 3}
+	if c != want {
+		t.Errorf("матрица %+v, ожидалось %+v", c, want)
+	}
+	if got := c.Agreement(); got < 71.4 || got > 71.5 {
+		t.Errorf("Agreement = %v, ожидалось 71.43", got)
+	}
+}
+
+func TestAccept(t *testing.T) {
+	m := &Manifest{Units: []Entry{
+		{Path: "Успешно/1", Label: Good, Verdict: Good},
+		{Path: "Брак/2", Label: Bad, Verdict: Bad, Reason: models.ReasonTooFewTurns},
+		{Path: "Брак/3", Label: Bad, Verdict: Bad, Reason: models.ReasonClockwise, Note: "проверено вручную"},
+		{Path: "Брак/4", Label: Bad, Verdict: Good},
+	}}
+	// Компас 1 стал браком, компас 2 - годным: матрица не изменилась,
+	// но оба изменения - регрессия
+	report := Report{Profile: "4x90-cw", Results: []Result{
+		{Entry: m.Units[0], Verdict: Bad, Reason: models.ReasonSoftIron},
+		{Entry: m.Units[1], Verdict: Good},
+		{Entry: m.Units[2], Verdict: Bad, Reason: models.ReasonSumDeviation},
+		{Entry: m.Units[3], Verdict: Good},
+	}}
+	if flips := report.Flips(); len(flips) != 2 || !report.Regressed() {
+		t.Fatalf("до Accept изменились %d вердикта, ожидалось 2", len(flips))
+	}
+	if changes := report.ReasonChanges(); len(changes) != 1 || changes[0].Entry.Path != "Брак/3" {
+		t.Errorf("изменившиеся причины: %+v", changes)
+	}
+
+	m.Accept(&report)
+	if report.Regressed() {
+		t.Errorf("после Accept остались изменившиеся вердикты: %+v", report.Flips())
+	}
+	if e := m.Units[0]; e.Verdict != Bad || e.Reason != models.ReasonSoftIron || e.Label != Good || e.Expected != "" {
+		t.Errorf("компас 1: %+v", e)
+	}
+	if e := m.Units[1]; e.Verdict != Good || e.Reason != "" {
+		t.Errorf("компас 2: %+v", e)
+	}
+	if e := m.Units[2]; e.Note != "проверено вручную" || e.Reason != models.ReasonSumDeviation {
+		t.Errorf("компас 3: %+v", e)
+	}
+	// Расхождение с оператором остается расхождением
+	if e := m.Units[3]; e.Verdict != Good || e.ExpectedLabel() != Bad {
+		t.Errorf("компас 4: %+v", e)
+	}
+}
+
+func TestValidateExpected(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		entry Entry
+		valid bool
+	}{
+		{"только метка", Entry{Path: "Брак/1", Label: Bad}, true},
+		{"с обоснованием", Entry{Path: "Брак/1", Label: Bad, Expected: Good, Note: "повторная калибровка прошла, в брак отложен по ошибке"}, true},
+		{"без обоснования", Entry{Path: "Брак/1", Label: Bad, Expected: Good}, false},
+		{"сгенерированный комментарий", Entry{Path: "Брак/1", Label: Bad, Expected: Good, Note: knownMismatchNote + "оператор - брак, анализатор - годен"}, false},
+	} {
+		m := &Manifest{Units: []Entry{tt.entry}}
+		if err := m.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v", tt.name, err)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	r, err := ParseRange("3:7:2")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got := r.Values(); len(got) != 3 || got[0] != 3 || got[2] != 7 {
+		t.Errorf("Values = %v, ожидалось [3 5 7]", got)
+	}
+	if r, err := ParseRange("15"); err != nil || len(r.Values()) != 1 {
+		t.Errorf("одно значение: %+v, %v", r, err)
+	}
+	for _, s := range []string{"", "3:7", "7:3:1", "3:7:0", "a:b:c"} {
+		if _, err := ParseRange(s); err == nil {
+			t.Errorf("ParseRange(%q): ожидалась ошибка", s)
+		}
+	}
+}
+
+func TestParetoFront(t *testing.T) {
+	space := Space{TuneStability: {Min: 1, Max: 9, Step: 1}}
+	candidate := func(stability float64, falseAccept, falseReject int) Candidate {
+		return Candidate{
+			Point:  Point{TuneStability: stability},
+			Matrix: Confusion{FalseAccept: falseAccept, TrueReject: 10 - falseAccept, FalseReject: falseReject, TruePass: 10 - falseReject},
+		}
+	}
+	candidates := []Candidate{
+		candidate(1, 5, 1), // на фронте
+		candidate(2, 2, 2), // на фронте
+		candidate(3, 3, 3), // хуже набора 2
+		candidate(4, 0, 6), // на фронте
+		candidate(6, 2, 2), // те же ошибки, что у набора 2, но ближе к текущему порогу 5
+	}
+	front := paretoFront(candidates, Point{TuneStability: 5}, space)
+
+	var got []float64
+	for _, c := range front {
+		got = append(got, c.Point[TuneStability])
+	}
+	want := []float64{4, 6, 1}
+	if len(got) != len(want) {
+		t.Fatalf("фронт %v, ожидалось %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("фронт %v, ожидалось %v", got, want)
+		}
+	}
+
+	result := TuneResult{Front: front}
+	if best, _ := result.Balanced(); best.Point[TuneStability] != 6 {
+		t.Errorf("Balanced: порог %v, ожидалось 6", best.Point[TuneStability])
+	}
+}
@@ -0,0 +1,217 @@
+// Package corpus - регрессионная проверка анализатора на размеченном наборе
+// компасов. Манифест перечисляет папки компасов с меткой оператора (годен/брак,
+// по папкам «Успешно» и «Брак») и принятым вердиктом анализатора; прогон
+// сравнивает текущий вердикт каждого компаса с принятым и строит матрицу
+// ошибок против меток оператора.
+package corpus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"compass_analyzer/models"
+)
+
+// Label - вердикт по компасу: метка оператора или результат анализатора
+type Label string
+
+const (
+	Good Label = "good" // Компас годен
+	Bad  Label = "bad"  // Компас забракован
+)
+
+// String возвращает вердикт на русском для отчетов
+func (l Label) String() string {
+	switch l {
+	case Good:
+		return "годен"
+	case Bad:
+		return "брак"
+	}
+	return string(l)
+}
+
+// labelDirs - папки с размеченными компасами и их метки
+var labelDirs = map[string]Label{
+	"Успешно": Good,
+	"Успех":   Good,
+	"Брак":    Bad,
+}
+
+// LabelForDir возвращает метку по имени папки, в которую оператор
+// разложил компасы («Успешно», «Успех» - годен, «Брак» - брак)
+func LabelForDir(name string) (Label, bool) {
+	label, ok := labelDirs[name]
+	return label, ok
+}
+
+// Entry - компас в манифесте
+type Entry struct {
+	// Path - папка компаса с SB_CMPS.csv или сам CSV файл, относительно Root манифеста
+	Path string `json:"path"`
+	// Label - метка оператора (эталон для матрицы ошибок)
+	Label Label `json:"label"`
+	// Expected - исправленный эталон, если метка оператора ошибочна; задается
+	// только вручную и только с обоснованием в Note
+	Expected Label `json:"expected,omitempty"`
+	// Verdict - принятый вердикт анализатора (записывает Accept); прогон
+	// с другим вердиктом - регрессия, даже если он расходится с Label
+	Verdict Label `json:"verdict,omitempty"`
+	// Reason - принятый код причины отбраковки (только для Verdict = bad)
+	Reason models.ReasonCode `json:"reason,omitempty"`
+	// Note - комментарий; для Expected - почему метка оператора ошибочна
+	Note string `json:"note,omitempty"`
+}
+
+// knownMismatchNote - начало комментария, которым прежняя версия Accept
+// записывала вердикт анализатора в Expected; такой комментарий не обоснование
+const knownMismatchNote = "известное расхождение: "
+
+// ExpectedLabel возвращает эталонный вердикт: метку оператора или исправленный эталон
+func (e Entry) ExpectedLabel() Label {
+	if e.Expected != "" {
+		return e.Expected
+	}
+	return e.Label
+}
+
+// Name возвращает номер компаса (имя папки или CSV файла без расширения)
+func (e Entry) Name() string {
+	name := filepath.Base(e.Path)
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		name = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(name, filepath.Ext(name)), "SB_CMPS"))
+	}
+	return name
+}
+
+// Manifest - размеченный набор компасов
+type Manifest struct {
+	// Root - корень путей компасов относительно файла манифеста
+	Root string `json:"root"`
+	// Profile - профиль процедуры, по которому приняты вердикты
+	Profile string `json:"profile"`
+	// Units - компасы набора
+	Units []Entry `json:"units"`
+
+	// dir - папка файла манифеста
+	dir string
+}
+
+// LoadManifest читает манифест из JSON файла
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения манифеста: %v", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("ошибка разбора манифеста %s: %v", path, err)
+	}
+	m.dir = filepath.Dir(path)
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("ошибка в манифесте %s: %v", path, err)
+	}
+	return &m, nil
+}
+
+// Validate проверяет метки и пути компасов
+func (m *Manifest) Validate() error {
+	if m.Profile != "" {
+		if _, err := models.FindProfile(m.Profile); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool)
+	for _, e := range m.Units {
+		if e.Path == "" {
+			return fmt.Errorf("у компаса не указан путь")
+		}
+		if seen[e.Path] {
+			return fmt.Errorf("компас %s указан дважды", e.Path)
+		}
+		seen[e.Path] = true
+		if e.Label != Good && e.Label != Bad {
+			return fmt.Errorf("компас %s: неизвестная метка «%s» (допустимо: %s, %s)", e.Path, e.Label, Good, Bad)
+		}
+		if e.Expected != "" && e.Expected != Good && e.Expected != Bad {
+			return fmt.Errorf("компас %s: неизвестный ожидаемый вердикт «%s»", e.Path, e.Expected)
+		}
+		if e.Verdict != "" && e.Verdict != Good && e.Verdict != Bad {
+			return fmt.Errorf("компас %s: неизвестный принятый вердикт «%s»", e.Path, e.Verdict)
+		}
+		if e.Expected != "" && (strings.TrimSpace(e.Note) == "" || strings.HasPrefix(e.Note, knownMismatchNote)) {
+			return fmt.Errorf("компас %s: вердикт «%s» вместо метки оператора «%s» указан без обоснования в note", e.Path, e.Expected, e.Label)
+		}
+		if e.Reason != "" && !e.Reason.Known() {
+			return fmt.Errorf("компас %s: неизвестный код причины «%s»", e.Path, e.Reason)
+		}
+	}
+	return nil
+}
+
+// Save записывает манифест в JSON файл; компасы сортируются по пути
+func (m *Manifest) Save(path string) error {
+	sort.Slice(m.Units, func(i, j int) bool { return m.Units[i].Path < m.Units[j].Path })
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения манифеста: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("ошибка сохранения манифеста: %v", err)
+	}
+	m.dir = filepath.Dir(path)
+	return nil
+}
+
+// UnitPath возвращает путь к папке или файлу компаса
+func (m *Manifest) UnitPath(e Entry) string {
+	return filepath.Join(m.dir, m.Root, e.Path)
+}
+
+// ScanLabeled собирает манифест по папкам «Успешно», «Успех» и «Брак» в root:
+// каждая подпапка (или отдельный SB_CMPS.csv файл) получает метку своей папки.
+// Принятые вердикты не заполняются - их записывает Accept после прогона.
+//
+// Параметры:
+//   - root: папка, содержащая размеченные папки
+//   - manifestPath: будущий путь файла манифеста (для относительного Root)
+//   - profile: имя профиля процедуры
+//
+// Возвращает:
+//   - *Manifest: манифест с найденными компасами
+//   - error: ошибка чтения папок или отсутствие размеченных папок
+func ScanLabeled(root, manifestPath, profile string) (*Manifest, error) {
+	m := &Manifest{Profile: profile, dir: filepath.Dir(manifestPath)}
+	rel, err := filepath.Rel(m.dir, root)
+	if err != nil {
+		rel = root
+	}
+	m.Root = filepath.ToSlash(rel)
+
+	found := false
+	for dir, label := range labelDirs {
+		entries, err := os.ReadDir(filepath.Join(root, dir))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("ошибка чтения папки %s: %v", dir, err)
+		}
+		found = true
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() && !strings.EqualFold(filepath.Ext(name), ".csv") {
+				continue
+			}
+			m.Units = append(m.Units, Entry{Path: dir + "/" + name, Label: label})
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("в папке %s нет размеченных папок (Успешно, Успех, Брак)", root)
+	}
+	sort.Slice(m.Units, func(i, j int) bool { return m.Units[i].Path < m.Units[j].Path })
+	return m, nil
+}
@@ -0,0 +1,247 @@
+package corpus
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"compass_analyzer/analyzer"
+	"compass_analyzer/models"
+	"compass_analyzer/parser"
+)
+
+// Result - вердикт анализатора по одному компасу набора
+type Result struct {
+	Entry   Entry                       `json:"entry"`
+	Verdict Label                       `json:"verdict"`
+	Reason  models.ReasonCode           `json:"reason,omitempty"`
+	Quality float64                     `json:"quality"`
+	Error   string                      `json:"error,omitempty"`  // Ошибка чтения файла
+	Window  *analyzer.CalibrationWindow `json:"window,omitempty"` // Окно калибровки, в котором искались повороты
+}
+
+// Mismatch - вердикт анализатора расходится с эталоном (меткой оператора)
+func (r Result) Mismatch() bool {
+	return r.Verdict != r.Entry.ExpectedLabel()
+}
+
+// Flipped - вердикт отличается от принятого в манифесте (или принятого нет)
+func (r Result) Flipped() bool {
+	return r.Verdict != r.Entry.Verdict
+}
+
+// ReasonChanged - компас по-прежнему забракован, но по другой причине
+func (r Result) ReasonChanged() bool {
+	return !r.Flipped() && r.Verdict == Bad && r.Entry.Reason != "" && r.Reason != r.Entry.Reason
+}
+
+// Confusion - матрица ошибок: метка оператора против вердикта анализатора
+type Confusion struct {
+	TruePass    int `json:"truePass"`    // Годен и принят
+	FalseReject int `json:"falseReject"` // Годен, но забракован
+	FalseAccept int `json:"falseAccept"` // Брак, но принят
+	TrueReject  int `json:"trueReject"`  // Брак и забракован
+}
+
+// add учитывает вердикт компаса с меткой label
+func (c *Confusion) add(label, verdict Label) {
+	switch {
+	case label == Good && verdict == Good:
+		c.TruePass++
+	case label == Good:
+		c.FalseReject++
+	case verdict == Good:
+		c.FalseAccept++
+	default:
+		c.TrueReject++
+	}
+}
+
+// Total возвращает число компасов в матрице
+func (c Confusion) Total() int {
+	return c.TruePass + c.FalseReject + c.FalseAccept + c.TrueReject
+}
+
+// Agreement возвращает долю совпадений с оператором в процентах
+func (c Confusion) Agreement() float64 {
+	if c.Total() == 0 {
+		return 0
+	}
+	return float64(c.TruePass+c.TrueReject) / float64(c.Total()) * 100
+}
+
+// Report - результат прогона набора
+type Report struct {
+	Profile string    `json:"profile"`
+	Params  string    `json:"params"`
+	Matrix  Confusion `json:"matrix"`
+	Results []Result  `json:"results"`
+}
+
+// Flips возвращает компасы, вердикт которых изменился
+func (r Report) Flips() []Result {
+	var flips []Result
+	for _, res := range r.Results {
+		if res.Flipped() {
+			flips = append(flips, res)
+		}
+	}
+	return flips
+}
+
+// Regressed сообщает, что вердикт хотя бы одного компаса изменился
+func (r Report) Regressed() bool {
+	return len(r.Flips()) > 0
+}
+
+// Mismatches возвращает компасы, вердикт которых расходится с эталоном
+func (r Report) Mismatches() []Result {
+	var mismatches []Result
+	for _, res := range r.Results {
+		if res.Mismatch() {
+			mismatches = append(mismatches, res)
+		}
+	}
+	return mismatches
+}
+
+// ReasonChanges возвращает забракованные компасы со сменившейся причиной
+func (r Report) ReasonChanges() []Result {
+	var changes []Result
+	for _, res := range r.Results {
+		if res.ReasonChanged() {
+			changes = append(changes, res)
+		}
+	}
+	return changes
+}
+
+// Run анализирует все компасы манифеста так же, как команда analyze
+// (анализ поворотов и проверка поля магнитометра), сравнивает вердикты
+// с принятыми и строит матрицу ошибок против меток оператора (или
+// исправленного эталона, см. Entry.ExpectedLabel). Компас, файл которого
+// не удалось прочитать, считается забракованным с кодом причины ошибки чтения.
+//
+// Параметры:
+//   - m: манифест набора
+//   - params: параметры анализа
+//   - profile: профиль процедуры
+//   - logFile: файл для записи подробного лога (может быть nil)
+//
+// Возвращает:
+//   - Report: вердикты компасов и матрица ошибок
+func Run(m *Manifest, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) Report {
+	report := Report{Profile: profile.Name, Params: profile.DescribeParams(params)}
+	for _, e := range m.Units {
+		res := analyzeUnit(m.UnitPath(e), params, profile, logFile)
+		res.Entry = e
+		report.Matrix.add(e.ExpectedLabel(), res.Verdict)
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+// analyzeUnit анализирует папку компаса или CSV файл
+func analyzeUnit(path string, params models.AnalysisParams, profile models.ProcedureProfile, logFile *os.File) Result {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "SB_CMPS.csv")
+	}
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n##### %s #####\n", path)
+	}
+
+	res := Result{Verdict: Bad}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		res.Reason = models.ReasonFileMissing
+		res.Error = res.Reason.Format(path)
+		return res
+	}
+	records, err := parser.ReadTelemetry(path)
+	if err != nil {
+		reason, detail := parser.ReadErrorReason(err)
+		res.Reason = reason
+		res.Error = reason.Format(path, detail)
+		return res
+	}
+
+	result := analyzer.AnalyzeTelemetryWithJournal(records, parser.ReadJournalFor(path), params, profile, logFile)
+	res.Quality = result.Quality
+	res.Window = result.Window
+	if result.IsValid {
+		res.Verdict = Good
+	} else {
+		res.Reason = result.Reason()
+	}
+	return res
+}
+
+// Accept записывает текущие вердикты и причины в манифест как принятые
+// и обновляет записи манифеста в отчете (после этого отчет не содержит
+// изменившихся вердиктов). Метки оператора и исправленный эталон не
+// меняются: расхождение с оператором остается ошибкой матрицы.
+func (m *Manifest) Accept(report *Report) {
+	index := make(map[string]int, len(m.Units))
+	for i, e := range m.Units {
+		index[e.Path] = i
+	}
+	m.Profile = report.Profile
+	for r, res := range report.Results {
+		i, ok := index[res.Entry.Path]
+		if !ok {
+			continue
+		}
+		e := m.Units[i]
+		e.Verdict, e.Reason = res.Verdict, res.Reason
+		m.Units[i] = e
+		report.Results[r].Entry = e
+	}
+}
+
+// Print выводит матрицу ошибок, изменившиеся вердикты и причины
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "Профиль процедуры: %s\n", r.Profile)
+	fmt.Fprintf(w, "Параметры анализа: %s\n\n", r.Params)
+
+	m := r.Matrix
+	fmt.Fprintf(w, "Матрица ошибок (%d компасов, совпадение с оператором %.1f%%):\n", m.Total(), m.Agreement())
+	fmt.Fprintf(w, "  %-16s %10s %10s\n", "оператор \\ анализ", "годен", "брак")
+	fmt.Fprintf(w, "  %-16s %10d %10d\n", "годен", m.TruePass, m.FalseReject)
+	fmt.Fprintf(w, "  %-16s %10d %10d\n", "брак", m.FalseAccept, m.TrueReject)
+
+	if mismatches := r.Mismatches(); len(mismatches) > 0 {
+		fmt.Fprintf(w, "\nРасхождения с оператором (%d):\n", len(mismatches))
+		for _, res := range mismatches {
+			fmt.Fprintf(w, "  %-24s оператор: %-5s анализ: %-5s", res.Entry.Path, res.Entry.ExpectedLabel(), res.Verdict)
+			if res.Reason != "" {
+				fmt.Fprintf(w, " [%s] %s", res.Reason, res.Reason.Title())
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	flips := r.Flips()
+	if len(flips) == 0 {
+		fmt.Fprintf(w, "\n✓ Вердикты совпадают с принятыми в манифесте\n")
+	} else {
+		fmt.Fprintf(w, "\n✗ Изменились вердикты (%d):\n", len(flips))
+		for _, res := range flips {
+			accepted := "нет"
+			if res.Entry.Verdict != "" {
+				accepted = res.Entry.Verdict.String()
+			}
+			fmt.Fprintf(w, "  %-24s %s → %s (оператор: %s)", res.Entry.Path, accepted, res.Verdict, res.Entry.ExpectedLabel())
+			if res.Reason != "" {
+				fmt.Fprintf(w, " [%s] %s", res.Reason, res.Reason.Title())
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	if changes := r.ReasonChanges(); len(changes) > 0 {
+		fmt.Fprintf(w, "\n⚠ Изменились причины отбраковки (%d):\n", len(changes))
+		for _, res := range changes {
+			fmt.Fprintf(w, "  %-24s %s → %s\n", res.Entry.Path, res.Entry.Reason, res.Reason)
+		}
+	}
+}
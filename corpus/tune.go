@@ -0,0 +1,375 @@
+package corpus
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"compass_analyzer/analyzer"
+	"compass_analyzer/models"
+	"compass_analyzer/parser"
+)
+
+// Подбираемые параметры анализа (ключи Space)
+const (
+	TuneStability  = "stability"  // Порог стабильности сегмента, градусы
+	TuneTurn       = "turn"       // Допуск угла поворота, градусы
+	TuneMinLen     = "min_len"    // Минимальная длина сегмента, записей
+	TuneSum        = "sum"        // Допуск суммы поворотов, градусы
+	TuneContinuity = "continuity" // Допуск непрерывности цепочки, градусы
+)
+
+// TuneNames возвращает подбираемые параметры в порядке вывода
+func TuneNames() []string {
+	return []string{TuneStability, TuneTurn, TuneMinLen, TuneSum, TuneContinuity}
+}
+
+// Range - диапазон значений параметра: от Min до Max с шагом Step
+type Range struct {
+	Min, Max, Step float64
+}
+
+// ParseRange разбирает диапазон «мин:макс:шаг» или одно значение
+func ParseRange(s string) (Range, error) {
+	parts := strings.Split(s, ":")
+	var values []float64
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Range{}, fmt.Errorf("некорректный диапазон «%s»: %v", s, err)
+		}
+		values = append(values, v)
+	}
+	switch len(values) {
+	case 1:
+		return Range{Min: values[0], Max: values[0], Step: 1}, nil
+	case 3:
+		r := Range{Min: values[0], Max: values[1], Step: values[2]}
+		if r.Step <= 0 || r.Max < r.Min {
+			return Range{}, fmt.Errorf("некорректный диапазон «%s»: нужно мин ≤ макс и шаг > 0", s)
+		}
+		return r, nil
+	}
+	return Range{}, fmt.Errorf("некорректный диапазон «%s»: ожидалось «мин:макс:шаг» или одно значение", s)
+}
+
+// Values возвращает все значения диапазона
+func (r Range) Values() []float64 {
+	var values []float64
+	for i := 0; ; i++ {
+		v := r.Min + float64(i)*r.Step
+		if v > r.Max+1e-9 {
+			break
+		}
+		values = append(values, math.Round(v*1000)/1000)
+	}
+	return values
+}
+
+// String возвращает диапазон в виде «мин:макс:шаг»
+func (r Range) String() string {
+	if r.Min == r.Max {
+		return strconv.FormatFloat(r.Min, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%g:%g:%g", r.Min, r.Max, r.Step)
+}
+
+// Space - диапазоны подбираемых параметров (ключи - TuneStability и т.д.)
+type Space map[string]Range
+
+// DefaultSpace возвращает диапазоны вокруг параметров по умолчанию
+func DefaultSpace() Space {
+	return Space{
+		TuneStability:  {Min: 3, Max: 7, Step: 1},
+		TuneTurn:       {Min: 10, Max: 20, Step: 5},
+		TuneMinLen:     {Min: 2, Max: 4, Step: 1},
+		TuneSum:        {Min: 10, Max: 20, Step: 5},
+		TuneContinuity: {Min: 10, Max: 30, Step: 10},
+	}
+}
+
+// Size возвращает количество точек сетки
+func (s Space) Size() int {
+	size := 1
+	for _, name := range TuneNames() {
+		size *= len(s[name].Values())
+	}
+	return size
+}
+
+// Point - значения подбираемых параметров
+type Point map[string]float64
+
+// apply возвращает профиль, в котором заданы значения точки
+func (p Point) apply(profile models.ProcedureProfile) models.ProcedureProfile {
+	profile.StabilityThreshold = p[TuneStability]
+	profile.StepTolerance = p[TuneTurn]
+	profile.MinStableLen = int(p[TuneMinLen])
+	profile.SumTolerance = p[TuneSum]
+	profile.ContinuityTolerance = p[TuneContinuity]
+	return profile
+}
+
+// String возвращает значения точки одной строкой
+func (p Point) String() string {
+	return fmt.Sprintf("стабильность %.2f°, поворот ±%.2f°, сегмент ≥ %d, сумма ±%.2f°, непрерывность %.2f°",
+		p[TuneStability], p[TuneTurn], int(p[TuneMinLen]), p[TuneSum], p[TuneContinuity])
+}
+
+// grid возвращает все точки сетки
+func (s Space) grid() []Point {
+	points := []Point{{}}
+	for _, name := range TuneNames() {
+		var next []Point
+		for _, p := range points {
+			for _, v := range s[name].Values() {
+				q := Point{name: v}
+				for k, x := range p {
+					q[k] = x
+				}
+				next = append(next, q)
+			}
+		}
+		points = next
+	}
+	return points
+}
+
+// random возвращает n случайных точек сетки без повторов
+func (s Space) random(n int, rng *rand.Rand) []Point {
+	all := s.grid()
+	if n >= len(all) {
+		return all
+	}
+	rng.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+	return all[:n]
+}
+
+// Candidate - набор параметров и его ошибки на размеченном наборе
+type Candidate struct {
+	Point  Point     `json:"point"`
+	Matrix Confusion `json:"matrix"`
+}
+
+// FalseAcceptRate возвращает долю принятого брака в процентах
+func (c Candidate) FalseAcceptRate() float64 {
+	return rate(c.Matrix.FalseAccept, c.Matrix.FalseAccept+c.Matrix.TrueReject)
+}
+
+// FalseRejectRate возвращает долю забракованных годных компасов в процентах
+func (c Candidate) FalseRejectRate() float64 {
+	return rate(c.Matrix.FalseReject, c.Matrix.FalseReject+c.Matrix.TruePass)
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// dominates сообщает, что c не хуже other по обеим ошибкам и лучше хотя бы по одной
+func (c Candidate) dominates(other Candidate) bool {
+	fa, fr := c.FalseAcceptRate(), c.FalseRejectRate()
+	ofa, ofr := other.FalseAcceptRate(), other.FalseRejectRate()
+	return fa <= ofa && fr <= ofr && (fa < ofa || fr < ofr)
+}
+
+// TuneOptions - способ перебора параметров
+type TuneOptions struct {
+	Space Space
+	// Samples - количество случайных точек; 0 - полный перебор сетки
+	Samples int
+	// Seed - начальное значение генератора случайных чисел
+	Seed int64
+	// Progress - куда выводить ход перебора (может быть nil)
+	Progress io.Writer
+}
+
+// TuneResult - результат подбора параметров
+type TuneResult struct {
+	Candidates []Candidate `json:"candidates"` // Все проверенные наборы
+	Front      []Candidate `json:"front"`      // Парето-фронт: по возрастанию доли принятого брака
+	Baseline   Candidate   `json:"baseline"`   // Текущие параметры
+}
+
+// unit - прочитанный компас набора
+type unit struct {
+	label   Label
+	records []models.TelemetryRecord
+	journal []parser.JournalEntry // Журнал блока AB для поиска окна калибровки
+}
+
+// loadUnits читает файлы всех компасов манифеста один раз для всего перебора.
+// Компасы, файлы которых не читаются, в подборе не участвуют (их вердикт
+// от параметров не зависит).
+func loadUnits(m *Manifest) ([]unit, int) {
+	var units []unit
+	skipped := 0
+	for _, e := range m.Units {
+		path := m.UnitPath(e)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, "SB_CMPS.csv")
+		}
+		records, err := parser.ReadTelemetry(path)
+		if err != nil {
+			skipped++
+			continue
+		}
+		units = append(units, unit{label: e.Label, records: records, journal: parser.ReadJournalFor(path)})
+	}
+	return units, skipped
+}
+
+// evaluate строит матрицу ошибок набора параметров против меток оператора
+func evaluate(units []unit, params models.AnalysisParams, profile models.ProcedureProfile) Confusion {
+	var matrix Confusion
+	for _, u := range units {
+		verdict := Bad
+		if analyzer.AnalyzeTelemetryWithJournal(u.records, u.journal, params, profile, nil).IsValid {
+			verdict = Good
+		}
+		matrix.add(u.label, verdict)
+	}
+	return matrix
+}
+
+// Tune подбирает порог стабильности, допуски поворота, суммы и непрерывности
+// и минимальную длину сегмента по меткам оператора размеченного набора
+// (полным перебором сетки или случайными точками сетки). Для каждого набора
+// считаются доля принятого брака и доля забракованных годных компасов;
+// результат - Парето-фронт наборов, которые нельзя улучшить по одной
+// ошибке, не ухудшив другую.
+//
+// Параметры:
+//   - m: манифест размеченного набора (используются метки Label)
+//   - params: остальные параметры анализа
+//   - profile: профиль процедуры, пороги которого подбираются
+//   - opts: диапазоны и способ перебора
+//
+// Возвращает:
+//   - TuneResult: проверенные наборы, Парето-фронт и текущие параметры
+//   - error: в наборе нет читаемых компасов
+func Tune(m *Manifest, params models.AnalysisParams, profile models.ProcedureProfile, opts TuneOptions) (TuneResult, error) {
+	units, skipped := loadUnits(m)
+	if len(units) == 0 {
+		return TuneResult{}, fmt.Errorf("в наборе нет компасов с читаемыми файлами")
+	}
+	if opts.Progress != nil && skipped > 0 {
+		fmt.Fprintf(opts.Progress, "ℹ Компасов с нечитаемыми файлами (не участвуют в подборе): %d\n", skipped)
+	}
+
+	var points []Point
+	if opts.Samples > 0 {
+		points = opts.Space.random(opts.Samples, rand.New(rand.NewSource(opts.Seed)))
+	} else {
+		points = opts.Space.grid()
+	}
+
+	var result TuneResult
+	current := profile.ParamsFor(params)
+	result.Baseline = Candidate{
+		Point: Point{
+			TuneStability:  current.StabilityThreshold,
+			TuneTurn:       profile.StepToleranceFor(params),
+			TuneMinLen:     float64(current.MinStableLen),
+			TuneSum:        profile.SumToleranceFor(params),
+			TuneContinuity: current.ContinuityTolerance,
+		},
+		Matrix: evaluate(units, params, profile),
+	}
+
+	for i, p := range points {
+		c := Candidate{Point: p, Matrix: evaluate(units, params, p.apply(profile))}
+		result.Candidates = append(result.Candidates, c)
+		if opts.Progress != nil && (i+1)%10 == 0 {
+			fmt.Fprintf(opts.Progress, "  проверено %d из %d наборов\n", i+1, len(points))
+		}
+	}
+	result.Front = paretoFront(result.Candidates, result.Baseline.Point, opts.Space)
+	return result, nil
+}
+
+// paretoFront возвращает недоминируемые наборы по возрастанию доли
+// принятого брака. Из наборов с одинаковыми ошибками остается ближайший
+// к текущим параметрам baseline (меньше всего меняющий настройку).
+func paretoFront(candidates []Candidate, baseline Point, space Space) []Candidate {
+	var front []Candidate
+	for i, c := range candidates {
+		dominated := false
+		for j, other := range candidates {
+			if other.dominates(c) {
+				dominated = true
+				break
+			}
+			if i != j && other.Matrix == c.Matrix {
+				d, od := c.Point.distance(baseline, space), other.Point.distance(baseline, space)
+				if od < d || (od == d && j < i) {
+					dominated = true
+					break
+				}
+			}
+		}
+		if !dominated {
+			front = append(front, c)
+		}
+	}
+	sort.SliceStable(front, func(i, j int) bool { return front[i].FalseAcceptRate() < front[j].FalseAcceptRate() })
+	return front
+}
+
+// distance возвращает расстояние между точками в шагах диапазонов
+func (p Point) distance(other Point, space Space) float64 {
+	d := 0.0
+	for _, name := range TuneNames() {
+		step := space[name].Step
+		if step <= 0 {
+			step = 1
+		}
+		d += math.Abs(p[name]-other[name]) / step
+	}
+	return d
+}
+
+// Balanced возвращает набор фронта с наименьшей суммой двух ошибок
+// (при равенстве - с меньшей долей принятого брака)
+func (r TuneResult) Balanced() (Candidate, bool) {
+	if len(r.Front) == 0 {
+		return Candidate{}, false
+	}
+	best := r.Front[0]
+	for _, c := range r.Front[1:] {
+		if c.FalseAcceptRate()+c.FalseRejectRate() < best.FalseAcceptRate()+best.FalseRejectRate() {
+			best = c
+		}
+	}
+	return best, true
+}
+
+// Profile возвращает профиль на основе base с параметрами набора
+func (c Candidate) Profile(base models.ProcedureProfile, name string) models.ProcedureProfile {
+	p := c.Point.apply(base)
+	p.Name = name
+	p.Description = fmt.Sprintf("%s; пороги подобраны по размеченному набору: принято брака %.1f%%, забраковано годных %.1f%%",
+		strings.TrimSuffix(base.Description, "."), c.FalseAcceptRate(), c.FalseRejectRate())
+	return p
+}
+
+// Print выводит текущие параметры и Парето-фронт
+func (r TuneResult) Print(w io.Writer) {
+	fmt.Fprintf(w, "Проверено наборов параметров: %d\n", len(r.Candidates))
+	fmt.Fprintf(w, "\nТекущие параметры: %s\n", r.Baseline.Point)
+	fmt.Fprintf(w, "  принято брака %.1f%%, забраковано годных %.1f%%\n", r.Baseline.FalseAcceptRate(), r.Baseline.FalseRejectRate())
+
+	fmt.Fprintf(w, "\nПарето-фронт (%d наборов):\n", len(r.Front))
+	fmt.Fprintf(w, "  %3s %14s %14s  %s\n", "№", "принято брака", "забрак. годных", "параметры")
+	for i, c := range r.Front {
+		fmt.Fprintf(w, "  %3d %13.1f%% %13.1f%%  %s\n", i+1, c.FalseAcceptRate(), c.FalseRejectRate(), c.Point)
+	}
+}
@@ -195,7 +195,9 @@ func (at *AnalysisTab) StartAnalysis() {
 	at.mainWindow.AppendLog(fmt.Sprintf("╔════════════════════════════════════════════════════════════╗\n"))
 	at.mainWindow.AppendLog(fmt.Sprintf("║         НАЧАЛО АНАЛИЗА КОМПАСОВ                          ║\n"))
 	at.mainWindow.AppendLog(fmt.Sprintf("╚════════════════════════════════════════════════════════════╝\n\n"))
-	at.mainWindow.AppendLog(fmt.Sprintf("Директория: %s\n\n", at.mainWindow.state.DataDir))
+	at.mainWindow.AppendLog(fmt.Sprintf("Директория: %s\n", at.mainWindow.state.DataDir))
+	at.mainWindow.AppendLog(fmt.Sprintf("Профиль процедуры: %s\n", at.mainWindow.state.Profile))
+	at.mainWindow.AppendLog(fmt.Sprintf("Параметры анализа: %s\n\n", at.mainWindow.state.Profile.DescribeParams(at.mainWindow.state.Params)))
 
 	// Запускаем анализ в отдельной горутине
 	go at.processDirectory()
@@ -257,7 +259,7 @@ func (at *AnalysisTab) processDirectory() {
 			failCount++
 		}
 
-		at.updateTableRow(i, folderName, status, fmt.Sprintf("%d/4", len(turns)))
+		at.updateTableRow(i, folderName, status, fmt.Sprintf("%d/%d", len(turns), at.mainWindow.state.Profile.StepCount))
 
 		// Пауза для визуализации (можно убрать в продакшене)
 		time.Sleep(100 * time.Millisecond)
@@ -288,35 +290,41 @@ func (at *AnalysisTab) analyzeCompass(folderPath, folderName string) (bool, []mo
 	}
 
 	// Читаем данные
-	data, err := parser.ReadCSVFile(csvPath)
+	records, err := parser.ReadTelemetry(csvPath)
 	if err != nil {
 		at.mainWindow.AppendLog(fmt.Sprintf("  ✗ Ошибка чтения CSV: %v\n", err))
 		return false, nil
 	}
 
-	// Извлекаем углы
-	angles := make([]float64, len(data))
-	for i, d := range data {
-		angles[i] = d.Angle
-	}
-
-	at.mainWindow.AppendLog(fmt.Sprintf("  ✓ Прочитано записей: %d\n", len(angles)))
+	at.mainWindow.AppendLog(fmt.Sprintf("  ✓ Прочитано записей: %d\n", len(records)))
 
 	// Анализируем (без лог-файла для GUI)
-	isValid, turns := analyzer.AnalyzeCompassData(angles, nil)
+	analysis := analyzer.AnalyzeTelemetryWithJournal(records, parser.ReadJournalFor(csvPath), at.mainWindow.state.Params, at.mainWindow.state.Profile, nil)
+	isValid, turns := analysis.IsValid, analysis.Turns
+
+	if analysis.Window != nil {
+		at.mainWindow.AppendLog(fmt.Sprintf("  • Окно калибровки: %s\n", analysis.Window))
+	} else {
+		at.mainWindow.AppendLog("  • Окно калибровки не найдено - анализируется весь файл\n")
+	}
 
-	at.mainWindow.AppendLog(fmt.Sprintf("  • Найдено поворотов: %d/4\n", len(turns)))
+	stepCount := at.mainWindow.state.Profile.StepCount
+	at.mainWindow.AppendLog(fmt.Sprintf("  • Найдено поворотов: %d/%d\n", len(turns), stepCount))
+	at.mainWindow.AppendLog(fmt.Sprintf("  • Оценка качества: %.0f/100\n", analysis.Quality))
 
 	if isValid {
 		at.mainWindow.AppendLog(fmt.Sprintf("  ✅ КАЛИБРОВКА УСПЕШНА\n"))
 		for i, turn := range turns {
-			at.mainWindow.AppendLog(fmt.Sprintf("    %d. %.2f° → %.2f° (Δ=%.2f°)\n",
-				i+1, turn.StartAngle, turn.EndAngle, turn.Diff))
+			at.mainWindow.AppendLog(fmt.Sprintf("    %d. %.2f° → %.2f° (Δ=%.2f°, уверенность %.0f)\n",
+				i+1, turn.StartAngle, turn.EndAngle, turn.Diff, turn.Confidence))
+		}
+		if analysis.NeedsRetest() {
+			at.mainWindow.AppendLog("  ⚠ Оценка качества ниже порога - рекомендуется перепроверка\n")
 		}
 	} else {
 		at.mainWindow.AppendLog(fmt.Sprintf("  ❌ КАЛИБРОВКА НЕ ПРОШЛА\n"))
-		if len(turns) < 4 {
-			at.mainWindow.AppendLog(fmt.Sprintf("    Причина: Недостаточно поворотов (%d < 4)\n", len(turns)))
+		for _, check := range analysis.FailedChecks() {
+			at.mainWindow.AppendLog(fmt.Sprintf("    Причина [%s]: %s\n", check.Reason, check.Message))
 		}
 	}
 
@@ -10,6 +10,8 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"compass_analyzer/models"
 )
 
 // AppState хранит глобальное состояние приложения
@@ -20,6 +22,8 @@ type AppState struct {
 	CurrentLog   string
 	Results      []CompassResult
 	IsProcessing bool
+	Params       models.AnalysisParams   // Параметры анализа из файла конфигурации
+	Profile      models.ProcedureProfile // Профиль процедуры калибровки
 }
 
 // CompassResult представляет результат анализа компаса
@@ -45,7 +49,7 @@ type MainWindow struct {
 }
 
 // NewMainWindow создает новое главное окно
-func NewMainWindow() *MainWindow {
+func NewMainWindow(params models.AnalysisParams, profile models.ProcedureProfile) *MainWindow {
 	myApp := app.NewWithID("com.ulyanov.compass-analyzer")
 	myApp.SetIcon(theme.ComputerIcon())
 
@@ -55,6 +59,8 @@ func NewMainWindow() *MainWindow {
 
 	state := &AppState{
 		Results: make([]CompassResult, 0),
+		Params:  params,
+		Profile: profile,
 	}
 
 	mw := &MainWindow{
@@ -200,8 +206,8 @@ func (mw *MainWindow) ShowProgress(show bool) {
 }
 
 // CreateDesktopApp создает и запускает десктопное приложение
-func CreateDesktopApp() {
-	mainWindow := NewMainWindow()
+func CreateDesktopApp(params models.AnalysisParams, profile models.ProcedureProfile) {
+	mainWindow := NewMainWindow(params, profile)
 	mainWindow.Run()
 }
 
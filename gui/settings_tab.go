@@ -6,6 +6,8 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"compass_analyzer/models"
 )
 
 // SettingsTab представляет вкладку настроек
@@ -15,6 +17,7 @@ type SettingsTab struct {
 	dataDirEntry    *widget.Entry
 	successDirEntry *widget.Entry
 	failureDirEntry *widget.Entry
+	profileSelect   *widget.Select
 }
 
 // NewSettingsTab создает новую вкладку настроек
@@ -48,11 +51,20 @@ func (st *SettingsTab) buildUI() {
 	st.failureDirEntry = widget.NewEntry()
 	st.failureDirEntry.SetPlaceHolder("Директория для брака...")
 
+	// Профиль процедуры калибровки
+	var profileNames []string
+	for _, p := range models.Profiles() {
+		profileNames = append(profileNames, p.Name)
+	}
+	st.profileSelect = widget.NewSelect(profileNames, nil)
+	st.profileSelect.SetSelected(st.mainWindow.state.Profile.Name)
+
 	form := &widget.Form{
 		Items: []*widget.FormItem{
 			{Text: "Директория с данными:", Widget: st.dataDirEntry},
 			{Text: "Успешные:", Widget: st.successDirEntry},
 			{Text: "Брак:", Widget: st.failureDirEntry},
+			{Text: "Профиль процедуры:", Widget: st.profileSelect},
 		},
 		OnSubmit: func() {
 			st.SaveSettings()
@@ -111,5 +123,12 @@ func (st *SettingsTab) SaveSettings() {
 	st.mainWindow.state.SuccessDir = st.successDirEntry.Text
 	st.mainWindow.state.FailureDir = st.failureDirEntry.Text
 
+	profile, err := models.FindProfile(st.profileSelect.Selected)
+	if err != nil {
+		dialog.ShowError(err, st.mainWindow.window)
+		return
+	}
+	st.mainWindow.state.Profile = profile
+
 	dialog.ShowInformation("Настройки сохранены", "Настройки успешно сохранены", st.mainWindow.window)
 }
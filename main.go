@@ -21,12 +21,20 @@ import (
 	"github.com/fatih/color"
 )
 
-// Config хранит пути к директориям
+// Config хранит пути к директориям и параметры анализа
 type Config struct {
 	DataDir    string `json:"data_dir"`
 	SuccessDir string `json:"success_dir"`
 	FailureDir string `json:"failure_dir"`
 	RenameDir  string `json:"rename_dir"`
+	// Analysis - пороги алгоритма; не указанные в файле поля берутся по умолчанию
+	Analysis *models.AnalysisParams `json:"analysis,omitempty"`
+	// Profile - имя профиля процедуры калибровки (по умолчанию "4x90-cw")
+	Profile string `json:"profile,omitempty"`
+	// Profiles - собственные профили процедуры в дополнение к встроенным
+	Profiles []models.ProcedureProfile `json:"profiles,omitempty"`
+	// FlagsFile - словарь флагов прошивки вместо встроенного (см. models.LoadFlagDictionary)
+	FlagsFile string `json:"flags_file,omitempty"`
 }
 
 func showResults(results models.SessionResults) {
@@ -36,6 +44,12 @@ func showResults(results models.SessionResults) {
 	red := color.New(color.FgRed).SprintFunc()
 
 	fmt.Printf("\n%s\n", cyan("Результаты калибровки компасов:"))
+	fmt.Printf("%s: %s\n", cyan("Профиль процедуры"), results.Profile)
+	if profile, err := models.FindProfile(results.Profile); err == nil {
+		fmt.Printf("%s: %s\n", cyan("Параметры анализа"), profile.DescribeParams(results.Params))
+	} else {
+		fmt.Printf("%s: %s\n", cyan("Параметры анализа"), results.Params)
+	}
 	totalCompasses := len(results.SuccessfulCompasses) + len(results.FailedCompasses)
 	fmt.Printf("%s: %d станций\n", cyan("Всего проанализировано"), totalCompasses)
 	fmt.Printf("%s: %d станций\n", green("Успешно откалибровано"), len(results.SuccessfulCompasses))
@@ -65,6 +79,17 @@ func showResults(results models.SessionResults) {
 			fmt.Printf("%s ", number)
 		}
 		fmt.Println()
+
+		var retest []string
+		for _, number := range successfulNumbers {
+			if r := results.SuccessfulCompasses[number]; r.NeedsRetest {
+				retest = append(retest, fmt.Sprintf("%s (%.0f)", number, r.Quality))
+			}
+		}
+		if len(retest) > 0 {
+			fmt.Printf("\n%s:\n%s\n", yellow(fmt.Sprintf("Рекомендуется перепроверка (оценка качества < %.0f)", results.Params.RetestQuality)),
+				strings.Join(retest, " "))
+		}
 	}
 
 	fmt.Printf("\n%s:\n", red("Станции, не прошедшие калибровку"))
@@ -75,6 +100,11 @@ func showResults(results models.SessionResults) {
 			fmt.Printf("%s ", number)
 		}
 		fmt.Println()
+
+		fmt.Printf("\n%s:\n", red("Причины отбраковки"))
+		for _, rc := range models.CountReasons(results.FailedCompasses) {
+			fmt.Printf("  %-20s %-36s %d\n", rc.Reason, rc.Reason.Title(), rc.Count)
+		}
 	}
 
 	fmt.Printf("\n%s", cyan("Показать детальную информацию по станциям? (y/n): "))
@@ -111,10 +141,14 @@ func showDetailedResults(results models.SessionResults) {
 	for _, number := range successfulNumbers {
 		result := results.SuccessfulCompasses[number]
 		fmt.Printf("\n%s %s:\n", green("Станция"), number)
+		fmt.Printf("%s %.0f/100\n", yellow("Оценка качества:"), result.Quality)
+		if result.Window != "" {
+			fmt.Printf("%s %s\n", yellow("Окно калибровки:"), result.Window)
+		}
 		fmt.Printf("%s\n", yellow("Найденные повороты:"))
 		for i, turn := range result.Turns {
-			fmt.Printf("Поворот %d: %.2f° -> %.2f° (изменение: %.2f°)\n",
-				i+1, turn.StartAngle, turn.EndAngle, turn.Diff)
+			fmt.Printf("Поворот %d: %.2f° -> %.2f° (изменение: %.2f°, уверенность: %.0f)\n",
+				i+1, turn.StartAngle, turn.EndAngle, turn.Diff, turn.Confidence)
 			fmt.Printf("  Индексы: %d -> %d\n", turn.StartIndex, turn.EndIndex)
 		}
 		fmt.Printf("\n%s\n", yellow("Все записи углов:"))
@@ -127,10 +161,23 @@ func showDetailedResults(results models.SessionResults) {
 	for _, number := range failedNumbers {
 		result := results.FailedCompasses[number]
 		fmt.Printf("\n%s %s:\n", red("Станция"), number)
+		fmt.Printf("%s %s (%s)\n", yellow("Причина:"), result.Reason.Title(), result.Reason)
+		if result.Window != "" {
+			fmt.Printf("%s %s\n", yellow("Окно калибровки:"), result.Window)
+		}
+		if result.Reason.SensorFault() {
+			fmt.Printf("%s\n", red("Неисправность датчика: повторная калибровка не поможет, прибор - в ремонт"))
+		}
 		fmt.Printf("%s\n", yellow("Ошибки:"))
 		for _, err := range result.Errors {
 			fmt.Printf("- %s\n", red(err))
 		}
+		if len(result.Checks) > 0 {
+			fmt.Printf("\n%s\n", yellow("Проверки:"))
+			for _, check := range result.Checks {
+				fmt.Printf("%s\n", check)
+			}
+		}
 		if len(result.Turns) > 0 {
 			fmt.Printf("\n%s\n", yellow("Найденные повороты:"))
 			for i, turn := range result.Turns {
@@ -168,9 +215,11 @@ func moveFolder(source, destination string) error {
 	return nil
 }
 
-func runSession(dataDir, successDir, failureDir string) models.SessionResults {
+func runSession(dataDir, successDir, failureDir string, params models.AnalysisParams, profile models.ProcedureProfile) models.SessionResults {
 	fmt.Println("\nАнализатор данных компаса")
 	fmt.Println("------------------------")
+	fmt.Printf("Профиль процедуры: %s\n", profile)
+	fmt.Printf("Параметры анализа: %s\n", profile.DescribeParams(params))
 
 	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
 		log.Fatalf("Директория с данными не существует: %s", dataDir)
@@ -182,6 +231,8 @@ func runSession(dataDir, successDir, failureDir string) models.SessionResults {
 	}
 
 	results := models.SessionResults{
+		Params:              params,
+		Profile:             profile.Name,
 		SuccessfulCompasses: make(map[string]models.CompassResult),
 		FailedCompasses:     make(map[string]models.CompassResult),
 	}
@@ -240,7 +291,8 @@ func runSession(dataDir, successDir, failureDir string) models.SessionResults {
 			fmt.Printf("Компас %s: файл не найден\n", folderName)
 			result := models.CompassResult{
 				CompassNumber: folderName,
-				Errors:        []string{fmt.Sprintf("Файл данных SB_CMPS.csv не найден: %s", csvPath)},
+				Reason:        models.ReasonFileMissing,
+				Errors:        []string{models.ReasonFileMissing.Format(csvPath)},
 			}
 			results.FailedCompasses[folderName] = result
 			continue
@@ -248,37 +300,26 @@ func runSession(dataDir, successDir, failureDir string) models.SessionResults {
 			fmt.Printf("Компас %s: ошибка доступа к файлу - %v\n", folderName, err)
 			result := models.CompassResult{
 				CompassNumber: folderName,
-				Errors:        []string{fmt.Sprintf("Ошибка доступа к файлу SB_CMPS.csv (%s): %v", csvPath, err)},
+				Reason:        models.ReasonFileAccess,
+				Errors:        []string{models.ReasonFileAccess.Format(csvPath, err)},
 			}
 			results.FailedCompasses[folderName] = result
 			continue
 		}
 
-		data, err := parser.ReadCSVFile(csvPath)
+		records, err := parser.ReadTelemetry(csvPath)
 		if err != nil {
 			fmt.Printf("Компас %s: ошибка чтения данных - %v\n", folderName, err)
-			errorMsg := fmt.Sprintf("Ошибка чтения файла данных (%s): ", csvPath)
-			if err.Error() == "ошибка чтения заголовка: EOF" {
-				errorMsg += "файл не содержит данных (пустой файл)"
-			} else if err.Error() == "файл не содержит данных" {
-				errorMsg += "файл содержит только заголовок, но не содержит данных"
-			} else {
-				errorMsg += err.Error()
-			}
+			reason, detail := parser.ReadErrorReason(err)
 			result := models.CompassResult{
 				CompassNumber: folderName,
-				Errors:        []string{errorMsg},
+				Reason:        reason,
+				Errors:        []string{reason.Format(csvPath, detail)},
 			}
 			results.FailedCompasses[folderName] = result
 			continue
 		}
 
-		// Получаем углы из данных
-		angles := make([]float64, len(data))
-		for i, d := range data {
-			angles[i] = d.Angle
-		}
-
 		// Создаем файл лога для текущего компаса
 		logFilePath := filepath.Join(analysisLogDir, fmt.Sprintf("compass_%s.log", folderName))
 		logFile, err := os.Create(logFilePath)
@@ -289,19 +330,24 @@ func runSession(dataDir, successDir, failureDir string) models.SessionResults {
 			defer logFile.Close()
 		}
 
-		// Анализируем данные с помощью нового алгоритма
-		isValid, turns := analyzer.AnalyzeCompassData(angles, logFile)
+		// Анализируем данные с помощью нового алгоритма (повороты - в окне калибровки)
+		analysis := analyzer.AnalyzeTelemetryWithJournal(records, parser.ReadJournalFor(csvPath), params, profile, logFile)
 
+		// Причины отбраковки берутся из проваленных проверок анализа
 		result := models.CompassResult{
 			CompassNumber: folderName,
-			AllAngles:     angles,
-			Turns:         turns,
-			IsValid:       isValid,
+			Profile:       profile.Name,
+			AllAngles:     analysis.Series.Filtered, // Индексы поворотов относятся к ряду после предобработки
+			Turns:         analysis.Turns,
+			IsValid:       analysis.IsValid,
+			Reason:        analysis.Reason(),
+			Quality:       analysis.Quality,
+			NeedsRetest:   analysis.NeedsRetest(),
+			Checks:        analysis.Checks,
+			Errors:        analysis.Errors(),
 		}
-
-		if !result.IsValid {
-			result.Errors = append(result.Errors,
-				fmt.Sprintf("Недостаточно найденных поворотов на ~90 градусов: %d (ожидалось не менее 4)", len(turns)))
+		if analysis.Window != nil {
+			result.Window = analysis.Window.String()
 		}
 
 		if result.IsValid {
@@ -335,14 +381,35 @@ func loadConfig() (*Config, error) {
 		return nil, err
 	}
 	configPath := filepath.Join(configDir, "compass_analyzer", "config.json")
+	defaults := models.DefaultAnalysisParams()
 	file, err := os.Open(configPath)
 	if err != nil {
-		return &Config{}, nil
+		return &Config{Analysis: &defaults}, nil
 	}
 	defer file.Close()
-	var cfg Config
+	// Раздел "analysis" декодируется поверх значений по умолчанию
+	cfg := Config{Analysis: &defaults}
 	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
-		return &Config{}, nil
+		return nil, fmt.Errorf("ошибка разбора %s: %v", configPath, err)
+	}
+	if cfg.Analysis == nil {
+		cfg.Analysis = &defaults
+	}
+	if err := cfg.Analysis.Validate(); err != nil {
+		return nil, fmt.Errorf("некорректные параметры анализа в %s: %v", configPath, err)
+	}
+	if err := models.RegisterProfiles(cfg.Profiles); err != nil {
+		return nil, fmt.Errorf("некорректные профили процедуры в %s: %v", configPath, err)
+	}
+	if _, err := models.FindProfile(cfg.Profile); err != nil {
+		return nil, fmt.Errorf("ошибка выбора профиля в %s: %v", configPath, err)
+	}
+	if cfg.FlagsFile != "" {
+		dict, err := models.LoadFlagDictionary(cfg.FlagsFile)
+		if err != nil {
+			return nil, err
+		}
+		models.RegisterFlagDictionary(dict)
 	}
 	return &cfg, nil
 }
@@ -478,6 +545,15 @@ func printLogo() {
 }
 
 func main() {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+	profile, err := models.FindProfile(cfg.Profile)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+
 	// Проверяем аргументы командной строки
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
@@ -487,12 +563,12 @@ func main() {
 			fmt.Println("║      Compass Analyzer - Desktop GUI Starting            ║")
 			fmt.Println("╚════════════════════════════════════════════════════════════╝")
 			fmt.Println("\n🖥️  Запуск десктопного приложения...\n")
-			gui.CreateDesktopApp()
+			gui.CreateDesktopApp(*cfg.Analysis, profile)
 			return
 			
 		case "tui":
 			// Запуск Terminal UI (работает без GCC)
-			StartTUI()
+			StartTUI(*cfg.Analysis, profile)
 			return
 			
 		case "web":
@@ -502,14 +578,41 @@ func main() {
 			fmt.Println("╚════════════════════════════════════════════════════════════╝")
 			fmt.Println("\n🌐 Запуск веб-сервера...")
 			fmt.Println("   Для остановки нажмите Ctrl+C\n")
-			webui.StartWebUI("8080")
+			webui.StartWebUI("8080", *cfg.Analysis, profile)
 			return
-		}
-	}
 
-	cfg, err := loadConfig()
-	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+		case "normalize":
+			// Сохранение упорядоченной копии CSV файла (исходный файл не изменяется)
+			os.Exit(runNormalizeCommand(os.Args[2:]))
+
+		case "window":
+			// Окно калибровки вместо ручной обрезки файла (с -save - копия файла окна)
+			os.Exit(runWindowCommand(os.Args[2:]))
+
+		case "analyze":
+			// Анализ папок компасов по выбранному профилю без перемещения папок
+			os.Exit(runAnalyzeCommand(os.Args[2:], *cfg.Analysis, profile))
+
+		case "segments":
+			// Сравнение алгоритмов поиска стабильных сегментов на одном файле
+			os.Exit(runSegmentsCommand(os.Args[2:], *cfg.Analysis, profile))
+
+		case "compare":
+			// Сравнение двух алгоритмов анализа поворотов на папках компасов
+			os.Exit(runCompareCommand(os.Args[2:], *cfg.Analysis, profile))
+
+		case "deviation":
+			// Кривая девиации: «Азимут RAW» против компенсированного азимута прибора
+			os.Exit(runDeviationCommand(os.Args[2:], *cfg.Analysis, profile))
+
+		case "corpus":
+			// Регрессионная проверка на размеченном наборе компасов
+			os.Exit(runCorpusCommand(os.Args[2:], *cfg.Analysis, profile))
+
+		case "tune":
+			// Подбор порогов анализа по размеченному набору компасов
+			os.Exit(runTuneCommand(os.Args[2:], cfg, profile))
+		}
 	}
 
 	for {
@@ -531,7 +634,7 @@ func main() {
 				fmt.Println("Сначала настройте пути к директориям!")
 				continue
 			}
-			results := runSession(cfg.DataDir, cfg.SuccessDir, cfg.FailureDir)
+			results := runSession(cfg.DataDir, cfg.SuccessDir, cfg.FailureDir, *cfg.Analysis, profile)
 			showResults(results)
 
 			// После показа результатов предлагаем продолжить
@@ -583,17 +686,17 @@ func main() {
 
 		case "4":
 			fmt.Println("\n📊 Запуск TUI (Terminal User Interface)...")
-			StartTUI()
+			StartTUI(*cfg.Analysis, profile)
 
 		case "5":
 			fmt.Println("\n🖥️  Запуск GUI приложения...")
 			fmt.Println("⚠️  Требуется GCC компилятор для Fyne!")
-			gui.CreateDesktopApp()
+			gui.CreateDesktopApp(*cfg.Analysis, profile)
 
 		case "6":
 			fmt.Println("\n🌐 Запуск веб-интерфейса...")
 			fmt.Println("Для остановки нажмите Ctrl+C")
-			webui.StartWebUI("8080")
+			webui.StartWebUI("8080", *cfg.Analysis, profile)
 
 		case "7":
 			fmt.Println("\nСпасибо за использование программы!")
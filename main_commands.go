@@ -0,0 +1,1046 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"compass_analyzer/analyzer"
+	"compass_analyzer/corpus"
+	"compass_analyzer/models"
+	"compass_analyzer/parser"
+)
+
+// Команды командной строки (запуск без интерактивного меню)
+
+// runNormalizeCommand сохраняет упорядоченную по времени копию файла SB_CMPS.csv.
+// Использование: compass_analyzer normalize <исходный.csv> [новый.csv]
+// Исходный файл не изменяется, копия получает тот же заголовок.
+func runNormalizeCommand(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("Использование: compass_analyzer normalize <исходный.csv> [новый.csv]")
+		return 2
+	}
+
+	srcPath := args[0]
+	dstPath := ""
+	if len(args) == 2 {
+		dstPath = args[1]
+	}
+
+	written, diagnostics, err := parser.NormalizeCSVFile(srcPath, dstPath)
+	for _, d := range diagnostics {
+		fmt.Printf("⚠ %s\n", d)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Ошибка нормализации: %v\n", err)
+		return 1
+	}
+
+	if len(diagnostics) == 0 {
+		fmt.Println("ℹ Записи в исходном файле уже упорядочены по времени")
+	}
+	fmt.Printf("✅ Упорядоченная копия сохранена: %s\n", written)
+	return 0
+}
+
+// windowEntry - окно калибровки одного компаса в отчете window
+type windowEntry struct {
+	Folder string                      `json:"folder"`
+	Reason models.ReasonCode           `json:"reason,omitempty"`
+	Error  string                      `json:"error,omitempty"`
+	Window *analyzer.CalibrationWindow `json:"window,omitempty"`
+	Saved  string                      `json:"saved,omitempty"` // Сохраненный файл окна (флаг -save)
+}
+
+// runWindowCommand находит окно калибровки (analyzer.FindCalibrationWindow) -
+// записи, которые раньше вырезались из SB_CMPS.csv вручную, - и выводит его
+// по каждому компасу. С флагом -save рядом с исходным файлом сохраняется
+// копия только с записями окна (parser.CutFileName); существующий файл
+// не перезаписывается. С флагом -json окна выводятся в формате JSON.
+// Код возврата 1 - файл не прочитан или копия не сохранена.
+// Использование: compass_analyzer window [-save] [-json] <папка>...
+func runWindowCommand(args []string) int {
+	fs := flag.NewFlagSet("window", flag.ContinueOnError)
+	save := fs.Bool("save", false, "сохранить копию файла с записями окна рядом с исходным")
+	asJSON := fs.Bool("json", false, "выводить результаты в формате JSON")
+	fs.Usage = func() {
+		fmt.Println("Использование: compass_analyzer window [-save] [-json] <папка>...")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var report []windowEntry
+	failed := 0
+	for _, folder := range expandCompassFolders(fs.Args()) {
+		entry := windowEntry{Folder: folder}
+		csvPath := filepath.Join(folder, "SB_CMPS.csv")
+		var records []models.TelemetryRecord
+		var err error
+		if _, err = os.Stat(csvPath); os.IsNotExist(err) {
+			entry.Reason = models.ReasonFileMissing
+			entry.Error = entry.Reason.Format(csvPath)
+		} else if records, err = parser.ReadTelemetry(csvPath); err != nil {
+			reason, detail := parser.ReadErrorReason(err)
+			entry.Reason = reason
+			entry.Error = reason.Format(csvPath, detail)
+		} else {
+			entry.Window = analyzer.FindCalibrationWindow(records, parser.ReadJournalFor(csvPath))
+		}
+		if w := entry.Window; w != nil && *save {
+			entry.Saved, _, err = parser.CutCSVFile(csvPath, "", records[w.From].Time, records[w.To].Time)
+			if err != nil {
+				entry.Error = err.Error()
+			}
+		}
+		if entry.Error != "" {
+			failed++
+		}
+		report = append(report, entry)
+		if *asJSON {
+			continue
+		}
+
+		name := filepath.Base(folder)
+		switch {
+		case entry.Reason != "":
+			fmt.Printf("❌ %s: [%s] %s\n", name, entry.Reason, entry.Error)
+		case entry.Window == nil:
+			fmt.Printf("ℹ %s: окно калибровки не найдено - файл анализируется целиком\n", name)
+		default:
+			fmt.Printf("✅ %s: %s\n", name, entry.Window)
+			if entry.Saved != "" {
+				fmt.Printf("   сохранено: %s\n", entry.Saved)
+			}
+			if entry.Error != "" {
+				fmt.Printf("   ❌ %s\n", entry.Error)
+			}
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Ошибка вывода JSON: %v\n", err)
+			return 1
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runAnalyzeCommand анализирует папки компасов по выбранному профилю процедуры
+// и выводит вердикт по каждой и сводку брака по причинам. Папки не перемещаются,
+// подробный лог пишется в стандартный вывод при флаге -v. С флагом -json вместо
+// текста выводятся полные результаты анализа (analyzer.AnalysisResult) в формате JSON.
+// Флаг -reason оставляет только брак с указанными кодами причин (models.ReasonCode),
+// флаг -segmenter заменяет алгоритм поиска сегментов профиля (см. analyzer.Segmenter),
+// флаг -cycles - режим вердикта при нескольких циклах поворотов (models.CycleVerdictBest и т.д.),
+// флаг -column - столбец азимута для поиска поворотов (models.AzimuthColumnRaw и т.д.),
+// флаг -window - поиск окна калибровки (models.CalibrationWindowAuto и т.д.).
+// Вместо папок компасов можно указать папку, которая их содержит (например «Брак»).
+// Использование: compass_analyzer analyze [-profile имя] [-segmenter алгоритм] [-cycles режим] [-column столбец] [-window режим] [-reason коды] [-v] [-json] <папка>...
+func runAnalyzeCommand(args []string, params models.AnalysisParams, profile models.ProcedureProfile) int {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	profileName := fs.String("profile", profile.Name, "профиль процедуры калибровки")
+	reasonList := fs.String("reason", "", "показывать только брак с указанными кодами причин (через запятую)")
+	verbose := fs.Bool("v", false, "выводить подробный лог анализа")
+	asJSON := fs.Bool("json", false, "выводить результаты в формате JSON")
+	segmenterName := fs.String("segmenter", "", "алгоритм поиска сегментов (по умолчанию - из профиля)")
+	cycleVerdict := fs.String("cycles", params.CycleVerdict, "вердикт при нескольких циклах: best, all или majority")
+	column := fs.String("column", params.AzimuthColumn, "столбец азимута для поиска поворотов: raw или compensated")
+	window := fs.String("window", params.CalibrationWindow, "окно калибровки: auto - только записи после калибровки, off - весь файл")
+	fs.Usage = func() {
+		fmt.Println("Использование: compass_analyzer analyze [-profile имя] [-segmenter алгоритм] [-cycles режим] [-column столбец] [-window режим] [-reason коды] [-v] [-json] <папка>...")
+		fmt.Println("Профили процедуры:")
+		for _, p := range models.Profiles() {
+			fmt.Printf("  %-10s %s\n", p.Name, p.Description)
+		}
+		fmt.Println("Коды причин отбраковки:")
+		for _, code := range models.ReasonCodes() {
+			fmt.Printf("  %-18s %s\n", code, code.Title())
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	selected, err := models.FindProfile(*profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	if *segmenterName != "" {
+		segmenter, err := analyzer.FindSegmenter(*segmenterName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 2
+		}
+		selected.Segmenter = segmenter.Name()
+	}
+	params.CycleVerdict = *cycleVerdict
+	params.AzimuthColumn = *column
+	params.CalibrationWindow = *window
+	if err := params.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	reasons, err := parseReasonFilter(*reasonList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	if !*asJSON {
+		fmt.Printf("Профиль процедуры: %s\n", selected)
+		fmt.Printf("Алгоритм поиска сегментов: %s\n", selected.SegmenterName())
+		fmt.Printf("Параметры анализа: %s\n\n", selected.DescribeParams(params))
+	}
+
+	var logFile *os.File
+	if *verbose && !*asJSON {
+		logFile = os.Stdout
+	}
+
+	// folderResult - результат анализа папки для вывода в JSON
+	type folderResult struct {
+		Folder string                   `json:"folder"`
+		Reason models.ReasonCode        `json:"reason,omitempty"`
+		Error  string                   `json:"error,omitempty"`
+		Result *analyzer.AnalysisResult `json:"result,omitempty"`
+	}
+	var report []folderResult
+	failedCompasses := make(map[string]models.CompassResult)
+
+	for _, folder := range expandCompassFolders(fs.Args()) {
+		name := filepath.Base(folder)
+		entry := folderResult{Folder: folder}
+
+		csvPath := filepath.Join(folder, "SB_CMPS.csv")
+		var result analyzer.AnalysisResult
+		if _, err := os.Stat(csvPath); os.IsNotExist(err) {
+			entry.Reason = models.ReasonFileMissing
+			entry.Error = entry.Reason.Format(csvPath)
+		} else if records, err := parser.ReadTelemetry(csvPath); err != nil {
+			reason, detail := parser.ReadErrorReason(err)
+			entry.Reason = reason
+			entry.Error = reason.Format(csvPath, detail)
+		} else {
+			result = analyzer.AnalyzeTelemetryWithJournal(records, parser.ReadJournalFor(csvPath), params, selected, logFile)
+			entry.Reason = result.Reason()
+			entry.Result = &result
+		}
+
+		if len(reasons) > 0 && !reasons[entry.Reason] {
+			continue
+		}
+		report = append(report, entry)
+		if entry.Result == nil || !result.IsValid {
+			failedCompasses[folder] = models.CompassResult{CompassNumber: name, Reason: entry.Reason}
+		}
+		if *asJSON {
+			continue
+		}
+
+		switch {
+		case entry.Result == nil:
+			fmt.Printf("❌ %s: [%s] %s\n", name, entry.Reason, entry.Error)
+		case result.IsValid:
+			fmt.Printf("✅ %s: успешно (%d/%d поворотов, качество %.0f)\n", name, len(result.Turns), selected.StepCount, result.Quality)
+			printCalibrationWindow(result.Window)
+			if result.Closure != nil {
+				fmt.Printf("   возврат к начальному курсу %+.2f°, повторяемость %.2f°\n", result.Closure.Error, result.Closure.Repeatability)
+			}
+			for _, p := range result.Positions {
+				fmt.Printf("   позиция %d (+%.0f°): размах между циклами %.2f°", p.Position, p.Nominal, p.Repeatability)
+				if p.Hysteresis != nil {
+					fmt.Printf(", гистерезис %+.2f°", *p.Hysteresis)
+				}
+				fmt.Println()
+			}
+			if d := result.Drift; d != nil && d.Estimated {
+				fmt.Printf("   дрейф курса %+.3f °/°C (%s, изменение температуры в позиции до %.1f °C)\n", d.Coefficient, d.Sensor, d.Span)
+			}
+			for _, name := range []string{models.CheckTiltLevel, models.CheckTiltChange, models.CheckDeviceDone} {
+				// Нарушение наклона (tilt_advisory в профиле) или незавершенная калибровка
+				// по флагам прибора у годного компаса - предупреждение
+				if check, ok := result.Check(name); ok && !check.Passed {
+					fmt.Printf("   ⚠ [%s] %s\n", check.Reason, check.Message)
+				}
+			}
+			if result.NeedsRetest() {
+				fmt.Printf("   ⚠ оценка качества ниже %.0f - рекомендуется перепроверка\n", params.RetestQuality)
+			}
+		default:
+			fmt.Printf("❌ %s: брак (%d/%d поворотов, качество %.0f)\n", name, len(result.Turns), selected.StepCount, result.Quality)
+			printCalibrationWindow(result.Window)
+			for _, check := range result.FailedChecks() {
+				fmt.Printf("   [%s] %s\n", check.Reason, check.Message)
+			}
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Ошибка вывода JSON: %v\n", err)
+			return 1
+		}
+	} else if len(failedCompasses) > 0 {
+		fmt.Printf("\nПричины отбраковки (%d из %d):\n", len(failedCompasses), len(report))
+		for _, rc := range models.CountReasons(failedCompasses) {
+			fmt.Printf("  %-18s %-36s %d\n", rc.Reason, rc.Reason.Title(), rc.Count)
+		}
+	}
+
+	if len(failedCompasses) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runSegmentsCommand сравнивает алгоритмы поиска стабильных сегментов
+// (analyzer.Segmenters) на одном файле: для каждого алгоритма выводит
+// найденные сегменты, выбранные повороты, вердикт и оценку качества.
+// Флаг -only оставляет указанные алгоритмы (через запятую).
+// Использование: compass_analyzer segments [-profile имя] [-only алгоритмы] <папка или SB_CMPS.csv>
+func runSegmentsCommand(args []string, params models.AnalysisParams, profile models.ProcedureProfile) int {
+	fs := flag.NewFlagSet("segments", flag.ContinueOnError)
+	profileName := fs.String("profile", profile.Name, "профиль процедуры калибровки")
+	only := fs.String("only", "", "сравнивать только указанные алгоритмы (через запятую)")
+	fs.Usage = func() {
+		fmt.Println("Использование: compass_analyzer segments [-profile имя] [-only алгоритмы] <папка или SB_CMPS.csv>")
+		fmt.Printf("Алгоритмы поиска сегментов: %s\n", strings.Join(models.SegmenterNames(), ", "))
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	selected, err := models.FindProfile(*profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	segmenters := analyzer.Segmenters()
+	if *only != "" {
+		segmenters = nil
+		for _, name := range strings.Split(*only, ",") {
+			s, err := analyzer.FindSegmenter(strings.TrimSpace(name))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+				return 2
+			}
+			segmenters = append(segmenters, s)
+		}
+	}
+
+	csvPath := fs.Arg(0)
+	if info, err := os.Stat(csvPath); err == nil && info.IsDir() {
+		csvPath = filepath.Join(csvPath, "SB_CMPS.csv")
+	}
+	records, err := parser.ReadTelemetry(csvPath)
+	if err != nil {
+		reason, detail := parser.ReadErrorReason(err)
+		fmt.Fprintf(os.Stderr, "❌ [%s] %s\n", reason, reason.Format(csvPath, detail))
+		return 1
+	}
+	records, window := analyzer.CalibrationRecords(records, parser.ReadJournalFor(csvPath), params)
+	data := make([]models.CompassData, len(records))
+	for i, r := range records {
+		data[i] = r.CompassDataFor(params.AzimuthColumn)
+	}
+
+	// Индексы сегментов относятся к ряду после предварительной обработки
+	filtered, _ := analyzer.Preprocess(data, params.Preprocess, nil)
+
+	fmt.Printf("Файл: %s (%d записей)\n", csvPath, len(data))
+	if window != nil {
+		fmt.Printf("Окно калибровки: %s\n", window)
+	}
+	fmt.Printf("Профиль процедуры: %s\n", selected)
+	fmt.Printf("Параметры анализа: %s\n", selected.DescribeParams(params))
+
+	for _, segmenter := range segmenters {
+		p := selected
+		p.Segmenter = segmenter.Name()
+		result := analyzer.AnalyzeCompassData(data, params, p, nil)
+
+		fmt.Printf("\n=== %s: %d сегментов, %d/%d поворотов ===\n",
+			segmenter.Name(), len(result.Segments), len(result.Turns), selected.StepCount)
+		for i, seg := range result.Segments {
+			fmt.Printf("  %2d. [%d:%d] %s – %s  %.1f°  σ=%.2f°  MAD=%.2f°\n", i+1,
+				seg.StartIndex, seg.EndIndex, filtered[seg.StartIndex].TimeString, filtered[seg.EndIndex].TimeString,
+				seg.AvgAngle, seg.StdDev, seg.MAD)
+		}
+		for i, turn := range result.Turns {
+			fmt.Printf("  Поворот %d: сегменты %d → %d, %.1f° → %.1f° (Δ = %.1f°, уверенность %.0f)\n",
+				i+1, turn.FromSegment+1, turn.ToSegment+1, turn.StartAngle, turn.EndAngle, turn.Diff, turn.Confidence)
+		}
+		if result.IsValid {
+			fmt.Printf("  ✅ успешно, качество %.0f\n", result.Quality)
+		} else {
+			fmt.Printf("  ❌ брак [%s], качество %.0f\n", result.Reason(), result.Quality)
+		}
+	}
+	return 0
+}
+
+// compareSide - результат одного алгоритма анализа по компасу в отчете compare
+type compareSide struct {
+	Valid      bool              `json:"valid"`
+	Reason     models.ReasonCode `json:"reason,omitempty"`
+	Turns      []float64         `json:"turns"`      // Углы выбранных поворотов, градусы
+	Candidates int               `json:"candidates"` // Все найденные повороты на угол шага
+	Message    string            `json:"message,omitempty"`
+}
+
+// compareEntry - сравнение двух алгоритмов анализа по одному компасу
+type compareEntry struct {
+	Folder         string      `json:"folder"`
+	Error          string      `json:"error,omitempty"`
+	A              compareSide `json:"a"`
+	B              compareSide `json:"b"`
+	VerdictChanged bool        `json:"verdictChanged"`
+	ReasonChanged  bool        `json:"reasonChanged"`
+	TurnsChanged   bool        `json:"turnsChanged"`
+}
+
+// Differs сообщает, что алгоритмы разошлись по вердикту, причине или поворотам
+func (e compareEntry) Differs() bool {
+	return e.VerdictChanged || e.ReasonChanged || e.TurnsChanged
+}
+
+// newCompareSide собирает результат алгоритма для отчета compare
+func newCompareSide(result analyzer.AnalysisResult) compareSide {
+	side := compareSide{Valid: result.IsValid, Reason: result.Reason(), Turns: []float64{}, Candidates: len(result.Candidates)}
+	for _, turn := range result.Turns {
+		side.Turns = append(side.Turns, turn.Diff)
+	}
+	if failed := result.FailedChecks(); len(failed) > 0 {
+		side.Message = failed[0].Message
+	}
+	return side
+}
+
+// turnsDiffer сообщает, что наборы поворотов различаются по количеству
+// или по углу хотя бы одного поворота больше чем на 1°
+func turnsDiffer(a, b []float64) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCompareSide возвращает результат алгоритма одной строкой
+func formatCompareSide(name string, side compareSide) string {
+	turns := make([]string, len(side.Turns))
+	for i, t := range side.Turns {
+		turns[i] = fmt.Sprintf("%.1f°", t)
+	}
+	verdict := "✅ годен"
+	if !side.Valid {
+		verdict = fmt.Sprintf("❌ брак [%s]", side.Reason)
+	}
+	if len(turns) == 0 {
+		return fmt.Sprintf("%-8s %s, поворотов нет (кандидатов %d)", name, verdict, side.Candidates)
+	}
+	return fmt.Sprintf("%-8s %s, поворотов %d: %s", name, verdict, len(side.Turns), strings.Join(turns, " "))
+}
+
+// runCompareCommand сравнивает два алгоритма анализа поворотов
+// (analyzer.Strategies, по умолчанию текущий и прежний детектор) на папках
+// компасов и выводит отчет о расхождениях: вердикты, выбранные повороты и
+// причины отбраковки по каждому компасу, сводку вердиктов и переходы причин.
+// Проверка поля магнитометра от алгоритма не зависит и не выполняется.
+// Флаг -all выводит и компасы без расхождений.
+// Использование: compass_analyzer compare [-a алгоритм] [-b алгоритм] [-profile имя] [-all] [-json] <папка>...
+func runCompareCommand(args []string, params models.AnalysisParams, profile models.ProcedureProfile) int {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	nameA := fs.String("a", analyzer.StrategyCurrent, "первый алгоритм анализа")
+	nameB := fs.String("b", analyzer.StrategyLegacy, "второй алгоритм анализа")
+	profileName := fs.String("profile", profile.Name, "профиль процедуры калибровки")
+	all := fs.Bool("all", false, "выводить и компасы без расхождений")
+	asJSON := fs.Bool("json", false, "выводить результаты в формате JSON")
+	fs.Usage = func() {
+		fmt.Println("Использование: compass_analyzer compare [-a алгоритм] [-b алгоритм] [-profile имя] [-all] [-json] <папка>...")
+		fmt.Println("Алгоритмы анализа:")
+		for _, s := range analyzer.Strategies() {
+			fmt.Printf("  %-8s %s\n", s.Name(), s.Description())
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	selected, err := models.FindProfile(*profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	strategyA, err := analyzer.FindStrategy(*nameA)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	strategyB, err := analyzer.FindStrategy(*nameB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+
+	if !*asJSON {
+		fmt.Printf("Профиль процедуры: %s\n", selected)
+		fmt.Printf("A: %s - %s\n", strategyA.Name(), strategyA.Description())
+		fmt.Printf("B: %s - %s\n\n", strategyB.Name(), strategyB.Description())
+	}
+
+	var report []compareEntry
+	var bothValid, bothFailed, onlyA, onlyB, unreadable int
+	transitions := make(map[string]int)
+	for _, folder := range expandCompassFolders(fs.Args()) {
+		entry := compareEntry{Folder: folder}
+		csvPath := filepath.Join(folder, "SB_CMPS.csv")
+		records, err := parser.ReadTelemetry(csvPath)
+		if err != nil {
+			reason, detail := parser.ReadErrorReason(err)
+			entry.Error = reason.Format(csvPath, detail)
+			unreadable++
+			report = append(report, entry)
+			if !*asJSON {
+				fmt.Printf("⚠ %s: [%s] %s\n", filepath.Base(folder), reason, entry.Error)
+			}
+			continue
+		}
+		records, _ = analyzer.CalibrationRecords(records, parser.ReadJournalFor(csvPath), params)
+		data := make([]models.CompassData, len(records))
+		for i, r := range records {
+			data[i] = r.CompassDataFor(params.AzimuthColumn)
+		}
+
+		entry.A = newCompareSide(strategyA.Analyze(data, params, selected, nil))
+		entry.B = newCompareSide(strategyB.Analyze(data, params, selected, nil))
+		entry.VerdictChanged = entry.A.Valid != entry.B.Valid
+		entry.ReasonChanged = entry.A.Reason != entry.B.Reason
+		entry.TurnsChanged = turnsDiffer(entry.A.Turns, entry.B.Turns)
+		report = append(report, entry)
+
+		switch {
+		case entry.A.Valid && entry.B.Valid:
+			bothValid++
+		case entry.A.Valid:
+			onlyA++
+		case entry.B.Valid:
+			onlyB++
+		default:
+			bothFailed++
+		}
+		if entry.ReasonChanged {
+			transitions[fmt.Sprintf("%s → %s", reasonOrValid(entry.A.Reason), reasonOrValid(entry.B.Reason))]++
+		}
+
+		if *asJSON || (!*all && !entry.Differs()) {
+			continue
+		}
+		mark := "="
+		if entry.VerdictChanged {
+			mark = "≠"
+		} else if entry.Differs() {
+			mark = "~"
+		}
+		fmt.Printf("%s %s\n", mark, filepath.Base(folder))
+		fmt.Printf("    A %s\n", formatCompareSide(strategyA.Name(), entry.A))
+		fmt.Printf("    B %s\n", formatCompareSide(strategyB.Name(), entry.B))
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Ошибка вывода JSON: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	compared := bothValid + bothFailed + onlyA + onlyB
+	fmt.Printf("\nСравнено компасов: %d (не прочитано: %d)\n", compared, unreadable)
+	fmt.Printf("  годен по обоим:            %d\n", bothValid)
+	fmt.Printf("  брак по обоим:             %d\n", bothFailed)
+	fmt.Printf("  годен только по A (%s): %d\n", strategyA.Name(), onlyA)
+	fmt.Printf("  годен только по B (%s): %d\n", strategyB.Name(), onlyB)
+	if compared > 0 {
+		fmt.Printf("  совпадение вердиктов:      %.1f%%\n", float64(bothValid+bothFailed)/float64(compared)*100)
+	}
+	if len(transitions) > 0 {
+		keys := make([]string, 0, len(transitions))
+		for k := range transitions {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if transitions[keys[i]] != transitions[keys[j]] {
+				return transitions[keys[i]] > transitions[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		fmt.Printf("\nСмена причины (A → B):\n")
+		for _, k := range keys {
+			fmt.Printf("  %-36s %d\n", k, transitions[k])
+		}
+	}
+	return 0
+}
+
+// reasonOrValid возвращает код причины или «годен» для годного компаса
+func reasonOrValid(reason models.ReasonCode) string {
+	if reason == "" {
+		return "годен"
+	}
+	return string(reason)
+}
+
+// deviationEntry - кривая девиации одного компаса в отчете deviation
+type deviationEntry struct {
+	Folder    string              `json:"folder"`
+	Error     string              `json:"error,omitempty"`
+	Valid     bool                `json:"valid"`
+	Reason    models.ReasonCode   `json:"reason,omitempty"`
+	Deviation *analyzer.Deviation `json:"deviation,omitempty"`
+}
+
+// runDeviationCommand сравнивает «Азимут RAW» с компенсированным азимутом
+// прибора в каждой позиции цикла поворотов и выводит кривую девиации
+// (ошибка курса в зависимости от курса), наибольшую и среднеквадратичную
+// девиацию по каждому компасу и по всем компасам. Флаг -column задает
+// столбец, по которому ищутся повороты: с -column compensated весь анализ
+// выполняется по компенсированному азимуту, чтобы проверить, что коррекция
+// прибора дает правильные повороты.
+// Использование: compass_analyzer deviation [-profile имя] [-column столбец] [-v] [-json] <папка>...
+func runDeviationCommand(args []string, params models.AnalysisParams, profile models.ProcedureProfile) int {
+	fs := flag.NewFlagSet("deviation", flag.ContinueOnError)
+	profileName := fs.String("profile", profile.Name, "профиль процедуры калибровки")
+	column := fs.String("column", params.AzimuthColumn, "столбец азимута для поиска поворотов: raw или compensated")
+	verbose := fs.Bool("v", false, "выводить подробный лог анализа")
+	asJSON := fs.Bool("json", false, "выводить результаты в формате JSON")
+	fs.Usage = func() {
+		fmt.Println("Использование: compass_analyzer deviation [-profile имя] [-column столбец] [-v] [-json] <папка>...")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	selected, err := models.FindProfile(*profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	params.AzimuthColumn = *column
+	if err := params.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	var logFile *os.File
+	if *verbose && !*asJSON {
+		logFile = os.Stdout
+	}
+	if !*asJSON {
+		fmt.Printf("Профиль процедуры: %s\n", selected)
+		fmt.Printf("Параметры анализа: %s\n\n", selected.DescribeParams(params))
+	}
+
+	var report []deviationEntry
+	var measured, valid int
+	maxDeviation, sumSquares := 0.0, 0.0
+	for _, folder := range expandCompassFolders(fs.Args()) {
+		entry := deviationEntry{Folder: folder}
+		csvPath := filepath.Join(folder, "SB_CMPS.csv")
+		records, err := parser.ReadTelemetry(csvPath)
+		if err != nil {
+			reason, detail := parser.ReadErrorReason(err)
+			entry.Reason = reason
+			entry.Error = reason.Format(csvPath, detail)
+		} else {
+			result := analyzer.AnalyzeTelemetryWithJournal(records, parser.ReadJournalFor(csvPath), params, selected, logFile)
+			entry.Valid = result.IsValid
+			entry.Reason = result.Reason()
+			entry.Deviation = result.Deviation
+		}
+		report = append(report, entry)
+		if entry.Valid {
+			valid++
+		}
+		if d := entry.Deviation; d != nil {
+			measured++
+			maxDeviation = math.Max(maxDeviation, d.Max)
+			sumSquares += d.RMS * d.RMS
+		}
+		if *asJSON {
+			continue
+		}
+
+		name := filepath.Base(folder)
+		switch {
+		case entry.Error != "":
+			fmt.Printf("❌ %s: [%s] %s\n", name, entry.Reason, entry.Error)
+			continue
+		case entry.Deviation == nil:
+			fmt.Printf("⚠ %s: нет стабильных курсов - кривая девиации не построена (%s)\n", name, reasonOrValid(entry.Reason))
+			continue
+		}
+		fmt.Printf("%s: девиация наибольшая %.2f°, СКО %.2f° (%s)\n",
+			name, entry.Deviation.Max, entry.Deviation.RMS, reasonOrValid(entry.Reason))
+		for _, p := range entry.Deviation.Points {
+			fmt.Printf("   курс %7.2f° → %7.2f°  девиация %+6.2f°  (позиция %d, записей %d)\n",
+				p.Heading, p.Compensated, p.Error, p.Position, p.Samples)
+		}
+		if entry.Deviation.Gross > 0 {
+			fmt.Printf("   ⚠ записей с расхождением больше 90°: %d\n", entry.Deviation.Gross)
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Ошибка вывода JSON: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Printf("\nКомпасов: %d, годных: %d, с кривой девиации: %d\n", len(report), valid, measured)
+	if measured > 0 {
+		fmt.Printf("Девиация по всем компасам: наибольшая %.2f°, СКО %.2f°\n", maxDeviation, math.Sqrt(sumSquares/float64(measured)))
+	}
+	return 0
+}
+
+// runCorpusCommand прогоняет анализ по размеченному набору компасов
+// (corpus.Manifest) и выводит матрицу ошибок против меток оператора,
+// расхождения с оператором и компасы, вердикт которых изменился относительно
+// принятого в манифесте. Флаг -init создает манифест по папкам «Успешно»,
+// «Успех» и «Брак» в указанной папке, флаг -accept записывает текущие
+// вердикты в манифест как принятые (после проверенного изменения алгоритма).
+// Профиль берется из манифеста, если не указан флаг -profile.
+// Код возврата 1 - изменился вердикт хотя бы одного компаса.
+// Использование: compass_analyzer corpus [-manifest файл] [-init папка] [-accept] [-profile имя] [-v] [-json]
+func runCorpusCommand(args []string, params models.AnalysisParams, profile models.ProcedureProfile) int {
+	fs := flag.NewFlagSet("corpus", flag.ContinueOnError)
+	manifestPath := fs.String("manifest", filepath.Join("corpus", "manifest.json"), "файл манифеста набора")
+	initRoot := fs.String("init", "", "создать манифест по размеченным папкам в указанной папке")
+	accept := fs.Bool("accept", false, "записать текущие вердикты в манифест как принятые")
+	profileName := fs.String("profile", "", "профиль процедуры (по умолчанию - из манифеста)")
+	verbose := fs.Bool("v", false, "выводить подробный лог анализа")
+	asJSON := fs.Bool("json", false, "выводить результаты в формате JSON")
+	fs.Usage = func() {
+		fmt.Println("Использование: compass_analyzer corpus [-manifest файл] [-init папка] [-accept] [-profile имя] [-v] [-json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var manifest *corpus.Manifest
+	var err error
+	if *initRoot != "" {
+		manifest, err = corpus.ScanLabeled(*initRoot, *manifestPath, profile.Name)
+		*accept = true
+	} else {
+		manifest, err = corpus.LoadManifest(*manifestPath)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+
+	selected := profile
+	if name := *profileName; name != "" || manifest.Profile != "" {
+		if name == "" {
+			name = manifest.Profile
+		}
+		if selected, err = models.FindProfile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 2
+		}
+	}
+
+	var logFile *os.File
+	if *verbose && !*asJSON {
+		logFile = os.Stdout
+	}
+	report := corpus.Run(manifest, params, selected, logFile)
+	if *initRoot != "" {
+		// У нового манифеста нет принятых вердиктов - сравнивать не с чем
+		manifest.Accept(&report)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Ошибка вывода JSON: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Printf("Манифест: %s\n", *manifestPath)
+		report.Print(os.Stdout)
+	}
+
+	if *accept {
+		manifest.Accept(&report)
+		if err := manifest.Save(*manifestPath); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if !*asJSON {
+			fmt.Printf("\n✓ Текущие вердикты записаны в %s\n", *manifestPath)
+		}
+		return 0
+	}
+	if report.Regressed() {
+		return 1
+	}
+	return 0
+}
+
+// runTuneCommand подбирает пороги анализа (порог стабильности, допуски
+// поворота, суммы и непрерывности, минимальную длину сегмента) по меткам
+// оператора размеченного набора (corpus.Tune) и выводит Парето-фронт долей
+// принятого брака и забракованных годных компасов. Флаг -save сохраняет
+// выбранный набор фронта (-pick номер, по умолчанию - с наименьшей суммой
+// ошибок) как собственный профиль процедуры в файле конфигурации.
+// Использование: compass_analyzer tune [-manifest файл] [-profile имя] [-random N] [-seed N]
+//
+//	[-stability мин:макс:шаг] [-turn ...] [-min-len ...] [-sum ...] [-continuity ...] [-pick номер] [-save имя] [-json]
+func runTuneCommand(args []string, cfg *Config, profile models.ProcedureProfile) int {
+	fs := flag.NewFlagSet("tune", flag.ContinueOnError)
+	manifestPath := fs.String("manifest", filepath.Join("corpus", "manifest.json"), "файл манифеста размеченного набора")
+	profileName := fs.String("profile", "", "профиль процедуры (по умолчанию - из манифеста)")
+	samples := fs.Int("random", 0, "проверить N случайных точек сетки вместо полного перебора")
+	seed := fs.Int64("seed", 1, "начальное значение генератора для -random")
+	pick := fs.Int("pick", 0, "номер набора Парето-фронта для -save (0 - с наименьшей суммой ошибок)")
+	saveName := fs.String("save", "", "сохранить выбранный набор как профиль процедуры с этим именем")
+	asJSON := fs.Bool("json", false, "выводить результаты в формате JSON")
+	space := corpus.DefaultSpace()
+	ranges := map[string]*string{}
+	for _, name := range corpus.TuneNames() {
+		flagName := strings.ReplaceAll(name, "_", "-")
+		ranges[name] = fs.String(flagName, space[name].String(), "диапазон «мин:макс:шаг» или одно значение")
+	}
+	fs.Usage = func() {
+		fmt.Println("Использование: compass_analyzer tune [-manifest файл] [-profile имя] [-random N] [-pick номер] [-save имя] [-json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	for name, value := range ranges {
+		r, err := corpus.ParseRange(*value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ -%s: %v\n", strings.ReplaceAll(name, "_", "-"), err)
+			return 2
+		}
+		space[name] = r
+	}
+
+	manifest, err := corpus.LoadManifest(*manifestPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	selected := profile
+	if name := *profileName; name != "" || manifest.Profile != "" {
+		if name == "" {
+			name = manifest.Profile
+		}
+		if selected, err = models.FindProfile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 2
+		}
+	}
+
+	opts := corpus.TuneOptions{Space: space, Samples: *samples, Seed: *seed}
+	if !*asJSON {
+		fmt.Printf("Манифест: %s (%d компасов)\n", *manifestPath, len(manifest.Units))
+		fmt.Printf("Профиль процедуры: %s\n", selected)
+		total := space.Size()
+		if *samples > 0 && *samples < total {
+			total = *samples
+		}
+		fmt.Printf("Наборов параметров: %d\n", total)
+		opts.Progress = os.Stdout
+	}
+	// Замечания парсера о файлах без заголовка не нужны при подборе
+	log.SetOutput(io.Discard)
+	result, err := corpus.Tune(manifest, *cfg.Analysis, selected, opts)
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Ошибка вывода JSON: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Println()
+		result.Print(os.Stdout)
+	}
+
+	if *saveName == "" {
+		return 0
+	}
+	chosen, ok := result.Balanced()
+	if *pick > 0 {
+		if *pick > len(result.Front) {
+			fmt.Fprintf(os.Stderr, "❌ В Парето-фронте %d наборов, выбран %d\n", len(result.Front), *pick)
+			return 2
+		}
+		chosen, ok = result.Front[*pick-1], true
+	}
+	if !ok {
+		fmt.Fprintf(os.Stderr, "❌ Нет наборов параметров для сохранения\n")
+		return 1
+	}
+
+	tuned := chosen.Profile(selected, *saveName)
+	profiles := make([]models.ProcedureProfile, 0, len(cfg.Profiles)+1)
+	for _, p := range cfg.Profiles {
+		if !strings.EqualFold(p.Name, tuned.Name) {
+			profiles = append(profiles, p)
+		}
+	}
+	profiles = append(profiles, tuned)
+	if err := models.RegisterProfiles(profiles); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	cfg.Profiles = profiles
+	if err := saveConfig(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Ошибка сохранения конфигурации: %v\n", err)
+		return 1
+	}
+	if !*asJSON {
+		fmt.Printf("\n✓ Профиль «%s» сохранен в конфигурации: %s\n", tuned.Name, chosen.Point)
+		fmt.Printf("  Анализ с этим профилем: compass_analyzer analyze -profile %s <папка>\n", tuned.Name)
+	}
+	return 0
+}
+
+// printCalibrationWindow выводит окно калибровки, в котором искались повороты
+func printCalibrationWindow(window *analyzer.CalibrationWindow) {
+	if window == nil {
+		fmt.Println("   окно калибровки не найдено - анализировался весь файл")
+		return
+	}
+	fmt.Printf("   окно калибровки: %s\n", window)
+}
+
+// parseReasonFilter разбирает список кодов причин через запятую
+func parseReasonFilter(list string) (map[models.ReasonCode]bool, error) {
+	reasons := make(map[models.ReasonCode]bool)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		code := models.ReasonCode(item)
+		if !code.Known() {
+			return nil, fmt.Errorf("неизвестный код причины «%s»", item)
+		}
+		reasons[code] = true
+	}
+	return reasons, nil
+}
+
+// expandCompassFolders заменяет папку с папками компасов (например «Брак»)
+// списком ее подпапок; папки компасов остаются как есть. Папка без SB_CMPS.csv
+// разворачивается, только если хотя бы в одной подпапке есть SB_CMPS.csv или
+// подпапка названа номером компаса: иначе папка компаса без основного файла
+// (например, только с SB_CMPS_CALIB_CUTTED.csv и служебными подпапками h, x, y, z)
+// выдавалась бы за несколько компасов.
+func expandCompassFolders(folders []string) []string {
+	var result []string
+	for _, folder := range folders {
+		if _, err := os.Stat(filepath.Join(folder, "SB_CMPS.csv")); err == nil {
+			result = append(result, folder)
+			continue
+		}
+		entries, err := os.ReadDir(folder)
+		if err != nil {
+			result = append(result, folder)
+			continue
+		}
+		var subfolders []string
+		compasses := false
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			sub := filepath.Join(folder, e.Name())
+			subfolders = append(subfolders, sub)
+			if _, err := os.Stat(filepath.Join(sub, "SB_CMPS.csv")); err == nil || isCompassNumber(e.Name()) {
+				compasses = true
+			}
+		}
+		if compasses {
+			result = append(result, subfolders...)
+		} else {
+			result = append(result, folder)
+		}
+	}
+	return result
+}
+
+// isCompassNumber сообщает, что имя папки - номер компаса (только цифры)
+func isCompassNumber(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
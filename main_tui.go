@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"compass_analyzer/analyzer"
+	"compass_analyzer/models"
 	"compass_analyzer/parser"
 
 	"github.com/fatih/color"
@@ -60,7 +61,7 @@ func showProgressBar(current, total int) {
 	fmt.Printf("\r%s %3d%% (%d/%d)", cyan(bar), percent, current, total)
 }
 
-func runTUIAnalysis() {
+func runTUIAnalysis(params models.AnalysisParams, profile models.ProcedureProfile) {
 	clearScreen()
 	
 	cyan := color.New(color.FgCyan).SprintFunc()
@@ -113,6 +114,9 @@ func runTUIAnalysis() {
 		Name   string
 		Status string
 		Turns  int
+		Reason string
+		Quality float64
+		Window string
 	}, 0)
 	
 	// Анализ
@@ -130,31 +134,32 @@ func runTUIAnalysis() {
 				Name   string
 				Status string
 				Turns  int
-			}{folderName, "Нет файла", 0})
+				Reason string
+				Quality float64
+				Window string
+			}{folderName, "Нет файла", 0, fmt.Sprintf("[%s] %s", models.ReasonFileMissing, models.ReasonFileMissing.Format(csvPath)), 0, ""})
 			failCount++
 			continue
 		}
 		
-		data, err := parser.ReadCSVFile(csvPath)
+		records, err := parser.ReadTelemetry(csvPath)
 		if err != nil {
 			results = append(results, struct {
 				Name   string
 				Status string
 				Turns  int
-			}{folderName, "Ошибка чтения", 0})
+				Reason string
+				Quality float64
+				Window string
+			}{folderName, "Ошибка чтения", 0, tuiReadError(csvPath, err), 0, ""})
 			failCount++
 			continue
 		}
 		
-		angles := make([]float64, len(data))
-		for i, d := range data {
-			angles[i] = d.Angle
-		}
-		
-		isValid, turns := analyzer.AnalyzeCompassData(angles, nil)
+		analysis := analyzer.AnalyzeTelemetryWithJournal(records, parser.ReadJournalFor(csvPath), params, profile, nil)
 		
 		status := "Брак"
-		if isValid {
+		if analysis.IsValid {
 			status = "Успешно"
 			successCount++
 		} else {
@@ -165,7 +170,10 @@ func runTUIAnalysis() {
 			Name   string
 			Status string
 			Turns  int
-		}{folderName, status, len(turns)})
+			Reason string
+			Quality float64
+			Window string
+		}{folderName, status, len(analysis.Turns), tuiReason(analysis), analysis.Quality, tuiWindow(analysis)})
 		
 		time.Sleep(50 * time.Millisecond)
 	}
@@ -177,6 +185,8 @@ func runTUIAnalysis() {
 	drawBox("РЕЗУЛЬТАТЫ АНАЛИЗА", 70)
 	fmt.Println()
 	
+	fmt.Printf("%s: %s\n", cyan("Профиль процедуры"), profile)
+	fmt.Printf("%s: %s\n", cyan("Параметры анализа"), profile.DescribeParams(params))
 	fmt.Printf("%s: %d\n", cyan("Всего обработано"), totalCount)
 	fmt.Printf("%s: %d\n", green("✅ Успешно"), successCount)
 	fmt.Printf("%s: %d\n", red("❌ Брак"), failCount)
@@ -206,7 +216,7 @@ func runTUIAnalysis() {
 		}
 		statusCell = statusCell + strings.Repeat(" ", 12-len(result.Status))
 		
-		turnsCell := fmt.Sprintf("%d/4", result.Turns)
+		turnsCell := fmt.Sprintf("%d/%d", result.Turns, profile.StepCount)
 		turnsCell = turnsCell + strings.Repeat(" ", 9-len(turnsCell))
 		
 		fmt.Printf("%s %s %s %s %s %s %s\n",
@@ -232,11 +242,11 @@ func runTUIAnalysis() {
 	
 	switch choice {
 	case "1":
-		showDetailedLog(results)
+		showDetailedLog(results, profile.StepCount)
 	case "2":
 		fmt.Println(yellow("\n💾 Экспорт в разработке..."))
 	case "3":
-		runTUIAnalysis()
+		runTUIAnalysis(params, profile)
 		return
 	case "4":
 		return
@@ -250,7 +260,10 @@ func showDetailedLog(results []struct {
 	Name   string
 	Status string
 	Turns  int
-}) {
+	Reason string
+	Quality float64
+	Window string
+}, stepCount int) {
 	clearScreen()
 	cyan := color.New(color.FgCyan).SprintFunc()
 	
@@ -260,7 +273,16 @@ func showDetailedLog(results []struct {
 	for _, result := range results {
 		fmt.Printf("%s %s\n", cyan("━"), result.Name)
 		fmt.Printf("  Статус: %s\n", result.Status)
-		fmt.Printf("  Повороты: %d/4\n", result.Turns)
+		if result.Status == "Успешно" || result.Status == "Брак" {
+			fmt.Printf("  Оценка качества: %.0f/100\n", result.Quality)
+		}
+		fmt.Printf("  Повороты: %d/%d\n", result.Turns, stepCount)
+		if result.Window != "" {
+			fmt.Printf("  Окно калибровки: %s\n", result.Window)
+		}
+		if result.Reason != "" {
+			fmt.Printf("  Причина: %s\n", result.Reason)
+		}
 		fmt.Println()
 	}
 	
@@ -268,8 +290,33 @@ func showDetailedLog(results []struct {
 	bufio.NewScanner(os.Stdin).Scan()
 }
 
+// tuiReason возвращает код и описание причин отбраковки для детального лога
+func tuiReason(analysis analyzer.AnalysisResult) string {
+	if analysis.NeedsRetest() {
+		return fmt.Sprintf("оценка качества ниже %.0f - рекомендуется перепроверка", analysis.Params.RetestQuality)
+	}
+	if analysis.IsValid {
+		return ""
+	}
+	return fmt.Sprintf("[%s] %s", analysis.Reason(), strings.Join(analysis.Errors(), "; "))
+}
+
+// tuiWindow возвращает окно калибровки, в котором искались повороты, для детального лога
+func tuiWindow(analysis analyzer.AnalysisResult) string {
+	if analysis.Window == nil {
+		return "весь файл"
+	}
+	return analysis.Window.String()
+}
+
+// tuiReadError возвращает код и описание ошибки чтения файла данных
+func tuiReadError(csvPath string, err error) string {
+	reason, detail := parser.ReadErrorReason(err)
+	return fmt.Sprintf("[%s] %s", reason, reason.Format(csvPath, detail))
+}
+
 // StartTUI запускает Terminal User Interface
-func StartTUI() {
-	runTUIAnalysis()
+func StartTUI(params models.AnalysisParams, profile models.ProcedureProfile) {
+	runTUIAnalysis(params, profile)
 }
 
@@ -0,0 +1,73 @@
+package models
+
+import "fmt"
+
+// Имена проверок анализа (Check.Name)
+const (
+	CheckTurnCount     = "turn_count"     // Найдено не меньше StepCount поворотов
+	CheckDirection     = "direction"      // Все повороты в направлении профиля
+	CheckIndexOrder    = "index_order"    // Повороты идут по порядку без пересечения индексов
+	CheckContinuity    = "continuity"     // Конец поворота совпадает с началом следующего (не влияет на вердикт)
+	CheckSum           = "sum"            // Сумма поворотов близка к номинальной
+	CheckClosure       = "closure"        // Курс после полного оборота совпадает с начальным
+	CheckCycles        = "cycles"         // Достаточно циклов поворотов прошли проверки (режим AnalysisParams.CycleVerdict)
+	CheckDwell         = "dwell"          // Неподвижность на каждом курсе
+	CheckTurnDuration  = "turn_duration"  // Длительность каждого поворота
+	CheckTotalDuration = "total_duration" // Длительность всего цикла поворотов
+	CheckSoftIron      = "soft_iron"      // Искажение мягкого железа
+	CheckFitResidual   = "fit_residual"   // СКО точек магнитометра от эллипса
+	CheckOffset        = "offset"         // Совпадение центра эллипса со смещением прибора
+)
+
+// Имена проверок наклона стенда (только для телеметрии, пороги - в профиле процедуры)
+const (
+	CheckTiltLevel  = "tilt_level"  // Наклон в каждой позиции не больше ProcedureProfile.MaxTilt
+	CheckTiltChange = "tilt_change" // Дифферент и крен между позициями меняются не больше ProcedureProfile.MaxTiltChange
+)
+
+// Имена проверок состояний, о которых сообщает сам прибор (словарь флагов, см. Flags)
+const (
+	CheckDeviceError = "device_error" // Прибор не сообщает об ошибке
+	CheckDeviceDone  = "device_done"  // Прибор сообщил о завершении калибровки (не влияет на вердикт)
+)
+
+// Имя проверки температурного дрейфа курса (только для телеметрии)
+const CheckThermalDrift = "thermal_drift" // Дрейф курса в неподвижных позициях от температуры
+
+// Имена проверок исправности датчиков (выполняются до анализа поворотов)
+const (
+	CheckSensorStuck    = "sensor_stuck"    // Азимут меняется (датчик не завис)
+	CheckSensorDropout  = "sensor_dropout"  // Нет длинных серий строк с нулевыми показаниями
+	CheckSensorRepeated = "sensor_repeated" // Нет длинных серий одинаковых строк
+	CheckSensorJump     = "sensor_jump"     // Нет невозможных скачков показаний между строками
+	CheckSensorRange    = "sensor_range"    // Показания в физическом диапазоне столбца
+)
+
+// Check - результат одной проверки анализа
+type Check struct {
+	Name     string     `json:"name"`               // Имя проверки (CheckTurnCount, CheckSum, ...)
+	Passed   bool       `json:"passed"`             // Проверка пройдена
+	Measured float64    `json:"measured"`           // Измеренное значение
+	Limit    float64    `json:"limit"`              // Допустимое значение (граница)
+	Unit     string     `json:"unit,omitempty"`     // Единица измерения ("°", "с", "%", "шт")
+	Reason   ReasonCode `json:"reason,omitempty"`   // Код причины, если проверка не пройдена
+	Message  string     `json:"message"`            // Описание результата для оператора
+	Advisory bool       `json:"advisory,omitempty"` // Проверка не влияет на вердикт (предупреждение)
+}
+
+// Failed сообщает, что проверка не пройдена и влияет на вердикт
+func (c Check) Failed() bool {
+	return !c.Passed && !c.Advisory
+}
+
+// String возвращает проверку одной строкой для логов и отчетов
+func (c Check) String() string {
+	mark := "✓"
+	switch {
+	case c.Failed():
+		mark = "✗"
+	case !c.Passed:
+		mark = "⚠"
+	}
+	return fmt.Sprintf("%s %s: %.2f%s (граница %.2f%s) - %s", mark, c.Name, c.Measured, c.Unit, c.Limit, c.Unit, c.Message)
+}
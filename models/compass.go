@@ -24,6 +24,7 @@ type Turn struct {
 	EndIndex      int     `json:"endIndex"`      // Индекс конечного угла
 	FromSegment   int     `json:"-"`             // Номер начального сегмента (не экспортируется в JSON)
 	ToSegment     int     `json:"-"`             // Номер конечного сегмента (не экспортируется в JSON)
+	Confidence    float64 `json:"confidence"`    // Уверенность в повороте, 0–100
 }
 
 // CompassResult представляет результаты анализа одного компаса.
@@ -31,12 +32,24 @@ type Turn struct {
 type CompassResult struct {
 	// CompassNumber - уникальный номер компаса
 	CompassNumber string
+	// Profile - имя профиля процедуры, по которому выполнен анализ
+	Profile string
 	// IsValid - флаг, указывающий на успешность анализа
 	IsValid bool
 	// AllAngles - все углы, записанные в процессе измерения
 	AllAngles []float64
 	// Turns - найденные повороты
 	Turns []Turn
+	// Quality - оценка качества калибровки, 0–100
+	Quality float64
+	// NeedsRetest - компас годен, но оценка качества ниже порога перепроверки
+	NeedsRetest bool
+	// Reason - код первой причины отбраковки (пусто, если компас годен)
+	Reason ReasonCode
+	// Checks - результаты проверок анализа (пусто, если анализ не выполнялся)
+	Checks []Check
+	// Window - окно калибровки, в котором искались повороты (пусто - весь файл)
+	Window string
 	// Errors - список ошибок, обнаруженных при анализе
 	Errors []string
 }
@@ -44,6 +57,10 @@ type CompassResult struct {
 // SessionResults хранит результаты сессии анализа.
 // Структура содержит информацию об успешных и неуспешных анализах компасов.
 type SessionResults struct {
+	// Params - параметры анализа, с которыми получены результаты
+	Params AnalysisParams
+	// Profile - имя профиля процедуры калибровки
+	Profile string
 	// SuccessfulCompasses - карта успешно проанализированных компасов
 	// Ключ - номер компаса, значение - результаты анализа
 	SuccessfulCompasses map[string]CompassResult
@@ -0,0 +1,229 @@
+package models
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DeviceState - состояние калибровки, о котором сообщает сам прибор
+type DeviceState string
+
+const (
+	// DeviceStateRunning - прибор выполняет калибровку
+	DeviceStateRunning DeviceState = "running"
+	// DeviceStateDone - прибор завершил калибровку
+	DeviceStateDone DeviceState = "done"
+	// DeviceStateError - прибор сообщает об ошибке
+	DeviceStateError DeviceState = "error"
+)
+
+// FlagEntry - именованное состояние в слове флагов прибора.
+// Слово совпадает с записью, если (слово & Mask) == Value.
+type FlagEntry struct {
+	Mask  uint32      `json:"-"`
+	Value uint32      `json:"-"`
+	Name  string      `json:"name"`            // Имя состояния (латиницей, для фильтров и JSON)
+	Title string      `json:"title"`           // Описание для оператора
+	State DeviceState `json:"state,omitempty"` // Состояние калибровки, если запись его задает
+}
+
+// flagEntryJSON - запись словаря в файле: маска и значение - шестнадцатеричные строки
+type flagEntryJSON struct {
+	Mask  string      `json:"mask"`
+	Value string      `json:"value"`
+	Name  string      `json:"name"`
+	Title string      `json:"title"`
+	State DeviceState `json:"state,omitempty"`
+}
+
+// UnmarshalJSON читает запись словаря с маской и значением вида "0x1F"
+func (e *FlagEntry) UnmarshalJSON(data []byte) error {
+	var raw flagEntryJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	mask, err := strconv.ParseUint(raw.Mask, 0, 32)
+	if err != nil {
+		return fmt.Errorf("флаг «%s»: некорректная маска «%s»", raw.Name, raw.Mask)
+	}
+	value, err := strconv.ParseUint(raw.Value, 0, 32)
+	if err != nil {
+		return fmt.Errorf("флаг «%s»: некорректное значение «%s»", raw.Name, raw.Value)
+	}
+	*e = FlagEntry{Mask: uint32(mask), Value: uint32(value), Name: raw.Name, Title: raw.Title, State: raw.State}
+	return nil
+}
+
+// MarshalJSON записывает маску и значение шестнадцатеричными строками
+func (e FlagEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(flagEntryJSON{
+		Mask:  fmt.Sprintf("0x%02X", e.Mask),
+		Value: fmt.Sprintf("0x%02X", e.Value),
+		Name:  e.Name,
+		Title: e.Title,
+		State: e.State,
+	})
+}
+
+// Matches сообщает, что слово флагов совпадает с записью
+func (e FlagEntry) Matches(word uint32) bool {
+	return word&e.Mask == e.Value
+}
+
+// FlagDictionary - словарь флагов прошивки для столбцов «Статус, hex»
+// и «Калибровка, hex». Встроенный словарь - файл device_flags.json пакета;
+// для другой прошивки словарь загружается из файла (см. LoadFlagDictionary).
+type FlagDictionary struct {
+	Firmware    string      `json:"firmware"`              // Прошивка, для которой составлен словарь
+	Description string      `json:"description,omitempty"` // Описание словаря
+	Status      []FlagEntry `json:"status"`                // Флаги слова состояния
+	Calibration []FlagEntry `json:"calibration"`           // Флаги слова калибровки
+}
+
+//go:embed device_flags.json
+var builtinFlagsJSON []byte
+
+// flagDictionary - текущий словарь флагов: встроенный, заполняется при
+// инициализации пакета, чтобы параллельные анализы читали его без гонки
+// (заменяется только через RegisterFlagDictionary)
+var flagDictionary = BuiltinFlagDictionary()
+
+// BuiltinFlagDictionary возвращает встроенный словарь флагов. Словарь
+// проверяется так же, как загруженный из файла (см. LoadFlagDictionary)
+func BuiltinFlagDictionary() FlagDictionary {
+	var dict FlagDictionary
+	if err := json.Unmarshal(builtinFlagsJSON, &dict); err != nil {
+		panic(fmt.Sprintf("встроенный словарь флагов поврежден: %v", err))
+	}
+	if err := dict.Validate(); err != nil {
+		panic(fmt.Sprintf("некорректный встроенный словарь флагов: %v", err))
+	}
+	return dict
+}
+
+// LoadFlagDictionary загружает словарь флагов из JSON-файла
+// (формат - как у встроенного device_flags.json)
+func LoadFlagDictionary(path string) (FlagDictionary, error) {
+	var dict FlagDictionary
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return dict, fmt.Errorf("ошибка чтения словаря флагов: %v", err)
+	}
+	if err := json.Unmarshal(data, &dict); err != nil {
+		return dict, fmt.Errorf("ошибка разбора словаря флагов %s: %v", path, err)
+	}
+	if err := dict.Validate(); err != nil {
+		return dict, fmt.Errorf("некорректный словарь флагов %s: %v", path, err)
+	}
+	return dict, nil
+}
+
+// RegisterFlagDictionary заменяет словарь флагов, которым пользуется анализ.
+// Вызывается при загрузке конфигурации, до запуска анализов и веб-интерфейса:
+// замена во время параллельных анализов не синхронизирована
+func RegisterFlagDictionary(dict FlagDictionary) {
+	flagDictionary = dict
+}
+
+// Flags возвращает текущий словарь флагов: зарегистрированный или встроенный
+func Flags() FlagDictionary {
+	return flagDictionary
+}
+
+// Validate проверяет, что записи словаря заданы полностью
+func (d FlagDictionary) Validate() error {
+	for _, entries := range [][]FlagEntry{d.Status, d.Calibration} {
+		for _, e := range entries {
+			switch {
+			case e.Name == "":
+				return fmt.Errorf("у флага 0x%02X/0x%02X не задано имя", e.Mask, e.Value)
+			case e.Mask == 0 || e.Value&^e.Mask != 0:
+				return fmt.Errorf("флаг «%s»: значение 0x%02X выходит за маску 0x%02X", e.Name, e.Value, e.Mask)
+			case e.State != "" && e.State != DeviceStateRunning && e.State != DeviceStateDone && e.State != DeviceStateError:
+				return fmt.Errorf("флаг «%s»: неизвестное состояние «%s»", e.Name, e.State)
+			}
+		}
+	}
+	return nil
+}
+
+// DecodedFlags - слова флагов одной строки, переведенные в именованные состояния
+type DecodedFlags struct {
+	Status      []FlagEntry // Совпавшие флаги слова состояния
+	Calibration []FlagEntry // Совпавшие флаги слова калибровки
+	Unknown     bool        // В одном из слов есть ненулевое значение, которого нет в словаре
+}
+
+// Decode переводит слова состояния и калибровки в именованные состояния
+func (d FlagDictionary) Decode(status, calibration uint32) DecodedFlags {
+	var decoded DecodedFlags
+	match := func(entries []FlagEntry, word uint32) []FlagEntry {
+		var matched []FlagEntry
+		for _, e := range entries {
+			if e.Matches(word) {
+				matched = append(matched, e)
+			}
+		}
+		if word != 0 && len(matched) == 0 {
+			decoded.Unknown = true
+		}
+		return matched
+	}
+	decoded.Status = match(d.Status, status)
+	decoded.Calibration = match(d.Calibration, calibration)
+	return decoded
+}
+
+// State возвращает состояние калибровки по строке: ошибка важнее
+// завершения, завершение важнее выполнения (пусто - состояние не задано)
+func (f DecodedFlags) State() DeviceState {
+	var state DeviceState
+	for _, e := range append(append([]FlagEntry(nil), f.Status...), f.Calibration...) {
+		switch {
+		case e.State == DeviceStateError:
+			return DeviceStateError
+		case e.State == DeviceStateDone:
+			state = DeviceStateDone
+		case e.State == DeviceStateRunning && state == "":
+			state = DeviceStateRunning
+		}
+	}
+	return state
+}
+
+// Has сообщает, совпал ли в строке флаг словаря с именем name
+func (f DecodedFlags) Has(name string) bool {
+	for _, e := range append(append([]FlagEntry(nil), f.Status...), f.Calibration...) {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors возвращает флаги, которыми прибор сообщает об ошибке
+func (f DecodedFlags) Errors() []FlagEntry {
+	var errors []FlagEntry
+	for _, e := range append(append([]FlagEntry(nil), f.Status...), f.Calibration...) {
+		if e.State == DeviceStateError {
+			errors = append(errors, e)
+		}
+	}
+	return errors
+}
+
+// String возвращает состояния строки для логов: «Калибровка выполняется, Шаг 2: вращение по кругу»
+func (f DecodedFlags) String() string {
+	var titles []string
+	for _, e := range append(append([]FlagEntry(nil), f.Calibration...), f.Status...) {
+		titles = append(titles, e.Title)
+	}
+	if len(titles) == 0 {
+		return "—"
+	}
+	return strings.Join(titles, ", ")
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnalysisParams содержит пороги алгоритма поиска поворотов.
+// Значения по умолчанию возвращает DefaultAnalysisParams; параметры можно
+// переопределить в разделе "analysis" файла конфигурации, например для
+// линейки изделий с более строгими допусками.
+type AnalysisParams struct {
+	// StabilityThreshold - порог стабильности сегмента, градусы
+	StabilityThreshold float64 `json:"stability_threshold"`
+	// TurnTolerance - допуск поворота (90±X), градусы
+	TurnTolerance float64 `json:"turn_tolerance"`
+	// MinStableLen - минимальная длина стабильного сегмента, записей
+	MinStableLen int `json:"min_stable_len"`
+	// MaxOutliers - максимум выбросов внутри сегмента (гистерезис)
+	MaxOutliers int `json:"max_outliers"`
+	// ContinuityTolerance - допуск на разрыв между соседними поворотами, градусы
+	ContinuityTolerance float64 `json:"continuity_tolerance"`
+	// SumTolerance - допуск отклонения суммы поворотов от 360°, градусы
+	SumTolerance float64 `json:"sum_tolerance"`
+	// ReturnTolerance - допуск расхождения курса после полного оборота с начальным курсом, градусы
+	ReturnTolerance float64 `json:"return_tolerance"`
+	// CycleVerdict - как выносится вердикт, если в файле несколько полных циклов
+	// поворотов (CycleVerdictBest, CycleVerdictAll, CycleVerdictMajority)
+	CycleVerdict string `json:"cycle_verdict"`
+	// AzimuthColumn - столбец азимута, по которому ищутся повороты
+	// (AzimuthColumnRaw, AzimuthColumnCompensated)
+	AzimuthColumn string `json:"azimuth_column"`
+	// CalibrationWindow - какие записи файла анализируются на повороты
+	// (CalibrationWindowAuto, CalibrationWindowOff)
+	CalibrationWindow string `json:"calibration_window"`
+	// RetestQuality - порог оценки качества (0–100), ниже которого годный компас
+	// рекомендуется перепроверить
+	RetestQuality float64 `json:"retest_quality"`
+	// Preprocess - предварительная обработка азимута перед поиском сегментов
+	Preprocess PreprocessParams `json:"preprocess"`
+}
+
+// PreprocessParams задает этапы предварительной обработки ряда азимутов
+// (см. analyzer.Preprocess). Нулевое значение параметра отключает этап;
+// по умолчанию все этапы отключены и анализ идет по исходным записям.
+type PreprocessParams struct {
+	// SpikeThreshold - порог одиночного выброса, градусы: запись, отличающаяся
+	// от обоих соседей больше порога при близких соседях, заменяется их средним
+	SpikeThreshold float64 `json:"spike_threshold,omitempty"`
+	// MedianWindow - окно кругового медианного фильтра, записей (нечетное, не меньше 3)
+	MedianWindow int `json:"median_window,omitempty"`
+	// ResampleInterval - шаг равномерной сетки времени, секунды (не меньше
+	// MinResampleInterval). Пороги в записях (MinStableLen, MaxOutliers)
+	// задаются для записей файла и при поиске сегментов пересчитываются на шаг сетки
+	ResampleInterval float64 `json:"resample_sec,omitempty"`
+}
+
+// MinResampleInterval - наименьший шаг сетки передискретизации, секунды:
+// прибор пишет телеметрию раз в несколько секунд, и более частая сетка
+// не добавляет данных, а только умножает число записей для поиска сегментов
+const MinResampleInterval = 1.0
+
+// Enabled сообщает, что включен хотя бы один этап предварительной обработки
+func (p PreprocessParams) Enabled() bool {
+	return p.SpikeThreshold > 0 || p.MedianWindow > 0 || p.ResampleInterval > 0
+}
+
+// Validate проверяет параметры предварительной обработки
+func (p PreprocessParams) Validate() error {
+	switch {
+	case p.SpikeThreshold < 0:
+		return fmt.Errorf("порог выброса не может быть отрицательным: %.2f", p.SpikeThreshold)
+	case p.MedianWindow < 0 || (p.MedianWindow > 0 && (p.MedianWindow < 3 || p.MedianWindow%2 == 0)):
+		return fmt.Errorf("окно медианного фильтра должно быть нечетным и не меньше 3: %d", p.MedianWindow)
+	case p.ResampleInterval < 0 || (p.ResampleInterval > 0 && p.ResampleInterval < MinResampleInterval):
+		return fmt.Errorf("шаг передискретизации должен быть не меньше %g с: %g", MinResampleInterval, p.ResampleInterval)
+	}
+	return nil
+}
+
+// String возвращает включенные этапы одной строкой для логов и отчетов
+func (p PreprocessParams) String() string {
+	if !p.Enabled() {
+		return "отключена"
+	}
+	var stages []string
+	if p.SpikeThreshold > 0 {
+		stages = append(stages, fmt.Sprintf("выбросы > %.2f°", p.SpikeThreshold))
+	}
+	if p.MedianWindow > 0 {
+		stages = append(stages, fmt.Sprintf("медиана по %d", p.MedianWindow))
+	}
+	if p.ResampleInterval > 0 {
+		stages = append(stages, fmt.Sprintf("сетка %g с", p.ResampleInterval))
+	}
+	return strings.Join(stages, ", ")
+}
+
+// Режимы вердикта при нескольких полных циклах поворотов в файле
+const (
+	CycleVerdictBest     = "best"     // Решает лучший цикл (сумма поворотов ближе всего к номинальной)
+	CycleVerdictAll      = "all"      // Проверки должны пройти все циклы
+	CycleVerdictMajority = "majority" // Проверки должно пройти больше половины циклов
+)
+
+// Столбцы азимута, по которым может выполняться поиск поворотов
+const (
+	AzimuthColumnRaw         = "raw"         // «Азимут RAW» - без компенсации (по умолчанию)
+	AzimuthColumnCompensated = "compensated" // «Азимут, град» - с компенсацией прибора
+)
+
+// Режимы выбора записей для поиска поворотов
+const (
+	// CalibrationWindowAuto - только окно после калибровки прибора, найденное
+	// по флагам калибровки, журналу блока AB или движению (по умолчанию)
+	CalibrationWindowAuto = "auto"
+	// CalibrationWindowOff - весь файл, как если бы он был обрезан вручную
+	CalibrationWindowOff = "off"
+)
+
+// DefaultAnalysisParams возвращает параметры анализа по умолчанию
+func DefaultAnalysisParams() AnalysisParams {
+	return AnalysisParams{
+		StabilityThreshold:  5.0,  // Порог стабильности в градусах
+		TurnTolerance:       15.0, // Допуск 90±15 градусов (было 10, увеличено для учета реальных данных)
+		MinStableLen:        2,    // Уменьшено с 3 до 2 для учета коротких переходных зон
+		MaxOutliers:         0,    // Гистерезис отключен - каждый нестабильный угол прерывает сегмент
+		ContinuityTolerance: 20.0, // Допуск на непрерывность в градусах
+		SumTolerance:        15.0, // Допуск ±15° на сумму
+		ReturnTolerance:     5.0,  // Курс после полного оборота в пределах ±5° от начального
+		CycleVerdict:        CycleVerdictBest,
+		AzimuthColumn:       AzimuthColumnRaw,
+		CalibrationWindow:   CalibrationWindowAuto,
+		RetestQuality:       75.0, // Ниже 75 из 100 - перепроверка
+	}
+}
+
+// Validate проверяет, что параметры имеют допустимые значения
+func (p AnalysisParams) Validate() error {
+	switch {
+	case p.StabilityThreshold <= 0:
+		return fmt.Errorf("порог стабильности должен быть больше 0: %.2f", p.StabilityThreshold)
+	case p.TurnTolerance <= 0 || p.TurnTolerance >= 90:
+		return fmt.Errorf("допуск поворота должен быть в диапазоне (0, 90): %.2f", p.TurnTolerance)
+	case p.MinStableLen < 1:
+		return fmt.Errorf("минимальная длина сегмента должна быть не меньше 1: %d", p.MinStableLen)
+	case p.MaxOutliers < 0:
+		return fmt.Errorf("максимум выбросов не может быть отрицательным: %d", p.MaxOutliers)
+	case p.ContinuityTolerance < 0:
+		return fmt.Errorf("допуск непрерывности не может быть отрицательным: %.2f", p.ContinuityTolerance)
+	case p.SumTolerance < 0:
+		return fmt.Errorf("допуск суммы поворотов не может быть отрицательным: %.2f", p.SumTolerance)
+	case p.ReturnTolerance < 0:
+		return fmt.Errorf("допуск возврата курса не может быть отрицательным: %.2f", p.ReturnTolerance)
+	case p.CycleVerdict != CycleVerdictBest && p.CycleVerdict != CycleVerdictAll && p.CycleVerdict != CycleVerdictMajority:
+		return fmt.Errorf("режим вердикта по циклам должен быть «%s», «%s» или «%s»: «%s»",
+			CycleVerdictBest, CycleVerdictAll, CycleVerdictMajority, p.CycleVerdict)
+	case p.AzimuthColumn != AzimuthColumnRaw && p.AzimuthColumn != AzimuthColumnCompensated:
+		return fmt.Errorf("столбец азимута должен быть «%s» или «%s»: «%s»",
+			AzimuthColumnRaw, AzimuthColumnCompensated, p.AzimuthColumn)
+	case p.CalibrationWindow != CalibrationWindowAuto && p.CalibrationWindow != CalibrationWindowOff:
+		return fmt.Errorf("окно калибровки должно быть «%s» или «%s»: «%s»",
+			CalibrationWindowAuto, CalibrationWindowOff, p.CalibrationWindow)
+	case p.RetestQuality < 0 || p.RetestQuality > 100:
+		return fmt.Errorf("порог перепроверки должен быть в диапазоне [0, 100]: %.2f", p.RetestQuality)
+	}
+	return p.Preprocess.Validate()
+}
+
+// String возвращает параметры одной строкой для логов и отчетов. Номинальные
+// углы поворота и суммы задает профиль: с ними и с порогами профиля параметры
+// выводит ProcedureProfile.DescribeParams
+func (p AnalysisParams) String() string {
+	return p.describe("", "")
+}
+
+// describe возвращает параметры одной строкой; step и total - номинальные
+// углы поворота и суммы поворотов (пусто - выводятся только допуски)
+func (p AnalysisParams) describe(step, total string) string {
+	s := fmt.Sprintf("стабильность %.2f°, поворот %s±%.2f°, сегмент ≥ %d, выбросов ≤ %d, непрерывность %.2f°, сумма %s±%.2f°, возврат ±%.2f°, циклы: %s, перепроверка < %.0f",
+		p.StabilityThreshold, step, p.TurnTolerance, p.MinStableLen, p.MaxOutliers, p.ContinuityTolerance, total, p.SumTolerance, p.ReturnTolerance, p.CycleVerdict, p.RetestQuality)
+	if p.AzimuthColumn == AzimuthColumnCompensated {
+		s += ", азимут: компенсированный"
+	}
+	if p.CalibrationWindow == CalibrationWindowOff {
+		s += ", окно калибровки: весь файл"
+	}
+	if p.Preprocess.Enabled() {
+		s += ", предобработка: " + p.Preprocess.String()
+	}
+	return s
+}
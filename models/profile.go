@@ -0,0 +1,432 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Direction - направление вращения компаса на стенде
+type Direction string
+
+const (
+	// Clockwise - вращение по часовой стрелке (азимут растет)
+	Clockwise Direction = "cw"
+	// CounterClockwise - вращение против часовой стрелки (азимут убывает)
+	CounterClockwise Direction = "ccw"
+)
+
+// String возвращает название направления для логов и отчетов
+func (d Direction) String() string {
+	switch d {
+	case Clockwise:
+		return "по часовой"
+	case CounterClockwise:
+		return "против часовой"
+	default:
+		return string(d)
+	}
+}
+
+// DefaultProfileName - профиль, который используется, если профиль не выбран
+const DefaultProfileName = "4x90-cw"
+
+// ProcedureProfile описывает процедуру калибровки на стенде:
+// сколько поворотов, на какой угол и в каком направлении делает оператор.
+// Встроенные профили возвращает BuiltinProfiles, собственные профили
+// задаются в разделе "profiles" файла конфигурации.
+type ProcedureProfile struct {
+	// Name - имя профиля (например "4x90-cw"), записывается в результат
+	Name string `json:"name"`
+	// Description - описание процедуры для оператора
+	Description string `json:"description,omitempty"`
+	// StepAngle - номинальный угол одного поворота, градусы
+	StepAngle float64 `json:"step_angle"`
+	// StepCount - количество поворотов в полном цикле
+	StepCount int `json:"step_count"`
+	// Direction - направление вращения
+	Direction Direction `json:"direction"`
+	// StepTolerance - допуск угла поворота; 0 - AnalysisParams.TurnTolerance
+	StepTolerance float64 `json:"step_tolerance,omitempty"`
+	// SumTolerance - допуск суммы поворотов; 0 - AnalysisParams.SumTolerance.
+	// Устаревший ключ closure_tolerance читается как sum_tolerance (см. UnmarshalJSON)
+	SumTolerance float64 `json:"sum_tolerance,omitempty"`
+	// ReturnTolerance - допуск возврата к начальному курсу после полного оборота; 0 - AnalysisParams.ReturnTolerance
+	ReturnTolerance float64 `json:"return_tolerance,omitempty"`
+	// StabilityThreshold - порог стабильности сегмента; 0 - AnalysisParams.StabilityThreshold
+	StabilityThreshold float64 `json:"stability_threshold,omitempty"`
+	// MinStableLen - минимальная длина стабильного сегмента, записей; 0 - AnalysisParams.MinStableLen
+	MinStableLen int `json:"min_stable_len,omitempty"`
+	// ContinuityTolerance - допуск на разрыв между поворотами; 0 - AnalysisParams.ContinuityTolerance
+	ContinuityTolerance float64 `json:"continuity_tolerance,omitempty"`
+	// MinDwell - минимальное время неподвижности на каждом курсе, секунды; 0 - не проверяется
+	MinDwell float64 `json:"min_dwell_sec,omitempty"`
+	// MaxTurnDuration - максимальная длительность одного поворота, секунды; 0 - не проверяется
+	MaxTurnDuration float64 `json:"max_turn_sec,omitempty"`
+	// MaxTotalDuration - максимальная длительность всего цикла поворотов, секунды; 0 - не проверяется
+	MaxTotalDuration float64 `json:"max_total_sec,omitempty"`
+	// Segmenter - алгоритм поиска стабильных сегментов (см. SegmenterNames); пусто - SegmenterGreedy
+	Segmenter string `json:"segmenter,omitempty"`
+	// MaxTilt - максимальный наклон стенда в каждой позиции, градусы; 0 - не проверяется
+	MaxTilt float64 `json:"max_tilt,omitempty"`
+	// MaxTiltChange - максимальное изменение дифферента или крена между позициями, градусы; 0 - не проверяется
+	MaxTiltChange float64 `json:"max_tilt_change,omitempty"`
+	// TiltAdvisory - нарушение наклона только предупреждение и не влияет на вердикт
+	TiltAdvisory bool `json:"tilt_advisory,omitempty"`
+	// MaxSoftIron - допустимое искажение мягкого железа (1 - малая/большая ось эллипса поля), %; 0 - не проверяется
+	MaxSoftIron float64 `json:"max_soft_iron,omitempty"`
+	// MaxFitResidual - допустимое СКО показаний магнитометра от эллипса, % радиуса поля; 0 - не проверяется
+	MaxFitResidual float64 `json:"max_fit_residual,omitempty"`
+	// MaxOffsetError - допустимое расхождение центра эллипса и смещения, сохраненного прибором, % радиуса поля; 0 - не проверяется
+	MaxOffsetError float64 `json:"max_offset_error,omitempty"`
+	// MinFitCoverage - охват окружности показаниями магнитометра, при котором проверяется поле, градусы; 0 - DefaultMinFitCoverage
+	MinFitCoverage float64 `json:"min_fit_coverage,omitempty"`
+	// MinSoftIronCoverage - охват окружности, при котором оценивается мягкое железо, градусы; 0 - DefaultMinSoftIronCoverage
+	MinSoftIronCoverage float64 `json:"min_soft_iron_coverage,omitempty"`
+	// MagFitAdvisory - нарушение проверок поля магнитометра только предупреждение и не влияет на вердикт
+	MagFitAdvisory bool `json:"mag_fit_advisory,omitempty"`
+	// MaxThermalDrift - допустимый дрейф курса от температуры в неподвижных позициях, °/°C; 0 - не проверяется
+	MaxThermalDrift float64 `json:"max_thermal_drift,omitempty"`
+	// MinDriftTempSpan - изменение температуры внутри позиции, при котором оценивается дрейф, °C; 0 - DefaultMinDriftTempSpan
+	MinDriftTempSpan float64 `json:"min_drift_temp_span,omitempty"`
+	// DriftAdvisory - превышение температурного дрейфа только предупреждение и не влияет на вердикт
+	DriftAdvisory bool `json:"drift_advisory,omitempty"`
+}
+
+// Охват окружности показаниями магнитометра по умолчанию (см. ProcedureProfile.MinFitCoverage)
+const (
+	// DefaultMinFitCoverage - эллипс строится по дуге не меньше 2/3 окружности
+	DefaultMinFitCoverage = 240.0
+	// DefaultMinSoftIronCoverage - вытянутость эллипса по неполной дуге экстраполируется
+	// и на наборе «Успешно» доходит до 40%, поэтому мягкое железо оценивается
+	// только по почти полной окружности
+	DefaultMinSoftIronCoverage = 330.0
+)
+
+// DefaultMinDriftTempSpan - изменение температуры внутри позиции по умолчанию,
+// при котором оценивается температурный дрейф курса (см. ProcedureProfile.MinDriftTempSpan), °C
+const DefaultMinDriftTempSpan = 1.0
+
+// Алгоритмы поиска стабильных сегментов (реализации - в пакете analyzer)
+const (
+	// SegmenterGreedy - наращивание сегмента по среднему углу со слиянием близких сегментов
+	SegmenterGreedy = "greedy"
+	// SegmenterChangePoint - поиск точек смены курса (штраф за разбиение, PELT)
+	SegmenterChangePoint = "changepoint"
+	// SegmenterWindow - удержание курса относительно опорного угла не меньше заданного времени
+	SegmenterWindow = "window"
+)
+
+// SegmenterNames возвращает имена всех алгоритмов поиска сегментов
+func SegmenterNames() []string {
+	return []string{SegmenterGreedy, SegmenterChangePoint, SegmenterWindow}
+}
+
+// UnmarshalJSON читает профиль из конфигурации. Устаревший ключ
+// closure_tolerance задавал допуск суммы поворотов, а не проверки возврата
+// к начальному курсу (return_tolerance), поэтому он читается как sum_tolerance.
+func (p *ProcedureProfile) UnmarshalJSON(data []byte) error {
+	type profileJSON ProcedureProfile
+	aux := struct {
+		*profileJSON
+		ClosureTolerance float64 `json:"closure_tolerance"`
+	}{profileJSON: (*profileJSON)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.ClosureTolerance != 0 {
+		if p.SumTolerance != 0 && p.SumTolerance != aux.ClosureTolerance {
+			return fmt.Errorf("профиль «%s»: заданы sum_tolerance (%.2f) и устаревший closure_tolerance (%.2f) с разными значениями",
+				p.Name, p.SumTolerance, aux.ClosureTolerance)
+		}
+		p.SumTolerance = aux.ClosureTolerance
+	}
+	return nil
+}
+
+// BuiltinProfiles возвращает встроенные профили процедур калибровки
+func BuiltinProfiles() []ProcedureProfile {
+	return []ProcedureProfile{
+		{
+			Name:        "4x90-cw",
+			Description: "4 поворота по 90° по часовой стрелке",
+			StepAngle:   90,
+			StepCount:   4,
+			Direction:   Clockwise,
+
+			MinDwell:         10,
+			MaxTurnDuration:  90,
+			MaxTotalDuration: 600,
+
+			MaxTilt:       10,
+			MaxTiltChange: 10,
+
+			MaxSoftIron:    15,
+			MaxFitResidual: 8,
+			MaxOffsetError: 10,
+			MagFitAdvisory: true,
+
+			MaxThermalDrift: 0.5,
+			DriftAdvisory:   true,
+		},
+		{
+			Name:        "4x90-ccw",
+			Description: "4 поворота по 90° против часовой стрелки",
+			StepAngle:   90,
+			StepCount:   4,
+			Direction:   CounterClockwise,
+
+			MinDwell:         10,
+			MaxTurnDuration:  90,
+			MaxTotalDuration: 600,
+
+			MaxTilt:       10,
+			MaxTiltChange: 10,
+
+			MaxSoftIron:    15,
+			MaxFitResidual: 8,
+			MaxOffsetError: 10,
+			MagFitAdvisory: true,
+
+			MaxThermalDrift: 0.5,
+			DriftAdvisory:   true,
+		},
+		{
+			Name:          "8x45-cw",
+			Description:   "8 поворотов по 45° по часовой стрелке",
+			StepAngle:     45,
+			StepCount:     8,
+			Direction:     Clockwise,
+			StepTolerance: 10,
+
+			MinDwell:         10,
+			MaxTurnDuration:  60,
+			MaxTotalDuration: 900,
+
+			MaxTilt:       10,
+			MaxTiltChange: 10,
+
+			MaxSoftIron:    15,
+			MaxFitResidual: 8,
+			MaxOffsetError: 10,
+			MagFitAdvisory: true,
+
+			MaxThermalDrift: 0.5,
+			DriftAdvisory:   true,
+		},
+	}
+}
+
+// customProfiles - собственные профили из файла конфигурации (см. RegisterProfiles)
+var customProfiles []ProcedureProfile
+
+// RegisterProfiles регистрирует собственные профили из конфигурации.
+// Профиль с именем встроенного профиля заменяет встроенный.
+// Повторный вызов заменяет ранее зарегистрированные профили.
+func RegisterProfiles(profiles []ProcedureProfile) error {
+	seen := make(map[string]bool, len(profiles))
+	for _, p := range profiles {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		key := strings.ToLower(p.Name)
+		if seen[key] {
+			return fmt.Errorf("профиль процедуры «%s» описан несколько раз", p.Name)
+		}
+		seen[key] = true
+	}
+	customProfiles = append([]ProcedureProfile(nil), profiles...)
+	return nil
+}
+
+// Profiles возвращает все доступные профили: собственные, затем встроенные
+// (встроенные, замененные собственными, не повторяются)
+func Profiles() []ProcedureProfile {
+	result := append([]ProcedureProfile(nil), customProfiles...)
+	for _, builtin := range BuiltinProfiles() {
+		overridden := false
+		for _, custom := range customProfiles {
+			if strings.EqualFold(custom.Name, builtin.Name) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			result = append(result, builtin)
+		}
+	}
+	return result
+}
+
+// FindProfile ищет профиль по имени без учета регистра (см. Profiles).
+// Пустое имя означает профиль по умолчанию.
+func FindProfile(name string) (ProcedureProfile, error) {
+	if name == "" {
+		name = DefaultProfileName
+	}
+	for _, p := range Profiles() {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	return ProcedureProfile{}, fmt.Errorf("профиль процедуры «%s» не найден", name)
+}
+
+// Validate проверяет, что профиль описывает выполнимую процедуру
+func (p ProcedureProfile) Validate() error {
+	switch {
+	case p.Name == "":
+		return fmt.Errorf("у профиля процедуры не задано имя")
+	case p.StepAngle <= 0 || p.StepAngle >= 180:
+		return fmt.Errorf("профиль «%s»: угол поворота должен быть в диапазоне (0, 180): %.2f", p.Name, p.StepAngle)
+	case p.StepCount < 1:
+		return fmt.Errorf("профиль «%s»: количество поворотов должно быть не меньше 1: %d", p.Name, p.StepCount)
+	case p.Direction != Clockwise && p.Direction != CounterClockwise:
+		return fmt.Errorf("профиль «%s»: направление должно быть «%s» или «%s»: «%s»", p.Name, Clockwise, CounterClockwise, p.Direction)
+	case p.StepTolerance < 0 || p.StepTolerance >= p.StepAngle:
+		return fmt.Errorf("профиль «%s»: допуск поворота должен быть в диапазоне [0, %.2f): %.2f", p.Name, p.StepAngle, p.StepTolerance)
+	case p.SumTolerance < 0:
+		return fmt.Errorf("профиль «%s»: допуск суммы поворотов не может быть отрицательным: %.2f", p.Name, p.SumTolerance)
+	case p.ReturnTolerance < 0:
+		return fmt.Errorf("профиль «%s»: допуск возврата курса не может быть отрицательным: %.2f", p.Name, p.ReturnTolerance)
+	case p.StabilityThreshold < 0 || p.MinStableLen < 0 || p.ContinuityTolerance < 0:
+		return fmt.Errorf("профиль «%s»: пороги поиска сегментов не могут быть отрицательными", p.Name)
+	case p.MinDwell < 0 || p.MaxTurnDuration < 0 || p.MaxTotalDuration < 0:
+		return fmt.Errorf("профиль «%s»: ограничения времени не могут быть отрицательными", p.Name)
+	case p.MaxTilt < 0 || p.MaxTilt >= 90 || p.MaxTiltChange < 0 || p.MaxTiltChange >= 90:
+		return fmt.Errorf("профиль «%s»: ограничения наклона должны быть в диапазоне [0, 90)", p.Name)
+	case p.MaxSoftIron < 0 || p.MaxFitResidual < 0 || p.MaxOffsetError < 0:
+		return fmt.Errorf("профиль «%s»: допуски поля магнитометра не могут быть отрицательными", p.Name)
+	case p.MinFitCoverage < 0 || p.MinFitCoverage > 360 || p.MinSoftIronCoverage < 0 || p.MinSoftIronCoverage > 360:
+		return fmt.Errorf("профиль «%s»: охват окружности магнитометром должен быть в диапазоне [0, 360]", p.Name)
+	case p.MaxThermalDrift < 0 || p.MinDriftTempSpan < 0:
+		return fmt.Errorf("профиль «%s»: ограничения температурного дрейфа не могут быть отрицательными", p.Name)
+	}
+	for _, name := range SegmenterNames() {
+		if strings.EqualFold(p.SegmenterName(), name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("профиль «%s»: неизвестный алгоритм поиска сегментов «%s» (доступны: %s)",
+		p.Name, p.Segmenter, strings.Join(SegmenterNames(), ", "))
+}
+
+// SegmenterName возвращает алгоритм поиска сегментов профиля (по умолчанию SegmenterGreedy)
+func (p ProcedureProfile) SegmenterName() string {
+	if p.Segmenter == "" {
+		return SegmenterGreedy
+	}
+	return p.Segmenter
+}
+
+// TotalAngle возвращает номинальную сумму поворотов цикла, градусы
+func (p ProcedureProfile) TotalAngle() float64 {
+	return p.StepAngle * float64(p.StepCount)
+}
+
+// Matches проверяет, совпадает ли направление поворота с направлением профиля
+func (p ProcedureProfile) Matches(turn Turn) bool {
+	return turn.IsClockwise == (p.Direction == Clockwise)
+}
+
+// String возвращает профиль одной строкой для логов и отчетов
+func (p ProcedureProfile) String() string {
+	return fmt.Sprintf("%s (%d × %.0f°, %s)", p.Name, p.StepCount, p.StepAngle, p.Direction)
+}
+
+// HasTimingLimits сообщает, задано ли в профиле хотя бы одно ограничение времени
+func (p ProcedureProfile) HasTimingLimits() bool {
+	return p.MinDwell > 0 || p.MaxTurnDuration > 0 || p.MaxTotalDuration > 0
+}
+
+// HasTiltLimits сообщает, задано ли в профиле хотя бы одно ограничение наклона
+func (p ProcedureProfile) HasTiltLimits() bool {
+	return p.MaxTilt > 0 || p.MaxTiltChange > 0
+}
+
+// HasMagFitLimits сообщает, задан ли в профиле хотя бы один допуск поля магнитометра
+func (p ProcedureProfile) HasMagFitLimits() bool {
+	return p.MaxSoftIron > 0 || p.MaxFitResidual > 0 || p.MaxOffsetError > 0
+}
+
+// FitCoverage возвращает охват окружности, при котором проверяется поле магнитометра, градусы
+func (p ProcedureProfile) FitCoverage() float64 {
+	if p.MinFitCoverage > 0 {
+		return p.MinFitCoverage
+	}
+	return DefaultMinFitCoverage
+}
+
+// SoftIronCoverage возвращает охват окружности, при котором оценивается мягкое железо, градусы
+func (p ProcedureProfile) SoftIronCoverage() float64 {
+	if p.MinSoftIronCoverage > 0 {
+		return p.MinSoftIronCoverage
+	}
+	return DefaultMinSoftIronCoverage
+}
+
+// DriftTempSpan возвращает изменение температуры внутри позиции, при котором оценивается дрейф курса, °C
+func (p ProcedureProfile) DriftTempSpan() float64 {
+	if p.MinDriftTempSpan > 0 {
+		return p.MinDriftTempSpan
+	}
+	return DefaultMinDriftTempSpan
+}
+
+// StepToleranceFor возвращает допуск угла поворота с учетом параметров анализа
+func (p ProcedureProfile) StepToleranceFor(params AnalysisParams) float64 {
+	if p.StepTolerance > 0 {
+		return p.StepTolerance
+	}
+	return params.TurnTolerance
+}
+
+// SumToleranceFor возвращает допуск суммы поворотов с учетом параметров анализа
+func (p ProcedureProfile) SumToleranceFor(params AnalysisParams) float64 {
+	if p.SumTolerance > 0 {
+		return p.SumTolerance
+	}
+	return params.SumTolerance
+}
+
+// ReturnToleranceFor возвращает допуск возврата к начальному курсу с учетом параметров анализа
+func (p ProcedureProfile) ReturnToleranceFor(params AnalysisParams) float64 {
+	if p.ReturnTolerance > 0 {
+		return p.ReturnTolerance
+	}
+	return params.ReturnTolerance
+}
+
+// ParamsFor возвращает параметры анализа с порогами, заданными в профиле
+// (порог стабильности, минимальная длина сегмента, допуск непрерывности,
+// допуски поворота, суммы и возврата - см. StepToleranceFor и т.д.): это
+// пороги, с которыми анализ выполняется на самом деле
+func (p ProcedureProfile) ParamsFor(params AnalysisParams) AnalysisParams {
+	params.TurnTolerance = p.StepToleranceFor(params)
+	params.SumTolerance = p.SumToleranceFor(params)
+	params.ReturnTolerance = p.ReturnToleranceFor(params)
+	if p.StabilityThreshold > 0 {
+		params.StabilityThreshold = p.StabilityThreshold
+	}
+	if p.MinStableLen > 0 {
+		params.MinStableLen = p.MinStableLen
+	}
+	if p.ContinuityTolerance > 0 {
+		params.ContinuityTolerance = p.ContinuityTolerance
+	}
+	return params
+}
+
+// DescribeParams возвращает пороги, с которыми анализ выполняется по профилю
+// (см. ParamsFor), одной строкой для логов и отчетов: допуски поворота и суммы -
+// вместе с номинальными углами профиля («поворот 45±10.00°, … сумма 360±15.00°»)
+func (p ProcedureProfile) DescribeParams(params AnalysisParams) string {
+	return p.ParamsFor(params).describe(fmt.Sprintf("%g", p.StepAngle), fmt.Sprintf("%g", p.TotalAngle()))
+}
+
+// FullRotation сообщает, что цикл поворотов профиля возвращает компас
+// к начальному курсу (сумма поворотов кратна 360°)
+func (p ProcedureProfile) FullRotation() bool {
+	return math.Mod(p.TotalAngle(), 360) == 0
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ReasonCode - стабильный код причины отбраковки компаса.
+// Коды не переводятся и не меняются между версиями: по ним считают брак
+// по причинам за смену и фильтруют папку «Брак». Текст для оператора
+// берется из каталога сообщений (см. Title и Format).
+type ReasonCode string
+
+// Причины, найденные анализом поворотов
+const (
+	ReasonTooFewTurns      ReasonCode = "TOO_FEW_TURNS"     // Найдено меньше поворотов, чем в профиле
+	ReasonCounterClockwise ReasonCode = "COUNTER_CLOCKWISE" // Поворот против часовой стрелки в профиле «по часовой»
+	ReasonClockwise        ReasonCode = "CLOCKWISE"         // Поворот по часовой стрелке в профиле «против часовой»
+	ReasonIndexOverlap     ReasonCode = "INDEX_OVERLAP"     // Повороты пересекаются по записям
+	ReasonChainGap         ReasonCode = "CHAIN_GAP"         // Разрыв цепочки поворотов (предупреждение)
+	ReasonSumDeviation     ReasonCode = "SUM_DEVIATION"     // Сумма поворотов далека от номинальной
+	ReasonReturnMismatch   ReasonCode = "RETURN_MISMATCH"   // Курс после полного оборота не совпадает с начальным
+	ReasonCyclesFailed     ReasonCode = "CYCLES_FAILED"     // Не прошли повторные циклы поворотов
+	ReasonShortDwell       ReasonCode = "SHORT_DWELL"       // Курс удерживался слишком мало
+	ReasonSlowTurn         ReasonCode = "SLOW_TURN"         // Поворот выполнялся слишком долго
+	ReasonSlowCycle        ReasonCode = "SLOW_CYCLE"        // Цикл поворотов выполнялся слишком долго
+)
+
+// Причины, найденные проверкой наклона стенда
+const (
+	ReasonTiltLevel  ReasonCode = "TILT_LEVEL"  // Стенд не выставлен по уровню
+	ReasonTiltChange ReasonCode = "TILT_CHANGE" // Наклон меняется между позициями
+)
+
+// Причина, найденная проверкой температурного дрейфа курса
+const ReasonThermalDrift ReasonCode = "THERMAL_DRIFT" // Курс уходит вместе с температурой
+
+// Причины, найденные проверкой поля магнитометра
+const (
+	ReasonSoftIron       ReasonCode = "SOFT_IRON"       // Искажение мягкого железа
+	ReasonFitResidual    ReasonCode = "FIT_RESIDUAL"    // Показания плохо ложатся на эллипс
+	ReasonOffsetMismatch ReasonCode = "OFFSET_MISMATCH" // Центр эллипса не совпадает со смещением прибора
+)
+
+// Неисправности датчиков (проверка исправности до анализа поворотов):
+// компас не годен не из-за процедуры калибровки, а из-за данных прибора
+const (
+	ReasonSensorStuck    ReasonCode = "SENSOR_STUCK"    // Азимут не меняется
+	ReasonSensorDropout  ReasonCode = "SENSOR_DROPOUT"  // Серия строк с нулевыми показаниями датчиков
+	ReasonSensorRepeated ReasonCode = "SENSOR_REPEATED" // Длинная серия одинаковых строк
+	ReasonSensorJump     ReasonCode = "SENSOR_JUMP"     // Невозможный скачок показаний
+	ReasonSensorRange    ReasonCode = "SENSOR_RANGE"    // Показания вне физического диапазона
+)
+
+// Причины, о которых сообщает сам прибор (столбцы «Статус, hex» и «Калибровка, hex»)
+const (
+	ReasonDeviceError      ReasonCode = "DEVICE_ERROR"      // Прибор сообщает об ошибке
+	ReasonDeviceIncomplete ReasonCode = "DEVICE_INCOMPLETE" // Прибор не сообщил о завершении калибровки (предупреждение)
+)
+
+// Причины, из-за которых анализ не выполнялся
+const (
+	ReasonFileMissing ReasonCode = "FILE_MISSING" // Нет файла SB_CMPS.csv
+	ReasonFileAccess  ReasonCode = "FILE_ACCESS"  // Нет доступа к файлу
+	ReasonParseEmpty  ReasonCode = "PARSE_EMPTY"  // В файле нет записей данных
+	ReasonParseError  ReasonCode = "PARSE_ERROR"  // Файл не удалось разобрать
+)
+
+// reasonMessage - локализованные тексты причины
+type reasonMessage struct {
+	title  string // Краткое название для сводок и фильтров
+	format string // Шаблон подробного сообщения (аргументы задает место проверки)
+}
+
+// reasonCatalog - каталог сообщений для оператора (русский язык)
+var reasonCatalog = map[ReasonCode]reasonMessage{
+	ReasonTooFewTurns: {"Недостаточно поворотов",
+		"Найдено %d поворотов, требуется минимум %d"},
+	ReasonCounterClockwise: {"Поворот против часовой стрелки",
+		"Поворот %d идет против часовой стрелки (%.2f° → %.2f°, знаковая разница: %.2f°)"},
+	ReasonClockwise: {"Поворот по часовой стрелке",
+		"Поворот %d идет по часовой стрелке (%.2f° → %.2f°, знаковая разница: %.2f°)"},
+	ReasonIndexOverlap: {"Пересечение поворотов",
+		"Поворот %d начинается до конца поворота %d (пересечение индексов)"},
+	ReasonChainGap: {"Разрыв цепочки поворотов",
+		"Разрыв между поворотом %d (конец %.2f°) и поворотом %d (начало %.2f°): %.2f° > %.2f°"},
+	ReasonSumDeviation: {"Отклонение суммы поворотов",
+		"Сумма поворотов (%.2f°) слишком отличается от %.0f° (отклонение: %.2f° > %.2f°)"},
+	ReasonReturnMismatch: {"Курс не вернулся к начальному",
+		"Курс после полного оборота (%.2f°) не совпадает с начальным (%.2f°): расхождение %.2f° > %.2f°"},
+	ReasonCyclesFailed: {"Не прошли повторные циклы",
+		"Проверки прошли %d из %d циклов поворотов (режим «%s»), не прошли: %s"},
+	ReasonShortDwell: {"Короткая остановка на курсе",
+		"Курс %d (%.2f°) удерживался слишком мало: %s – %s (%.0f с < %.0f с)"},
+	ReasonSlowTurn: {"Медленный поворот",
+		"Поворот %d выполнялся слишком долго: %s – %s (%.0f с > %.0f с)"},
+	ReasonSlowCycle: {"Медленный цикл поворотов",
+		"Цикл поворотов выполнялся слишком долго: %s – %s (%.0f с > %.0f с)"},
+	ReasonTiltLevel: {"Стенд не выставлен по уровню",
+		"Позиция %d (%.2f°): наклон %.2f° > %.2f° (дифферент %+.2f°, крен %+.2f°)"},
+	ReasonTiltChange: {"Наклон меняется между позициями",
+		"%s меняется между позициями на %.2f° > %.2f° (позиции %d и %d: %+.2f° и %+.2f°)"},
+	ReasonThermalDrift: {"Температурный дрейф курса",
+		"Дрейф курса %+.3f °/°C > %.2f °/°C (%s, корреляция %.2f): за процедуру курс уходит на %+.1f° при изменении температуры на %.1f °C"},
+	ReasonSoftIron: {"Искажение мягкого железа",
+		"Искажение мягкого железа %.1f%% > %.1f%% (оси эллипса %.0f и %.0f)"},
+	ReasonFitResidual: {"Поле не ложится на эллипс",
+		"Показания магнитометра плохо ложатся на эллипс: СКО %.1f%% > %.1f%% радиуса поля"},
+	ReasonOffsetMismatch: {"Смещение магнитометра не совпадает",
+		"Центр эллипса (%.0f, %.0f) не совпадает со смещением прибора (%.0f, %.0f): %.1f%% > %.1f%% радиуса поля"},
+	ReasonSensorStuck: {"Азимут не меняется",
+		"Азимут не меняется: %.0f%% записей подряд (%d из %d) с одним значением %.1f° > %.0f%%"},
+	ReasonSensorDropout: {"Нулевые показания датчиков",
+		"Нулевые показания всех датчиков в %d строках подряд (строки %d–%d) > %d"},
+	ReasonSensorRepeated: {"Повторяющиеся строки",
+		"Одинаковые показания датчиков в %d строках подряд (строки %d–%d) > %d"},
+	ReasonSensorJump: {"Невозможный скачок показаний",
+		"Скачок «%s» в строке %d: %.2f → %.2f (изменение %.2f > %.2f)"},
+	ReasonSensorRange: {"Показания вне диапазона",
+		"Значение «%s» в строке %d вне диапазона [%.0f, %.0f]: %.2f"},
+	ReasonDeviceError: {"Прибор сообщает об ошибке",
+		"Прибор сообщает об ошибке в %d строках: %s"},
+	ReasonDeviceIncomplete: {"Калибровка не завершена прибором",
+		"Прибор выполнял калибровку (строки %d–%d), но не сообщил о ее завершении"},
+	ReasonFileMissing: {"Нет файла данных",
+		"Файл данных SB_CMPS.csv не найден: %s"},
+	ReasonFileAccess: {"Нет доступа к файлу данных",
+		"Ошибка доступа к файлу SB_CMPS.csv (%s): %v"},
+	ReasonParseEmpty: {"Файл данных пуст",
+		"Файл данных не содержит записей (%s): %v"},
+	ReasonParseError: {"Ошибка чтения файла данных",
+		"Ошибка чтения файла данных (%s): %v"},
+}
+
+// Title возвращает краткое название причины для сводок
+func (c ReasonCode) Title() string {
+	if c == "" {
+		return "Причина не указана"
+	}
+	if m, ok := reasonCatalog[c]; ok {
+		return m.title
+	}
+	return string(c)
+}
+
+// Format возвращает подробное сообщение о причине по шаблону каталога
+func (c ReasonCode) Format(args ...interface{}) string {
+	if m, ok := reasonCatalog[c]; ok {
+		return fmt.Sprintf(m.format, args...)
+	}
+	return fmt.Sprintf("%s %v", c, args)
+}
+
+// SensorFault сообщает, что причина - неисправность датчика, а не ошибка процедуры калибровки
+func (c ReasonCode) SensorFault() bool {
+	switch c {
+	case ReasonSensorStuck, ReasonSensorDropout, ReasonSensorRepeated, ReasonSensorJump, ReasonSensorRange:
+		return true
+	}
+	return false
+}
+
+// Known сообщает, что код есть в каталоге причин
+func (c ReasonCode) Known() bool {
+	_, ok := reasonCatalog[c]
+	return ok
+}
+
+// ReasonCodes возвращает все известные коды причин в алфавитном порядке
+func ReasonCodes() []ReasonCode {
+	codes := make([]ReasonCode, 0, len(reasonCatalog))
+	for c := range reasonCatalog {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// ReasonCount - количество компасов, отбракованных по одной причине
+type ReasonCount struct {
+	Reason ReasonCode
+	Count  int
+}
+
+// CountReasons считает отбракованные компасы по причинам; результат
+// упорядочен по убыванию количества (при равенстве - по коду)
+func CountReasons(results map[string]CompassResult) []ReasonCount {
+	counts := make(map[ReasonCode]int)
+	for _, r := range results {
+		if r.IsValid {
+			continue
+		}
+		counts[r.Reason]++
+	}
+	summary := make([]ReasonCount, 0, len(counts))
+	for reason, count := range counts {
+		summary = append(summary, ReasonCount{Reason: reason, Count: count})
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Count != summary[j].Count {
+			return summary[i].Count > summary[j].Count
+		}
+		return summary[i].Reason < summary[j].Reason
+	})
+	return summary
+}
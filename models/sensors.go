@@ -0,0 +1,29 @@
+package models
+
+// MagVector представляет показания магнитометра по трем осям.
+// Прибор передает каждую ось как 32-битное целое со знаком.
+type MagVector struct {
+	X int32 `json:"x"`
+	Y int32 `json:"y"`
+	Z int32 `json:"z"`
+}
+
+// AccelVector представляет показания акселерометра по трем осям.
+// Прибор передает каждую ось как 16-битное целое со знаком.
+type AccelVector struct {
+	X int16 `json:"x"`
+	Y int16 `json:"y"`
+	Z int16 `json:"z"`
+}
+
+// SensorVectors содержит декодированные векторы датчиков одной записи SB_CMPS.csv
+type SensorVectors struct {
+	// Magn - показания магнитометра (XYZmagn0-11)
+	Magn MagVector `json:"magn"`
+	// Axs - показания акселерометра (XYZaxs0-5)
+	Axs AccelVector `json:"axs"`
+	// MagnOffs - смещения магнитометра, сохраненные прибором (XYZmagnOffs0-11)
+	MagnOffs MagVector `json:"magnOffs"`
+	// AxsOffs - смещения акселерометра, сохраненные прибором (XYZaxsOffs0-5)
+	AxsOffs AccelVector `json:"axsOffs"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// TelemetryRecord представляет одну строку файла SB_CMPS.csv со всеми столбцами.
+// В отличие от CompassData, запись хранит данные датчиков среды, ориентацию
+// и служебные поля прибора, которые нужны для дополнительных проверок.
+// Отсутствующие в файле столбцы остаются нулевыми.
+type TelemetryRecord struct {
+	// Line - номер строки в исходном файле (с 1, с учетом заголовка)
+	Line int
+	// Time - время измерения (столбец «Отсчеты AB», Unix timestamp)
+	Time time.Time
+	// TimeString - строка даты и времени из столбца «Posix AB»
+	TimeString string
+
+	// Status - слово состояния прибора (столбец «Статус, hex»)
+	Status uint32
+	// Calibration - слово состояния калибровки (столбец «Калибровка, hex»)
+	Calibration uint32
+
+	// TemperatureHDC - температура датчика HDC1080, °C
+	TemperatureHDC float64
+	// HumidityHDC - относительная влажность датчика HDC1080, %
+	HumidityHDC float64
+	// TemperatureDPS - температура датчика DPS310, °C
+	TemperatureDPS float64
+	// PressureDPS - давление датчика DPS310, кПа
+	PressureDPS float64
+
+	// RotationRaw - код поворота (столбец «Поворот RAW»)
+	RotationRaw int
+	// AzimuthRaw - азимут без компенсации, градусы (анализируемый столбец)
+	AzimuthRaw float64
+	// PitchRaw - дифферент без компенсации, градусы
+	PitchRaw float64
+	// RollRaw - крен без компенсации, градусы
+	RollRaw float64
+	// Azimuth - компенсированный азимут, градусы
+	Azimuth float64
+	// Pitch - компенсированный дифферент, градусы
+	Pitch float64
+	// Roll - компенсированный крен, градусы
+	Roll float64
+
+	// KK - калибровочные коэффициенты КК0-KK17
+	KK [18]float64
+
+	// XYZMagn - байты показаний магнитометра (XYZmagn0-11)
+	XYZMagn [12]byte
+	// XYZAxs - байты показаний акселерометра (XYZaxs0-5)
+	XYZAxs [6]byte
+	// XYZMagnOffs - байты смещений магнитометра (XYZmagnOffs0-11)
+	XYZMagnOffs [12]byte
+	// XYZAxsOffs - байты смещений акселерометра (XYZaxsOffs0-5)
+	XYZAxsOffs [6]byte
+
+	// FWVersion - версия прошивки (столбец «FWver, hex»)
+	FWVersion uint32
+}
+
+// Коды поворота прибора (столбец «Поворот RAW»)
+const (
+	RotationNormal  = 0 // Прибор в рабочем положении
+	RotationFlipped = 1 // Прибор перевернут: компенсированные углы повернуты на 180°
+	// Коды 2 и 3 - прибор на боку (компенсированный крен отличается от RAW на ±90°),
+	// поворот компенсированного азимута по ним не определен
+)
+
+// CompassData возвращает данные измерения в формате, который использует анализатор
+func (r TelemetryRecord) CompassData() CompassData {
+	return CompassData{
+		Time:       r.Time,
+		Angle:      r.AzimuthRaw,
+		TimeString: r.TimeString,
+	}
+}
+
+// CompensatedAzimuth возвращает компенсированный азимут в системе координат
+// «Азимут RAW». Когда прибор перевернут (код поворота RotationFlipped), он
+// поворачивает систему координат компенсированных углов на 180°, и азимут
+// поворачивается обратно. Решение принимается только по коду поворота, без
+// сравнения с «Азимут RAW», поэтому грубая ошибка компенсации сохраняется.
+//
+// Возвращает:
+//   - float64: компенсированный азимут, градусы [0, 360)
+//   - bool: азимут повернут на 180°
+func (r TelemetryRecord) CompensatedAzimuth() (float64, bool) {
+	if r.RotationRaw == RotationFlipped {
+		return math.Mod(r.Azimuth+180+360, 360), true
+	}
+	return math.Mod(r.Azimuth+360, 360), false
+}
+
+// CompassDataFor возвращает данные измерения по выбранному столбцу азимута
+// (AzimuthColumnRaw, AzimuthColumnCompensated)
+func (r TelemetryRecord) CompassDataFor(column string) CompassData {
+	data := r.CompassData()
+	if column == AzimuthColumnCompensated {
+		data.Angle, _ = r.CompensatedAzimuth()
+	}
+	return data
+}
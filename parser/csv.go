@@ -36,30 +36,32 @@ func ParseUnixTime(timeStr string) (time.Time, error) {
 	return time.Unix(timestamp, 0), nil
 }
 
-// ReadCSVFile читает данные из CSV файла и преобразует их в массив CompassData.
-// Функция ожидает файл с разделителем ';' и следующими столбцами:
-// - A: Unix timestamp
-// - B: Строка времени (опционально)
-// - J: Значение угла азимута
-//
-// Параметры:
-//   - filePath: путь к CSV файлу
-//
-// Возвращает:
-//   - []models.CompassData: массив данных компаса
-//   - error: ошибка чтения или парсинга файла
-//
-// Пример:
-//
-//	data, err := ReadCSVFile("path/to/file.csv")
-//	if err != nil {
-//	    log.Fatal(err)
-//	}
-//	fmt.Printf("Прочитано %d записей\n", len(data))
-func ReadCSVFile(filePath string) ([]models.CompassData, error) {
+// Diagnostic описывает замечание к содержимому файла, обнаруженное при чтении.
+// Замечания не прерывают чтение, но передаются вызывающему коду, чтобы
+// исходный файл можно было проверить вручную.
+type Diagnostic struct {
+	// Line - номер строки в файле (с 1, с учетом заголовка), 0 - замечание ко всему файлу
+	Line int
+	// Message - текст замечания
+	Message string
+}
+
+// String возвращает замечание в виде строки для логов
+func (d Diagnostic) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("строка %d: %s", d.Line, d.Message)
+	}
+	return d.Message
+}
+
+// readRawCSV читает заголовок и записи CSV файла без какой-либо обработки
+// (байты полей не перекодируются). Если первая строка не похожа на заголовок,
+// заголовок возвращается как nil, а строка считается первой записью.
+// Файл открывается только на чтение.
+func readRawCSV(filePath string) ([]string, [][]string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+		return nil, nil, fmt.Errorf("ошибка открытия файла: %v", err)
 	}
 	defer file.Close()
 
@@ -67,105 +69,152 @@ func ReadCSVFile(filePath string) ([]models.CompassData, error) {
 	reader.Comma = ';'          // Разделитель в CSV файле
 	reader.FieldsPerRecord = -1 // Разрешаем разное количество полей в строках
 
-	// Пропускаем заголовок
-	if _, err := reader.Read(); err != nil {
-		return nil, fmt.Errorf("ошибка чтения заголовка: %v", err)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения заголовка: %v", err)
 	}
 
 	records, err := reader.ReadAll()
 	if err != nil {
-		return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+		return nil, nil, fmt.Errorf("ошибка чтения данных: %v", err)
 	}
 
-	// Сортируем записи по Unix timestamp в первом столбце
-	sort.SliceStable(records, func(i, j int) bool {
-		timestampI, errI := strconv.ParseInt(records[i][0], 10, 64)
-		if errI != nil {
-			// Если парсинг не удался, считаем, что текущий элемент больше для стабильной сортировки
-			return false
-		}
-		timestampJ, errJ := strconv.ParseInt(records[j][0], 10, 64)
-		if errJ != nil {
-			// Если парсинг не удался для j, считаем, что i меньше
-			return true
-		}
-		return timestampI < timestampJ
-	})
-
-	// Перезаписываем файл отсортированными данными
-	outFile, err := os.Create(filePath) // Открываем файл для записи (обрезая существующее содержимое)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка создания файла для записи отсортированных данных: %v", err)
+	if !looksLikeHeader(header) {
+		return nil, append([][]string{header}, records...), nil
 	}
-	defer outFile.Close()
 
-	writer := csv.NewWriter(outFile)
-	writer.Comma = ';' // Используем тот же разделитель
-
-	// Опционально: можно записать заголовок снова, если это необходимо.
-	// Пропускаем запись заголовка, так как мы не храним его отдельно.
+	return header, records, nil
+}
 
-	if err := writer.WriteAll(records); err != nil {
-		return nil, fmt.Errorf("ошибка записи отсортированных данных в файл: %v", err)
+// recordTimestamp возвращает Unix timestamp из первого столбца записи
+func recordTimestamp(record []string) (int64, bool) {
+	if len(record) == 0 {
+		return 0, false
 	}
-
-	writer.Flush()
-
-	if len(records) == 0 {
-		return nil, fmt.Errorf("файл не содержит данных")
+	timestamp, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
+	if err != nil {
+		return 0, false
 	}
+	return timestamp, true
+}
 
-	var data []models.CompassData
-	ignoredCount := 0 // Счетчик пропущенных записей из-за ошибок парсинга
+// checkTimestampOrder проверяет, что метки времени идут строго по возрастанию.
+// Нарушения порядка и повторы меток возвращаются как замечания;
+// строки с нечитаемым временем пропускаются (о них сообщает разбор строк).
+//
+// Параметры:
+//   - records: записи файла без заголовка
+//   - firstLine: номер строки файла, соответствующий records[0]
+func checkTimestampOrder(records [][]string, firstLine int) []Diagnostic {
+	var diagnostics []Diagnostic
+	var prev int64
+	havePrev := false
 
 	for i, record := range records {
-		if len(record) < 10 {
-			log.Printf("некорректная строка в файле меньше 10 символов в папке %s (строка %d): %v", filepath.Base(filepath.Dir(filePath)), i+2, record) // i+2 учитывает заголовок и 0-вой индекс записей
-			ignoredCount++
+		timestamp, ok := recordTimestamp(record)
+		if !ok {
 			continue
 		}
-
-		// Парсим время
-		timestampStr := strings.TrimSpace(record[0])
-		timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
-		if err != nil {
-			log.Printf("ошибка парсинга времени в папке %s (строка %d, значение '%s'): %v", filepath.Base(filepath.Dir(filePath)), i+2, timestampStr, err)
-			ignoredCount++
-			continue
+		if havePrev {
+			if timestamp < prev {
+				diagnostics = append(diagnostics, Diagnostic{
+					Line:    firstLine + i,
+					Message: fmt.Sprintf("метка времени %d меньше предыдущей %d (нарушен порядок записей)", timestamp, prev),
+				})
+			} else if timestamp == prev {
+				diagnostics = append(diagnostics, Diagnostic{
+					Line:    firstLine + i,
+					Message: fmt.Sprintf("метка времени %d повторяет предыдущую", timestamp),
+				})
+			}
 		}
+		prev = timestamp
+		havePrev = true
+	}
 
-		// Парсим угол из столбца J
-		angleStr := strings.TrimSpace(record[9])
-		// Заменяем запятую на точку для корректного парсинга
-		angleStr = strings.Replace(angleStr, ",", ".", -1)
-		angle, err := strconv.ParseFloat(angleStr, 64)
-		if err != nil {
-			log.Printf("ошибка парсинга угла в папке %s (строка %d, значение '%s'): %v", filepath.Base(filepath.Dir(filePath)), i+2, angleStr, err)
-			ignoredCount++
-			continue
-		}
+	return diagnostics
+}
 
-		// Получаем строку времени из столбца B
-		timeStringB := ""
-		if len(record) > 1 {
-			timeStringB = strings.TrimSpace(record[1])
+// timestampOrder возвращает порядок записей по Unix timestamp в первом столбце.
+// Сортировка стабильная, записи с нечитаемым временем уходят в конец.
+// Сами записи не переставляются, чтобы сохранить номера строк исходного файла.
+func timestampOrder(records [][]string) []int {
+	order := make([]int, len(records))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		timestampI, okI := recordTimestamp(records[order[i]])
+		if !okI {
+			// Если парсинг не удался, считаем, что текущий элемент больше для стабильной сортировки
+			return false
 		}
+		timestampJ, okJ := recordTimestamp(records[order[j]])
+		if !okJ {
+			// Если парсинг не удался для j, считаем, что i меньше
+			return true
+		}
+		return timestampI < timestampJ
+	})
+	return order
+}
 
-		data = append(data, models.CompassData{
-			Time:       time.Unix(timestamp, 0),
-			Angle:      angle,
-			TimeString: timeStringB,
-		})
+// ReadCSVFileWithDiagnostics читает данные из CSV файла и преобразует их в массив CompassData.
+// Файл открывается только на чтение и никогда не перезаписывается.
+// Для получения всех столбцов файла используйте ReadTelemetryFile.
+//
+// Столбцы определяются по заголовку файла (см. ParseHeader), заголовок может
+// быть записан в CP1251 или UTF-8. Если обязательный столбец отсутствует,
+// возвращается ошибка. Файлы без заголовка читаются по стандартному порядку
+// столбцов прошивки, о чем сообщается в замечаниях.
+//
+// Если метки времени в файле не упорядочены или повторяются, это сообщается
+// в списке замечаний, а данные упорядочиваются только в памяти.
+// Для сохранения упорядоченной копии файла используйте NormalizeCSVFile.
+//
+// Параметры:
+//   - filePath: путь к CSV файлу
+//
+// Возвращает:
+//   - []models.CompassData: массив данных компаса, упорядоченный по времени
+//   - []Diagnostic: замечания к содержимому файла
+//   - error: ошибка чтения или парсинга файла
+func ReadCSVFileWithDiagnostics(filePath string) ([]models.CompassData, []Diagnostic, error) {
+	records, diagnostics, err := ReadTelemetryFile(filePath)
+	if err != nil {
+		return nil, diagnostics, err
 	}
 
-	// Логируем количество пропущенных записей
-	if ignoredCount > 0 {
-		log.Printf("Пропущено %d записей в папке %s из-за ошибок парсинга времени или угла.", ignoredCount, filepath.Base(filepath.Dir(filePath)))
+	data := make([]models.CompassData, len(records))
+	for i, record := range records {
+		data[i] = record.CompassData()
 	}
 
-	if len(data) == 0 {
-		return nil, fmt.Errorf("не удалось прочитать ни одной валидной записи из файла")
-	}
+	return data, diagnostics, nil
+}
 
-	return data, nil
+// ReadCSVFile читает данные из CSV файла и преобразует их в массив CompassData.
+// Это обертка над ReadCSVFileWithDiagnostics, которая записывает замечания
+// к файлу в стандартный лог. Исходный файл не изменяется.
+//
+// Параметры:
+//   - filePath: путь к CSV файлу
+//
+// Возвращает:
+//   - []models.CompassData: массив данных компаса
+//   - error: ошибка чтения или парсинга файла
+//
+// Пример:
+//
+//	data, err := ReadCSVFile("path/to/file.csv")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Printf("Прочитано %d записей\n", len(data))
+func ReadCSVFile(filePath string) ([]models.CompassData, error) {
+	data, diagnostics, err := ReadCSVFileWithDiagnostics(filePath)
+	for _, d := range diagnostics {
+		log.Printf("замечание к файлу в папке %s: %s", filepath.Base(filepath.Dir(filePath)), d)
+	}
+	return data, err
 }
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CutFileName возвращает имя файла для копии с записями окна калибровки.
+// Копия кладется рядом с оригиналом с суффиксом _CALIB_AUTO, чтобы не
+// пересекаться с обрезанными вручную файлами _CALIB_CUTTED.
+//
+// Пример:
+//
+//	CutFileName("data/1908/SB_CMPS.csv") // вернет "data/1908/SB_CMPS_CALIB_AUTO.csv"
+func CutFileName(srcPath string) string {
+	ext := filepath.Ext(srcPath)
+	return strings.TrimSuffix(srcPath, ext) + "_CALIB_AUTO" + ext
+}
+
+// CutCSVFile сохраняет копию CSV файла только с записями, время которых
+// (столбец «Отсчеты AB») попадает в интервал [from, to], - так, как раньше
+// файл обрезали вручную. Записи упорядочиваются по времени, заголовок
+// (если он есть) и сами строки переносятся без изменений, в той же кодировке.
+// Файл назначения должен быть новым: существующий файл не перезаписывается.
+//
+// Параметры:
+//   - srcPath: путь к исходному CSV файлу
+//   - dstPath: путь к создаваемому файлу (пустая строка - CutFileName(srcPath))
+//   - from, to: время первой и последней записи окна
+//
+// Возвращает:
+//   - string: путь к созданному файлу
+//   - int: количество записанных записей
+//   - error: ошибка чтения или записи
+func CutCSVFile(srcPath, dstPath string, from, to time.Time) (string, int, error) {
+	if dstPath == "" {
+		dstPath = CutFileName(srcPath)
+	}
+
+	srcAbs, err := filepath.Abs(srcPath)
+	if err != nil {
+		return "", 0, fmt.Errorf("ошибка определения пути исходного файла: %v", err)
+	}
+	dstAbs, err := filepath.Abs(dstPath)
+	if err != nil {
+		return "", 0, fmt.Errorf("ошибка определения пути файла назначения: %v", err)
+	}
+	if srcAbs == dstAbs {
+		return "", 0, fmt.Errorf("файл назначения совпадает с исходным файлом: %s", srcPath)
+	}
+
+	header, records, err := readRawCSV(srcPath)
+	if err != nil {
+		return "", 0, err
+	}
+	var window [][]string
+	for _, idx := range timestampOrder(records) {
+		timestamp, ok := recordTimestamp(records[idx])
+		if ok && timestamp >= from.Unix() && timestamp <= to.Unix() {
+			window = append(window, records[idx])
+		}
+	}
+	if len(window) == 0 {
+		return "", 0, fmt.Errorf("в файле нет записей с %s по %s", from.UTC().Format("02.01.2006 15:04:05"), to.UTC().Format("02.01.2006 15:04:05"))
+	}
+
+	if err := writeNewCSV(dstPath, header, window); err != nil {
+		return "", 0, fmt.Errorf("ошибка записи окна калибровки: %v", err)
+	}
+
+	return dstPath, len(window), nil
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Кодировки, в которых встречаются файлы SB_CMPS.csv
+const (
+	EncodingUTF8    = "UTF-8"
+	EncodingUTF8BOM = "UTF-8 (BOM)"
+	EncodingCP1251  = "CP1251"
+)
+
+// utf8BOM - метка порядка байтов, которую добавляют некоторые редакторы (Excel, Блокнот)
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// cp1251High содержит символы Unicode для байтов 0x80-0xBF кодировки Windows-1251.
+// Байты 0xC0-0xFF соответствуют подряд идущим буквам А-я (U+0410-U+044F).
+var cp1251High = [64]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0xFFFD, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7,
+	0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7,
+	0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+}
+
+// decodeCP1251 преобразует текст в кодировке Windows-1251 в UTF-8
+func decodeCP1251(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(data) * 2)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			sb.WriteByte(b)
+		case b < 0xC0:
+			sb.WriteRune(cp1251High[b-0x80])
+		default:
+			sb.WriteRune(rune(b-0xC0) + 0x0410)
+		}
+	}
+	return sb.String()
+}
+
+// DecodeText определяет кодировку содержимого файла и возвращает текст в UTF-8.
+// Поддерживаются UTF-8 (с меткой BOM и без нее) и CP1251, в которой прибор
+// сохраняет заголовок SB_CMPS.csv. Текст, не являющийся корректным UTF-8,
+// считается записанным в CP1251.
+//
+// Параметры:
+//   - data: содержимое файла
+//
+// Возвращает:
+//   - string: текст в UTF-8
+//   - string: название обнаруженной кодировки
+//
+// Пример:
+//
+//	text, enc := DecodeText([]byte{0xC0, 0xE7}) // вернет "Аз", "CP1251"
+func DecodeText(data []byte) (string, string) {
+	if bytes.HasPrefix(data, utf8BOM) {
+		return string(data[len(utf8BOM):]), EncodingUTF8BOM
+	}
+	if utf8.Valid(data) {
+		return string(data), EncodingUTF8
+	}
+	return decodeCP1251(data), EncodingCP1251
+}
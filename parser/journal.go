@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JournalEntry - сообщение журнала блока AB (файл Journal_N.rtf рядом с SB_CMPS.csv)
+type JournalEntry struct {
+	Time    time.Time // Время сообщения (часы блока AB, как столбец «Posix AB»)
+	Message string    // Текст сообщения без разметки RTF
+}
+
+// Сообщения журнала, которыми блок AB отмечает этапы процедуры калибровки
+const (
+	// JournalCalibrationFinished - блок AB перешел к этапу «калибровка завершена»
+	JournalCalibrationFinished = "Calibration finished or not required"
+	// JournalRecordingStop - запись остановлена (прибор подключен к стойке)
+	JournalRecordingStop = "Recording stop"
+)
+
+// journalLinePattern - строка журнала: «23.05.2025 06:04:24 > сообщение»
+var journalLinePattern = regexp.MustCompile(`(\d{2}\.\d{2}\.\d{4} \d{2}:\d{2}:\d{2}) > (.*)`)
+
+// rtfControlPattern - управляющие слова и группы RTF, не несущие текста
+var rtfControlPattern = regexp.MustCompile(`\\[a-z]+-?\d* ?|[{}]`)
+
+// JournalFiles возвращает файлы журнала Journal_*.rtf из папки файла SB_CMPS.csv
+func JournalFiles(csvPath string) []string {
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(csvPath), "Journal_*.rtf"))
+	sort.Strings(files)
+	return files
+}
+
+// ReadJournal читает сообщения журнала блока AB из RTF-файла.
+// Журнал ведется кольцевым буфером, поэтому сообщения возвращаются
+// упорядоченными по времени, а не в порядке файла. Строки без метки
+// времени (продолжения сообщений, служебные строки) пропускаются.
+//
+// Параметры:
+//   - path: путь к файлу Journal_N.rtf
+//
+// Возвращает:
+//   - []JournalEntry: сообщения журнала по возрастанию времени
+//   - error: ошибка чтения файла
+func ReadJournal(path string) ([]JournalEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала: %v", err)
+	}
+	text, _ := DecodeText(content)
+
+	var entries []JournalEntry
+	for _, line := range strings.Split(text, `\par`) {
+		line = strings.TrimSpace(rtfControlPattern.ReplaceAllString(line, ""))
+		match := journalLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		t, err := time.Parse("02.01.2006 15:04:05", match[1])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, JournalEntry{Time: t, Message: strings.TrimSpace(match[2])})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+// ReadJournalFor читает все файлы журнала из папки файла SB_CMPS.csv
+// (см. JournalFiles) и объединяет их сообщения по времени. Журнал
+// не обязателен для анализа: файл, который не удалось прочитать,
+// пропускается с замечанием в стандартный лог. Если журнала в папке нет,
+// возвращает nil.
+func ReadJournalFor(csvPath string) []JournalEntry {
+	var entries []JournalEntry
+	for _, path := range JournalFiles(csvPath) {
+		fileEntries, err := ReadJournal(path)
+		if err != nil {
+			log.Printf("журнал блока AB в папке %s пропущен: %v", filepath.Base(filepath.Dir(path)), err)
+			continue
+		}
+		entries = append(entries, fileEntries...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries
+}
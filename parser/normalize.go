@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NormalizedFileName возвращает имя файла для упорядоченной копии исходного файла.
+// Копия кладется рядом с оригиналом с суффиксом _SORTED.
+//
+// Пример:
+//
+//	NormalizedFileName("data/1908/SB_CMPS.csv") // вернет "data/1908/SB_CMPS_SORTED.csv"
+func NormalizedFileName(srcPath string) string {
+	ext := filepath.Ext(srcPath)
+	return strings.TrimSuffix(srcPath, ext) + "_SORTED" + ext
+}
+
+// NormalizeCSVFile сохраняет копию CSV файла с записями, упорядоченными по времени.
+// Исходный файл не изменяется, заголовок (если он есть) переносится в копию
+// без изменений, в той же кодировке.
+// Файл назначения должен быть новым: существующий файл не перезаписывается.
+//
+// Параметры:
+//   - srcPath: путь к исходному CSV файлу
+//   - dstPath: путь к создаваемому файлу (пустая строка - NormalizedFileName(srcPath))
+//
+// Возвращает:
+//   - string: путь к созданному файлу
+//   - []Diagnostic: замечания к порядку записей в исходном файле
+//   - error: ошибка чтения или записи
+func NormalizeCSVFile(srcPath, dstPath string) (string, []Diagnostic, error) {
+	if dstPath == "" {
+		dstPath = NormalizedFileName(srcPath)
+	}
+
+	srcAbs, err := filepath.Abs(srcPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("ошибка определения пути исходного файла: %v", err)
+	}
+	dstAbs, err := filepath.Abs(dstPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("ошибка определения пути файла назначения: %v", err)
+	}
+	if srcAbs == dstAbs {
+		return "", nil, fmt.Errorf("файл назначения совпадает с исходным файлом: %s", srcPath)
+	}
+
+	header, records, err := readRawCSV(srcPath)
+	if err != nil {
+		return "", nil, err
+	}
+	firstLine := 2
+	if header == nil {
+		firstLine = 1
+	}
+	diagnostics := checkTimestampOrder(records, firstLine)
+
+	sorted := make([][]string, 0, len(records))
+	for _, idx := range timestampOrder(records) {
+		sorted = append(sorted, records[idx])
+	}
+	if err := writeNewCSV(dstPath, header, sorted); err != nil {
+		return "", diagnostics, fmt.Errorf("ошибка записи отсортированных данных: %v", err)
+	}
+
+	return dstPath, diagnostics, nil
+}
+
+// writeNewCSV создает новый CSV файл с разделителем «;» и записывает в него
+// заголовок (если он есть) и строки. Существующий файл не перезаписывается.
+// Если запись или закрытие файла не удались, недописанный файл удаляется -
+// иначе повторный запуск упирался бы в «файл уже существует».
+//
+// Параметры:
+//   - path: путь к создаваемому файлу
+//   - header: заголовок (nil - файл без заголовка)
+//   - rows: строки данных
+//
+// Возвращает:
+//   - error: ошибка создания, записи или закрытия файла
+func writeNewCSV(path string, header []string, rows [][]string) (err error) {
+	// O_EXCL гарантирует, что мы не затрем существующий файл
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла: %v", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("ошибка закрытия файла: %v", closeErr)
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = ';' // Используем тот же разделитель
+	if header != nil {
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("ошибка записи заголовка: %v", err)
+		}
+	}
+	// WriteAll сбрасывает буфер и возвращает ошибку записи
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("ошибка записи строк: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Column обозначает столбец файла SB_CMPS.csv независимо от его позиции в файле
+type Column int
+
+// Столбцы файла SB_CMPS.csv в стандартном порядке прошивки
+const (
+	ColTicks       Column = iota // Отсчеты AB - Unix timestamp записи
+	ColPosix                     // Posix AB - строка даты и времени
+	ColStatus                    // Статус, hex
+	ColCalibration               // Калибровка, hex
+	ColTempHDC                   // Температура HDC1080, C
+	ColHumidityHDC               // Влажность HDC1080, %
+	ColTempDPS                   // Температура DPS310, C
+	ColPressureDPS               // Давление DPS310, кПа
+	ColRotationRaw               // Поворот RAW
+	ColAzimuthRaw                // Азимут RAW, град - анализируемый азимут
+	ColPitchRaw                  // Дифферент RAW, град
+	ColRollRaw                   // Крен RAW, град
+	ColAzimuth                   // Азимут, град (компенсированный)
+	ColPitch                     // Дифферент, град
+	ColRoll                      // Крен, град
+	ColKK0                       // КК0-KK17 - калибровочные коэффициенты
+
+	ColXYZMagn0     = ColKK0 + KKCount               // XYZmagn0-11 - байты магнитометра
+	ColXYZAxs0      = ColXYZMagn0 + XYZMagnCount     // XYZaxs0-5 - байты акселерометра
+	ColXYZMagnOffs0 = ColXYZAxs0 + XYZAxsCount       // XYZmagnOffs0-11 - байты смещений магнитометра
+	ColXYZAxsOffs0  = ColXYZMagnOffs0 + XYZMagnCount // XYZaxsOffs0-5 - байты смещений акселерометра
+	ColFWVer        = ColXYZAxsOffs0 + XYZAxsCount   // FWver, hex
+
+	// ColumnCount - количество столбцов в стандартном формате файла
+	ColumnCount = int(ColFWVer) + 1
+)
+
+// Размеры групп столбцов
+const (
+	KKCount      = 18 // КК0-KK17
+	XYZMagnCount = 12 // XYZmagn0-11 (3 оси по 4 байта)
+	XYZAxsCount  = 6  // XYZaxs0-5 (3 оси по 2 байта)
+)
+
+// requiredColumns - столбцы, без которых анализ невозможен
+var requiredColumns = []Column{ColTicks, ColAzimuthRaw}
+
+// columnNames содержит допустимые названия столбцов в заголовке.
+// Первое название - каноническое (так пишет заголовок прошивка с русской локалью).
+var columnNames = buildColumnNames()
+
+func buildColumnNames() [ColumnCount][]string {
+	var names [ColumnCount][]string
+	names[ColTicks] = []string{"Отсчеты AB", "AB ticks"}
+	names[ColPosix] = []string{"Posix AB", "AB time"}
+	names[ColStatus] = []string{"Статус, hex", "Status, hex"}
+	names[ColCalibration] = []string{"Калибровка, hex", "Calibration, hex"}
+	names[ColTempHDC] = []string{"Температура HDC1080, C", "Temperature HDC1080, C"}
+	names[ColHumidityHDC] = []string{"Влажность HDC1080, %", "Humidity HDC1080, %"}
+	names[ColTempDPS] = []string{"Температура DPS310, C", "Temperature DPS310, C"}
+	names[ColPressureDPS] = []string{"Давление DPS310, кПа", "Pressure DPS310, kPa"}
+	names[ColRotationRaw] = []string{"Поворот RAW", "Rotation code RAW"}
+	names[ColAzimuthRaw] = []string{"Азимут RAW, град", "Azimuth RAW, deg."}
+	names[ColPitchRaw] = []string{"Дифферент RAW, град", "Pitch RAW, deg."}
+	names[ColRollRaw] = []string{"Крен RAW, град", "Roll RAW, deg."}
+	names[ColAzimuth] = []string{"Азимут, град", "Azimuth, deg."}
+	names[ColPitch] = []string{"Дифферент, град", "Pitch, deg."}
+	names[ColRoll] = []string{"Крен, град", "Roll, deg."}
+	for i := 0; i < KKCount; i++ {
+		// В заголовке прошивки КК0 написан кириллицей, остальные - латиницей
+		names[ColKK0+Column(i)] = []string{fmt.Sprintf("KK%d", i), fmt.Sprintf("КК%d", i), fmt.Sprintf("CC%d", i)}
+	}
+	for i := 0; i < XYZMagnCount; i++ {
+		names[ColXYZMagn0+Column(i)] = []string{fmt.Sprintf("XYZmagn%d", i)}
+		names[ColXYZMagnOffs0+Column(i)] = []string{fmt.Sprintf("XYZmagnOffs%d", i)}
+	}
+	for i := 0; i < XYZAxsCount; i++ {
+		names[ColXYZAxs0+Column(i)] = []string{fmt.Sprintf("XYZaxs%d", i)}
+		names[ColXYZAxsOffs0+Column(i)] = []string{fmt.Sprintf("XYZaxsOffs%d", i)}
+	}
+	names[ColFWVer] = []string{"FWver, hex"}
+	return names
+}
+
+// String возвращает каноническое название столбца
+func (c Column) String() string {
+	if c < 0 || int(c) >= ColumnCount {
+		return fmt.Sprintf("столбец #%d", int(c))
+	}
+	return columnNames[c][0]
+}
+
+// normalizeHeaderName приводит название столбца к виду для сравнения:
+// нижний регистр, без лишних пробелов
+func normalizeHeaderName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// columnByName - обратный индекс нормализованных названий
+var columnByName = func() map[string]Column {
+	m := make(map[string]Column)
+	for c, names := range columnNames {
+		for _, name := range names {
+			m[normalizeHeaderName(name)] = Column(c)
+		}
+	}
+	return m
+}()
+
+// Schema описывает расположение столбцов в конкретном файле SB_CMPS.csv.
+// Схема строится по заголовку файла, поэтому перестановка столбцов в новой
+// прошивке не приводит к чтению не того поля.
+type Schema struct {
+	// HasHeader - true, если схема построена по заголовку файла
+	HasHeader bool
+	// Encoding - кодировка, в которой записан файл
+	Encoding string
+	// index хранит номер поля для каждого столбца (-1 - столбец отсутствует)
+	index [ColumnCount]int
+}
+
+// DefaultSchema возвращает схему со стандартным порядком столбцов прошивки.
+// Используется для файлов без заголовка.
+func DefaultSchema() *Schema {
+	s := &Schema{}
+	for c := range s.index {
+		s.index[c] = c
+	}
+	return s
+}
+
+// ParseHeader строит схему по строке заголовка.
+// Неизвестные столбцы игнорируются, а столбцы на нестандартных позициях
+// сообщаются как замечания.
+//
+// Параметры:
+//   - header: поля строки заголовка (в UTF-8)
+//
+// Возвращает:
+//   - *Schema: схема файла
+//   - []Diagnostic: замечания к заголовку
+//   - error: ошибка, если обязательный столбец отсутствует или указан дважды
+func ParseHeader(header []string) (*Schema, []Diagnostic, error) {
+	s := &Schema{HasHeader: true}
+	for c := range s.index {
+		s.index[c] = -1
+	}
+
+	var diagnostics []Diagnostic
+	for i, name := range header {
+		c, ok := columnByName[normalizeHeaderName(name)]
+		if !ok {
+			continue
+		}
+		if prev := s.index[c]; prev >= 0 {
+			if isRequired(c) {
+				return nil, diagnostics, fmt.Errorf("обязательный столбец «%s» указан в заголовке дважды (позиции %d и %d)", c, prev+1, i+1)
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:    1,
+				Message: fmt.Sprintf("столбец «%s» указан дважды (позиции %d и %d), используется первый", c, prev+1, i+1),
+			})
+			continue
+		}
+		s.index[c] = i
+	}
+
+	for _, c := range requiredColumns {
+		if s.index[c] < 0 {
+			return nil, diagnostics, fmt.Errorf("обязательный столбец «%s» не найден в заголовке (ожидался в позиции %d)", c, int(c)+1)
+		}
+	}
+
+	for c, idx := range s.index {
+		if idx >= 0 && idx != c {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:    1,
+				Message: fmt.Sprintf("столбец «%s» находится в позиции %d вместо стандартной %d", Column(c), idx+1, c+1),
+			})
+		}
+	}
+
+	return s, diagnostics, nil
+}
+
+// isRequired проверяет, является ли столбец обязательным
+func isRequired(c Column) bool {
+	for _, r := range requiredColumns {
+		if r == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Index возвращает номер поля столбца в записи
+func (s *Schema) Index(c Column) (int, bool) {
+	if c < 0 || int(c) >= ColumnCount || s.index[c] < 0 {
+		return 0, false
+	}
+	return s.index[c], true
+}
+
+// Field возвращает значение столбца из записи (без пробелов по краям).
+// Если столбца нет в схеме или запись короче, возвращается false.
+func (s *Schema) Field(record []string, c Column) (string, bool) {
+	idx, ok := s.Index(c)
+	if !ok || idx >= len(record) {
+		return "", false
+	}
+	return strings.TrimSpace(record[idx]), true
+}
+
+// MinFields возвращает минимальное количество полей в записи,
+// при котором все обязательные столбцы присутствуют
+func (s *Schema) MinFields() int {
+	minFields := 0
+	for _, c := range requiredColumns {
+		if s.index[c]+1 > minFields {
+			minFields = s.index[c] + 1
+		}
+	}
+	return minFields
+}
+
+// looksLikeHeader проверяет, является ли первая строка файла заголовком.
+// Строки данных всегда начинаются с числового Unix timestamp.
+func looksLikeHeader(record []string) bool {
+	_, ok := recordTimestamp(record)
+	return !ok
+}
+
+// significantFields возвращает количество полей записи без пустых полей в конце
+// (прошивка часто завершает строку лишним разделителем ';')
+func significantFields(record []string) int {
+	n := len(record)
+	for n > 0 && strings.TrimSpace(record[n-1]) == "" {
+		n--
+	}
+	return n
+}
+
+// parseDecimal парсит число с запятой или точкой в качестве десятичного разделителя
+func parseDecimal(value string) (float64, error) {
+	value = strings.Replace(strings.TrimSpace(value), ",", ".", -1)
+	return strconv.ParseFloat(value, 64)
+}
+
+// readSchemaCSV читает CSV файл, определяет кодировку и схему столбцов.
+// Файл открывается только на чтение.
+//
+// Возвращает:
+//   - *Schema: схема файла (стандартная, если заголовка нет)
+//   - [][]string: записи без заголовка
+//   - int: номер строки файла, соответствующий первой записи
+//   - []Diagnostic: замечания к кодировке и заголовку
+//   - error: ошибка чтения или несоответствия заголовка
+func readSchemaCSV(filePath string) (*Schema, [][]string, int, []Diagnostic, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, 0, nil, fmt.Errorf("ошибка открытия файла: %v", err)
+	}
+	text, encoding := DecodeText(content)
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = ';'          // Разделитель в CSV файле
+	reader.FieldsPerRecord = -1 // Разрешаем разное количество полей в строках
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, 0, nil, fmt.Errorf("ошибка чтения данных: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, 0, nil, fmt.Errorf("ошибка чтения заголовка: %w", io.EOF)
+	}
+
+	var diagnostics []Diagnostic
+	if !looksLikeHeader(records[0]) {
+		schema := DefaultSchema()
+		schema.Encoding = encoding
+		diagnostics = append(diagnostics, Diagnostic{
+			Message: "файл не содержит заголовка, используется стандартный порядок столбцов SB_CMPS",
+		})
+		if n := significantFields(records[0]); n != ColumnCount {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:    1,
+				Message: fmt.Sprintf("в записи %d полей вместо %d, расположение столбцов не может быть проверено", n, ColumnCount),
+			})
+		}
+		return schema, records, 1, diagnostics, nil
+	}
+
+	schema, headerDiagnostics, err := ParseHeader(records[0])
+	diagnostics = append(diagnostics, headerDiagnostics...)
+	if err != nil {
+		return nil, nil, 0, diagnostics, err
+	}
+	schema.Encoding = encoding
+
+	return schema, records[1:], 2, diagnostics, nil
+}
@@ -0,0 +1,296 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"compass_analyzer/models"
+)
+
+// Ошибки чтения, означающие, что в файле нет данных для анализа
+// (проверяются через IsNoData)
+var (
+	// ErrNoData - в файле есть заголовок, но нет ни одной строки данных
+	ErrNoData = errors.New("файл не содержит данных")
+	// ErrNoValidRecords - ни в одной строке не удалось разобрать время и азимут
+	ErrNoValidRecords = errors.New("не удалось прочитать ни одной валидной записи из файла")
+)
+
+// IsNoData сообщает, что ошибка чтения означает отсутствие данных в файле:
+// пустой файл, только заголовок или ни одной валидной записи
+func IsNoData(err error) bool {
+	return errors.Is(err, ErrNoData) || errors.Is(err, ErrNoValidRecords) || errors.Is(err, io.EOF)
+}
+
+// ReadErrorReason определяет код причины отбраковки и пояснение для ошибки
+// чтения файла данных (см. ReadTelemetry): PARSE_EMPTY, если в файле нет данных,
+// иначе PARSE_ERROR
+func ReadErrorReason(err error) (models.ReasonCode, string) {
+	switch {
+	case errors.Is(err, io.EOF):
+		return models.ReasonParseEmpty, "пустой файл"
+	case errors.Is(err, ErrNoData):
+		return models.ReasonParseEmpty, "файл содержит только заголовок"
+	case IsNoData(err):
+		return models.ReasonParseEmpty, err.Error()
+	default:
+		return models.ReasonParseError, err.Error()
+	}
+}
+
+// columnIssue накапливает ошибки разбора необязательного столбца,
+// чтобы не выводить отдельное замечание на каждую строку
+type columnIssue struct {
+	count      int
+	firstLine  int
+	firstValue string
+}
+
+// recordParser разбирает записи файла по схеме столбцов
+type recordParser struct {
+	schema *Schema
+	issues map[Column]*columnIssue
+}
+
+// fail запоминает ошибку разбора необязательного столбца
+func (p *recordParser) fail(c Column, line int, value string) {
+	issue, ok := p.issues[c]
+	if !ok {
+		issue = &columnIssue{firstLine: line, firstValue: value}
+		p.issues[c] = issue
+	}
+	issue.count++
+}
+
+// decimal возвращает значение столбца как число с плавающей точкой
+func (p *recordParser) decimal(record []string, c Column, line int) float64 {
+	value, ok := p.schema.Field(record, c)
+	if !ok || value == "" {
+		return 0
+	}
+	v, err := parseDecimal(value)
+	if err != nil {
+		p.fail(c, line, value)
+		return 0
+	}
+	return v
+}
+
+// integer возвращает значение столбца как целое число
+func (p *recordParser) integer(record []string, c Column, line int) int {
+	value, ok := p.schema.Field(record, c)
+	if !ok || value == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		p.fail(c, line, value)
+		return 0
+	}
+	return v
+}
+
+// hex возвращает значение шестнадцатеричного столбца (с префиксом 0x или без него)
+func (p *recordParser) hex(record []string, c Column, line int) uint32 {
+	value, ok := p.schema.Field(record, c)
+	if !ok || value == "" {
+		return 0
+	}
+	v, err := parseHex(value)
+	if err != nil {
+		p.fail(c, line, value)
+		return 0
+	}
+	return v
+}
+
+// byteValue возвращает значение столбца как байт (0-255)
+func (p *recordParser) byteValue(record []string, c Column, line int) byte {
+	value, ok := p.schema.Field(record, c)
+	if !ok || value == "" {
+		return 0
+	}
+	v, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		p.fail(c, line, value)
+		return 0
+	}
+	return byte(v)
+}
+
+// diagnostics возвращает накопленные ошибки разбора в порядке столбцов
+func (p *recordParser) diagnostics() []Diagnostic {
+	var result []Diagnostic
+	for c := Column(0); int(c) < ColumnCount; c++ {
+		issue, ok := p.issues[c]
+		if !ok {
+			continue
+		}
+		result = append(result, Diagnostic{
+			Line: issue.firstLine,
+			Message: fmt.Sprintf("столбец «%s»: не разобрано значений - %d (первое '%s'), использован 0",
+				c, issue.count, issue.firstValue),
+		})
+	}
+	return result
+}
+
+// parseHex парсит шестнадцатеричное значение, например "0x06", "1F" или "10"
+func parseHex(value string) (uint32, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	v, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(v), nil
+}
+
+// ReadTelemetryFile читает все столбцы файла SB_CMPS.csv в массив TelemetryRecord.
+// Файл открывается только на чтение, столбцы определяются по заголовку
+// (см. ReadCSVFileWithDiagnostics).
+//
+// Строки, в которых не удалось разобрать время или азимут, пропускаются.
+// Ошибки в остальных столбцах не приводят к пропуску строки: значение
+// заменяется нулем, а по каждому столбцу выдается одно сводное замечание.
+//
+// Параметры:
+//   - filePath: путь к CSV файлу
+//
+// Возвращает:
+//   - []models.TelemetryRecord: записи, упорядоченные по времени
+//   - []Diagnostic: замечания к содержимому файла
+//   - error: ошибка чтения файла или отсутствие валидных записей
+//
+// Пример:
+//
+//	records, diagnostics, err := ReadTelemetryFile("path/to/SB_CMPS.csv")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Printf("Температура в начале: %.1f°C\n", records[0].TemperatureDPS)
+func ReadTelemetryFile(filePath string) ([]models.TelemetryRecord, []Diagnostic, error) {
+	schema, records, firstLine, diagnostics, err := readSchemaCSV(filePath)
+	if err != nil {
+		return nil, diagnostics, err
+	}
+
+	if len(records) == 0 {
+		return nil, diagnostics, ErrNoData
+	}
+
+	if orderDiagnostics := checkTimestampOrder(records, firstLine); len(orderDiagnostics) > 0 {
+		diagnostics = append(diagnostics, orderDiagnostics...)
+		diagnostics = append(diagnostics, Diagnostic{
+			Message: "записи упорядочены по времени только в памяти, исходный файл не изменен",
+		})
+	}
+
+	p := &recordParser{schema: schema, issues: make(map[Column]*columnIssue)}
+	var result []models.TelemetryRecord
+	ignoredCount := 0 // Счетчик пропущенных записей из-за ошибок парсинга
+	folder := filepath.Base(filepath.Dir(filePath))
+	minFields := schema.MinFields()
+
+	for _, idx := range timestampOrder(records) {
+		record := records[idx]
+		line := idx + firstLine
+
+		if len(record) < minFields {
+			log.Printf("некорректная строка в файле меньше %d полей в папке %s (строка %d): %v", minFields, folder, line, record)
+			ignoredCount++
+			continue
+		}
+
+		// Парсим время
+		timestampStr, _ := schema.Field(record, ColTicks)
+		timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+		if err != nil {
+			log.Printf("ошибка парсинга времени в папке %s (строка %d, значение '%s'): %v", folder, line, timestampStr, err)
+			ignoredCount++
+			continue
+		}
+
+		// Парсим угол из столбца «Азимут RAW»
+		angleStr, _ := schema.Field(record, ColAzimuthRaw)
+		angle, err := parseDecimal(angleStr)
+		if err != nil {
+			log.Printf("ошибка парсинга угла в папке %s (строка %d, значение '%s'): %v", folder, line, angleStr, err)
+			ignoredCount++
+			continue
+		}
+
+		// Получаем строку времени из столбца «Posix AB» (опционально)
+		timeString, _ := schema.Field(record, ColPosix)
+
+		rec := models.TelemetryRecord{
+			Line:           line,
+			Time:           time.Unix(timestamp, 0),
+			TimeString:     timeString,
+			Status:         p.hex(record, ColStatus, line),
+			Calibration:    p.hex(record, ColCalibration, line),
+			TemperatureHDC: p.decimal(record, ColTempHDC, line),
+			HumidityHDC:    p.decimal(record, ColHumidityHDC, line),
+			TemperatureDPS: p.decimal(record, ColTempDPS, line),
+			PressureDPS:    p.decimal(record, ColPressureDPS, line),
+			RotationRaw:    p.integer(record, ColRotationRaw, line),
+			AzimuthRaw:     angle,
+			PitchRaw:       p.decimal(record, ColPitchRaw, line),
+			RollRaw:        p.decimal(record, ColRollRaw, line),
+			Azimuth:        p.decimal(record, ColAzimuth, line),
+			Pitch:          p.decimal(record, ColPitch, line),
+			Roll:           p.decimal(record, ColRoll, line),
+			FWVersion:      p.hex(record, ColFWVer, line),
+		}
+		for i := range rec.KK {
+			rec.KK[i] = p.decimal(record, ColKK0+Column(i), line)
+		}
+		for i := range rec.XYZMagn {
+			rec.XYZMagn[i] = p.byteValue(record, ColXYZMagn0+Column(i), line)
+			rec.XYZMagnOffs[i] = p.byteValue(record, ColXYZMagnOffs0+Column(i), line)
+		}
+		for i := range rec.XYZAxs {
+			rec.XYZAxs[i] = p.byteValue(record, ColXYZAxs0+Column(i), line)
+			rec.XYZAxsOffs[i] = p.byteValue(record, ColXYZAxsOffs0+Column(i), line)
+		}
+
+		result = append(result, rec)
+	}
+
+	diagnostics = append(diagnostics, p.diagnostics()...)
+
+	// Логируем количество пропущенных записей
+	if ignoredCount > 0 {
+		log.Printf("Пропущено %d записей в папке %s из-за ошибок парсинга времени или угла.", ignoredCount, folder)
+	}
+
+	if len(result) == 0 {
+		return nil, diagnostics, ErrNoValidRecords
+	}
+
+	return result, diagnostics, nil
+}
+
+// ReadTelemetry читает записи телеметрии из CSV файла.
+// Это обертка над ReadTelemetryFile, которая записывает замечания
+// к файлу в стандартный лог (аналогично ReadCSVFile).
+//
+// Параметры:
+//   - filePath: путь к CSV файлу
+//
+// Возвращает:
+//   - []models.TelemetryRecord: записи, упорядоченные по времени
+//   - error: ошибка чтения или парсинга файла
+func ReadTelemetry(filePath string) ([]models.TelemetryRecord, error) {
+	records, diagnostics, err := ReadTelemetryFile(filePath)
+	for _, d := range diagnostics {
+		log.Printf("замечание к файлу в папке %s: %s", filepath.Base(filepath.Dir(filePath)), d)
+	}
+	return records, err
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"encoding/binary"
+
+	"compass_analyzer/models"
+)
+
+// Столбцы XYZmagn*, XYZaxs* и соответствующие *Offs содержат байты векторов
+// датчиков в порядке little-endian: сначала младший байт оси X, затем Y и Z.
+// Например, байты 151;236;255;255 дают int32 0xFFFFEC97 = -4969.
+
+// DecodeMagnetometer преобразует 12 байт столбцов XYZmagn0-11 (или XYZmagnOffs0-11)
+// в вектор магнитометра из трех int32.
+//
+// Пример:
+//
+//	v := DecodeMagnetometer([12]byte{151, 236, 255, 255, 163, 7, 0, 0, 138, 15, 0, 0})
+//	// v = {X: -4969, Y: 1955, Z: 3978}
+func DecodeMagnetometer(b [12]byte) models.MagVector {
+	return models.MagVector{
+		X: int32(binary.LittleEndian.Uint32(b[0:4])),
+		Y: int32(binary.LittleEndian.Uint32(b[4:8])),
+		Z: int32(binary.LittleEndian.Uint32(b[8:12])),
+	}
+}
+
+// DecodeAccelerometer преобразует 6 байт столбцов XYZaxs0-5 (или XYZaxsOffs0-5)
+// в вектор акселерометра из трех int16.
+//
+// Пример:
+//
+//	v := DecodeAccelerometer([6]byte{0, 0, 255, 255, 1, 0}) // v = {X: 0, Y: -1, Z: 1}
+func DecodeAccelerometer(b [6]byte) models.AccelVector {
+	return models.AccelVector{
+		X: int16(binary.LittleEndian.Uint16(b[0:2])),
+		Y: int16(binary.LittleEndian.Uint16(b[2:4])),
+		Z: int16(binary.LittleEndian.Uint16(b[4:6])),
+	}
+}
+
+// DecodeSensorVectors декодирует все векторы датчиков из записи телеметрии
+func DecodeSensorVectors(r models.TelemetryRecord) models.SensorVectors {
+	return models.SensorVectors{
+		Magn:     DecodeMagnetometer(r.XYZMagn),
+		Axs:      DecodeAccelerometer(r.XYZAxs),
+		MagnOffs: DecodeMagnetometer(r.XYZMagnOffs),
+		AxsOffs:  DecodeAccelerometer(r.XYZAxsOffs),
+	}
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"testing"
+
+	"compass_analyzer/models"
+)
+
+// Строки из SB_CMPS.csv в корне репозитория (номер строки файла и байты XYZ-столбцов)
+var sensorRows = []struct {
+	line     int
+	magn     [12]byte
+	axs      [6]byte
+	magnOffs [12]byte
+	axsOffs  [6]byte
+	want     models.SensorVectors
+}{
+	{
+		line:     2,
+		magn:     [12]byte{151, 236, 255, 255, 163, 7, 0, 0, 138, 15, 0, 0},
+		axs:      [6]byte{4, 0, 9, 0, 7, 1},
+		magnOffs: [12]byte{},
+		axsOffs:  [6]byte{0, 0, 255, 255, 1, 0},
+		want: models.SensorVectors{
+			Magn:     models.MagVector{X: -4969, Y: 1955, Z: 3978},
+			Axs:      models.AccelVector{X: 4, Y: 9, Z: 263},
+			MagnOffs: models.MagVector{},
+			AxsOffs:  models.AccelVector{X: 0, Y: -1, Z: 1},
+		},
+	},
+	{
+		line:     60,
+		magn:     [12]byte{46, 2, 0, 0, 166, 255, 255, 255, 183, 15, 0, 0},
+		axs:      [6]byte{3, 0, 7, 0, 7, 1},
+		magnOffs: [12]byte{30, 241, 255, 255, 16, 8, 0, 0, 0, 0, 0, 0},
+		axsOffs:  [6]byte{255, 255, 255, 255, 1, 0},
+		want: models.SensorVectors{
+			Magn:     models.MagVector{X: 558, Y: -90, Z: 4023},
+			Axs:      models.AccelVector{X: 3, Y: 7, Z: 263},
+			MagnOffs: models.MagVector{X: -3810, Y: 2064, Z: 0},
+			AxsOffs:  models.AccelVector{X: -1, Y: -1, Z: 1},
+		},
+	},
+	{
+		line:     123,
+		magn:     [12]byte{177, 251, 255, 255, 201, 250, 255, 255, 87, 251, 255, 255},
+		axs:      [6]byte{4, 0, 5, 0, 6, 1},
+		magnOffs: [12]byte{30, 241, 255, 255, 16, 8, 0, 0, 218, 21, 0, 0},
+		axsOffs:  [6]byte{255, 255, 255, 255, 1, 0},
+		want: models.SensorVectors{
+			Magn:     models.MagVector{X: -1103, Y: -1335, Z: -1193},
+			Axs:      models.AccelVector{X: 4, Y: 5, Z: 262},
+			MagnOffs: models.MagVector{X: -3810, Y: 2064, Z: 5594},
+			AxsOffs:  models.AccelVector{X: -1, Y: -1, Z: 1},
+		},
+	},
+}
+
+func TestDecodeSensorVectors(t *testing.T) {
+	for _, row := range sensorRows {
+		got := DecodeSensorVectors(models.TelemetryRecord{
+			XYZMagn:     row.magn,
+			XYZAxs:      row.axs,
+			XYZMagnOffs: row.magnOffs,
+			XYZAxsOffs:  row.axsOffs,
+		})
+		if got != row.want {
+			t.Errorf("строка %d: получено %+v, ожидалось %+v", row.line, got, row.want)
+		}
+	}
+}
+
+func TestDecodeMagnetometerExtremes(t *testing.T) {
+	tests := []struct {
+		b    [12]byte
+		want models.MagVector
+	}{
+		{[12]byte{255, 255, 255, 127, 0, 0, 0, 128, 255, 255, 255, 255}, models.MagVector{X: 2147483647, Y: -2147483648, Z: -1}},
+		{[12]byte{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0}, models.MagVector{X: 1, Y: 256, Z: 65536}},
+	}
+	for _, tt := range tests {
+		if got := DecodeMagnetometer(tt.b); got != tt.want {
+			t.Errorf("DecodeMagnetometer(%v) = %+v, ожидалось %+v", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeAccelerometerExtremes(t *testing.T) {
+	tests := []struct {
+		b    [6]byte
+		want models.AccelVector
+	}{
+		{[6]byte{255, 127, 0, 128, 255, 255}, models.AccelVector{X: 32767, Y: -32768, Z: -1}},
+		{[6]byte{0, 1, 1, 0, 0, 0}, models.AccelVector{X: 256, Y: 1, Z: 0}},
+	}
+	for _, tt := range tests {
+		if got := DecodeAccelerometer(tt.b); got != tt.want {
+			t.Errorf("DecodeAccelerometer(%v) = %+v, ожидалось %+v", tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestDecodeSensorVectorsFromFile проверяет декодирование на записях,
+// прочитанных из SB_CMPS.csv, а не только на байтах, заданных вручную
+func TestDecodeSensorVectorsFromFile(t *testing.T) {
+	records, _, err := ReadTelemetryFile("../SB_CMPS.csv")
+	if err != nil {
+		t.Fatalf("ошибка чтения SB_CMPS.csv: %v", err)
+	}
+
+	byLine := make(map[int]models.TelemetryRecord, len(records))
+	for _, r := range records {
+		byLine[r.Line] = r
+	}
+
+	for _, row := range sensorRows {
+		r, ok := byLine[row.line]
+		if !ok {
+			t.Errorf("строка %d не найдена в прочитанных записях", row.line)
+			continue
+		}
+		if got := DecodeSensorVectors(r); got != row.want {
+			t.Errorf("строка %d: получено %+v, ожидалось %+v", row.line, got, row.want)
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 // AnalysisRequest представляет запрос на анализ
 type AnalysisRequest struct {
 	FolderPath string `json:"folderPath"`
+	Profile    string `json:"profile"` // Имя профиля процедуры (пусто - профиль по умолчанию)
 }
 
 // AnalysisResponse представляет ответ с результатами анализа
@@ -24,11 +25,31 @@ type AnalysisResponse struct {
 	Success    bool           `json:"success"`
 	IsValid    bool           `json:"isValid"`
 	Compass    string         `json:"compass"`
+	Params     models.AnalysisParams `json:"params"`
+	Profile    string         `json:"profile"`
+	Segmenter  string         `json:"segmenter"` // Алгоритм поиска стабильных сегментов
 	Turns      []models.Turn  `json:"turns"`
 	AllAngles  []float64      `json:"allAngles"`
 	Segments   []SegmentInfo  `json:"segments"`
+	Reason     models.ReasonCode `json:"reason,omitempty"` // Код первой причины отбраковки
+	Quality    float64        `json:"quality"`     // Оценка качества калибровки, 0–100
+	NeedsRetest bool          `json:"needsRetest"` // Годен, но рекомендуется перепроверка
+	Checks     []models.Check `json:"checks"`     // Результаты проверок анализа
+	Candidates []models.Turn  `json:"candidates"` // Все найденные повороты на угол шага
 	Errors     []string       `json:"errors"`
 	Log        string         `json:"log"`
+	MagneticFit *analyzer.MagneticFit `json:"magneticFit,omitempty"`
+	Health     *analyzer.SensorHealth     `json:"health,omitempty"`    // Проверка исправности датчиков
+	Tilt       []analyzer.TiltStats       `json:"tilt,omitempty"`      // Наклон стенда в каждой позиции
+	Deviation  *analyzer.Deviation        `json:"deviation,omitempty"` // Кривая девиации компенсированного азимута
+	Drift      *analyzer.ThermalDrift     `json:"drift,omitempty"`     // Температурный дрейф курса
+	Device     *analyzer.DeviceReport     `json:"device,omitempty"`    // Состояния калибровки по флагам прибора
+	Window     *analyzer.CalibrationWindow `json:"window,omitempty"`   // Окно калибровки, в котором искались повороты
+	Closure    *analyzer.Closure          `json:"closure,omitempty"` // Возврат к начальному курсу после полного оборота
+	Cycles     []analyzer.Cycle           `json:"cycles,omitempty"`    // Все полные циклы поворотов
+	Positions  []analyzer.PositionStats   `json:"positions,omitempty"` // Повторяемость позиций между циклами
+	Preprocess []analyzer.PreprocessStage `json:"preprocess"`       // Этапы предварительной обработки
+	Series     *analyzer.Series          `json:"series,omitempty"` // Исходный и обработанный ряды для графика
 }
 
 // SegmentInfo представляет информацию о сегменте для фронтенда
@@ -37,16 +58,20 @@ type SegmentInfo struct {
 	EndIndex   int     `json:"endIndex"`
 	AvgAngle   float64 `json:"avgAngle"`
 	Length     int     `json:"length"`
+	StdDev     float64 `json:"stdDev"` // Круговое СКО углов сегмента, градусы
+	MAD        float64 `json:"mad"`    // Медианное абсолютное отклонение, градусы
 }
 
 // Server представляет веб-сервер
 type Server struct {
-	port string
+	port    string
+	params  models.AnalysisParams
+	profile models.ProcedureProfile // Профиль по умолчанию, если в запросе не указан другой
 }
 
-// NewServer создает новый веб-сервер
-func NewServer(port string) *Server {
-	return &Server{port: port}
+// NewServer создает новый веб-сервер с заданными параметрами анализа
+func NewServer(port string, params models.AnalysisParams, profile models.ProcedureProfile) *Server {
+	return &Server{port: port, params: params, profile: profile}
 }
 
 // Start запускает веб-сервер
@@ -59,6 +84,7 @@ func (s *Server) Start() error {
 	http.HandleFunc("/api/analyze", s.handleAnalyze)
 	http.HandleFunc("/api/batch-analyze", s.handleBatchAnalyze)
 	http.HandleFunc("/api/batch-analyze-stream", s.handleBatchAnalyzeStream)
+	http.HandleFunc("/api/profiles", s.handleProfiles)
 
 	addr := ":" + s.port
 	fmt.Printf("\n╔════════════════════════════════════════════════════════╗\n")
@@ -82,7 +108,13 @@ func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := s.analyzeFolder(req.FolderPath)
+	profile, err := s.resolveProfile(req.Profile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := s.analyzeFolder(req.FolderPath, profile)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -97,6 +129,7 @@ func (s *Server) handleBatchAnalyze(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		DataDir string `json:"dataDir"`
+		Profile string `json:"profile"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -104,6 +137,12 @@ func (s *Server) handleBatchAnalyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	profile, err := s.resolveProfile(req.Profile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	folders, err := os.ReadDir(req.DataDir)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -118,7 +157,7 @@ func (s *Server) handleBatchAnalyze(w http.ResponseWriter, r *http.Request) {
 		}
 
 		folderPath := filepath.Join(req.DataDir, folder.Name())
-		response := s.analyzeFolder(folderPath)
+		response := s.analyzeFolder(folderPath, profile)
 		responses = append(responses, response)
 	}
 
@@ -135,6 +174,7 @@ func (s *Server) handleBatchAnalyzeStream(w http.ResponseWriter, r *http.Request
 
 	var req struct {
 		DataDir string `json:"dataDir"`
+		Profile string `json:"profile"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -142,6 +182,12 @@ func (s *Server) handleBatchAnalyzeStream(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	profile, err := s.resolveProfile(req.Profile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	folders, err := os.ReadDir(req.DataDir)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -182,7 +228,7 @@ func (s *Server) handleBatchAnalyzeStream(w http.ResponseWriter, r *http.Request
 		
 		log.Printf("⏳ [%d/%d] Анализ: %s", processed, totalFolders, folder.Name())
 		
-		response := s.analyzeFolder(folderPath)
+		response := s.analyzeFolder(folderPath, profile)
 		
 		// Создаем сообщение с прогрессом
 		progressMsg := map[string]interface{}{
@@ -223,32 +269,56 @@ func (s *Server) handleBatchAnalyzeStream(w http.ResponseWriter, r *http.Request
 	log.Printf("✅ Потоковый пакетный анализ завершен: %d папок обработано", totalFolders)
 }
 
-// analyzeFolder анализирует данные компаса из указанной папки
-func (s *Server) analyzeFolder(folderPath string) AnalysisResponse {
+// resolveProfile возвращает профиль процедуры по имени из запроса
+// или профиль сервера по умолчанию, если имя не указано
+func (s *Server) resolveProfile(name string) (models.ProcedureProfile, error) {
+	if name == "" {
+		return s.profile, nil
+	}
+	return models.FindProfile(name)
+}
+
+// handleProfiles возвращает список доступных профилей процедуры
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"default":  s.profile.Name,
+		"profiles": models.Profiles(),
+	})
+}
+
+// analyzeFolder анализирует данные компаса из указанной папки по профилю процедуры
+func (s *Server) analyzeFolder(folderPath string, profile models.ProcedureProfile) AnalysisResponse {
 	response := AnalysisResponse{
 		Compass: filepath.Base(folderPath),
+		Params:  s.params,
+		Profile: profile.Name,
+		Segmenter: profile.SegmenterName(),
 	}
 
 	// Проверяем наличие CSV файла
 	csvPath := filepath.Join(folderPath, "SB_CMPS.csv")
 	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
 		response.Success = false
-		response.Errors = []string{fmt.Sprintf("Файл SB_CMPS.csv не найден в %s", folderPath)}
+		response.Reason = models.ReasonFileMissing
+		response.Errors = []string{response.Reason.Format(csvPath)}
 		return response
 	}
 
 	// Читаем данные
-	data, err := parser.ReadCSVFile(csvPath)
+	records, err := parser.ReadTelemetry(csvPath)
 	if err != nil {
 		response.Success = false
-		response.Errors = []string{fmt.Sprintf("Ошибка чтения CSV: %v", err)}
+		reason, detail := parser.ReadErrorReason(err)
+		response.Reason = reason
+		response.Errors = []string{reason.Format(csvPath, detail)}
 		return response
 	}
 
 	// Извлекаем углы
-	angles := make([]float64, len(data))
-	for i, d := range data {
-		angles[i] = d.Angle
+	angles := make([]float64, len(records))
+	for i, r := range records {
+		angles[i] = r.AzimuthRaw
 	}
 	response.AllAngles = angles
 
@@ -266,11 +336,30 @@ func (s *Server) analyzeFolder(folderPath string) AnalysisResponse {
 	}()
 
 	// Анализируем
-	isValid, turns := analyzer.AnalyzeCompassData(angles, logFile)
+	result := analyzer.AnalyzeTelemetryWithJournal(records, parser.ReadJournalFor(csvPath), s.params, profile, logFile)
 
 	response.Success = true
-	response.IsValid = isValid
-	response.Turns = turns
+	response.IsValid = result.IsValid
+	response.Turns = result.Turns
+	response.Candidates = result.Candidates
+	response.Checks = result.Checks
+	response.Reason = result.Reason()
+	response.Quality = result.Quality
+	response.NeedsRetest = result.NeedsRetest()
+	response.MagneticFit = result.MagneticFit
+	response.Health = result.Health
+	response.Tilt = result.Tilt
+	response.Deviation = result.Deviation
+	response.Drift = result.Drift
+	response.Device = result.Device
+	response.Window = result.Window
+	response.Closure = result.Closure
+	response.Cycles = result.Cycles
+	response.Positions = result.Positions
+	response.Preprocess = result.Preprocess
+	response.Series = result.Series
+	// Индексы сегментов и поворотов относятся к ряду после предварительной обработки
+	response.AllAngles = result.Series.Filtered
 
 	// Читаем лог
 	if logFile != nil {
@@ -279,30 +368,27 @@ func (s *Server) analyzeFolder(folderPath string) AnalysisResponse {
 		response.Log = string(logBytes)
 	}
 
-	// Получаем информацию о сегментах (для визуализации)
-	// Пересоздаем сегменты для отправки на фронт
-	segments := analyzer.GetSegments(angles)
-	for _, seg := range segments {
+	// Информация о сегментах (для визуализации)
+	for _, seg := range result.Segments {
 		response.Segments = append(response.Segments, SegmentInfo{
 			StartIndex: seg.StartIndex,
 			EndIndex:   seg.EndIndex,
 			AvgAngle:   seg.AvgAngle,
 			Length:     len(seg.AllAngles),
+			StdDev:     seg.StdDev,
+			MAD:        seg.MAD,
 		})
 	}
 
-	if !isValid {
-		response.Errors = []string{
-			fmt.Sprintf("Недостаточно найденных поворотов на ~90 градусов: %d (ожидалось 4)", len(turns)),
-		}
-	}
+	// Причины отбраковки - проваленные проверки анализа
+	response.Errors = result.Errors()
 
 	return response
 }
 
 // StartWebUI запускает веб-интерфейс
-func StartWebUI(port string) {
-	server := NewServer(port)
+func StartWebUI(port string, params models.AnalysisParams, profile models.ProcedureProfile) {
+	server := NewServer(port, params, profile)
 	if err := server.Start(); err != nil {
 		log.Fatalf("Ошибка запуска веб-сервера: %v", err)
 	}
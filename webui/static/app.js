@@ -565,15 +565,23 @@ function displayResults(data) {
     document.getElementById('resultsSection').style.display = 'block';
     
     // Update stats
-    document.getElementById('statValid').textContent = data.isValid ? '✓ Валидно' : '✗ Не прошло';
+    document.getElementById('statValid').textContent = (data.isValid ? (data.needsRetest ? '⚠ Перепроверка' : '✓ Валидно') : (data.reason ? `✗ ${data.reason}` : '✗ Не прошло'))
+        + (data.quality != null ? ` · ${data.quality.toFixed(0)}/100` : '');
     document.getElementById('statValid').style.color = data.isValid ? 'var(--success)' : 'var(--error)';
     document.getElementById('statTurns').textContent = data.turns ? data.turns.length : 0;
     document.getElementById('statSegments').textContent = data.segments ? data.segments.length : 0;
     document.getElementById('statAngles').textContent = data.allAngles ? data.allAngles.length : 0;
+    // Окно калибровки, в котором искались повороты
+    document.getElementById('statWindow').textContent = data.window
+        ? `окно: строки ${data.window.firstLine}–${data.window.lastLine} из ${data.window.total}`
+        : 'весь файл';
     
     // Update turns table
     displayTurnsTable(data.turns);
     
+    // Update checks table
+    displayChecksTable(data.checks);
+    
     // Display polar chart
     displayPolarChart(data);
     
@@ -1309,6 +1317,39 @@ function displayTurnsTable(turns) {
     }).join('');
 }
 
+function displayChecksTable(checks) {
+    const tbody = document.getElementById('checksTableBody');
+    const failed = (checks || []).filter(c => !c.passed && !c.advisory).length;
+    document.getElementById('checksBadge').textContent = failed > 0 ? `✗ ${failed}` : (checks ? checks.length : 0);
+    
+    if (!checks || checks.length === 0) {
+        tbody.innerHTML = `
+            <tr>
+                <td colspan="5" class="empty-state">
+                    <span class="material-icons">info</span>
+                    Проверки не выполнялись
+                </td>
+            </tr>
+        `;
+        return;
+    }
+    
+    tbody.innerHTML = checks.map(check => {
+        const badge = check.passed ? 'success' : (check.advisory ? 'warning' : 'error');
+        const unit = check.unit || '';
+        
+        return `
+            <tr>
+                <td><span class="badge ${badge}">${check.name}</span></td>
+                <td>${check.measured.toFixed(2)} ${unit}</td>
+                <td>${check.limit.toFixed(2)} ${unit}</td>
+                <td>${check.reason || '-'}</td>
+                <td>${check.message}</td>
+            </tr>
+        `;
+    }).join('');
+}
+
 function displayPolarChart(data) {
     const ctx = document.getElementById('polarChart');
     
@@ -1350,6 +1391,9 @@ function displayPolarChart(data) {
         };
     });
     
+    // Исходные записи поверх обработанного ряда (если предобработка что-то изменила)
+    const rawPoints = rawOverlayPoints(data);
+    
     state.chart = new Chart(ctx, {
         type: 'scatter',
         data: {
@@ -1376,7 +1420,14 @@ function displayPolarChart(data) {
                     return point && point.inTurnZone ? 5 : 3;
                 },
                 pointHoverRadius: 7
-            }]
+            }].concat(rawPoints ? [{
+                label: 'Исходные записи',
+                data: rawPoints,
+                backgroundColor: 'rgba(248, 113, 113, 0.5)',
+                borderColor: 'rgba(248, 113, 113, 0.8)',
+                pointStyle: 'crossRot',
+                pointRadius: 3
+            }] : [])
         },
         options: {
             responsive: true,
@@ -1404,7 +1455,14 @@ function displayPolarChart(data) {
                                     hidden: false,
                                     index: 1
                                 }
-                            ];
+                            ].concat(rawPoints ? [{
+                                text: '✖ Исходные записи (до предобработки)',
+                                fillStyle: 'rgba(248, 113, 113, 0.5)',
+                                strokeStyle: 'rgba(248, 113, 113, 0.8)',
+                                lineWidth: 2,
+                                hidden: false,
+                                index: 2
+                            }] : []);
                         }
                     }
                 },
@@ -1454,6 +1512,28 @@ function displayPolarChart(data) {
     });
 }
 
+// rawOverlayPoints возвращает исходные записи в координатах обработанного ряда
+// (дробный индекс по времени записи) или null, если предобработка ничего не изменила
+function rawOverlayPoints(data) {
+    const series = data.series;
+    const changed = (data.preprocess || []).some(stage => stage.changed > 0);
+    if (!series || !changed || !series.times || series.times.length < 2) {
+        return null;
+    }
+    
+    let j = 0;
+    return series.raw.map((angle, i) => {
+        const t = series.rawTimes[i];
+        while (j + 2 < series.times.length && series.times[j + 1] < t) {
+            j++;
+        }
+        const t0 = series.times[j];
+        const t1 = series.times[j + 1];
+        const x = t1 > t0 ? j + Math.min(1, Math.max(0, (t - t0) / (t1 - t0))) : j;
+        return { x: x, y: angle };
+    });
+}
+
 // Utility Functions
 function showLoading(show, message = 'Загрузка...') {
     const overlay = document.getElementById('loadingOverlay');